@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,15 +11,20 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
-	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	commonsHttp "github.com/omniful/go_commons/http"
 	logger "github.com/omniful/go_commons/log"
+	"github.com/omniful/ims-service/internal/api/grpcapi"
 	"github.com/omniful/ims-service/internal/api/handlers"
 	"github.com/omniful/ims-service/internal/config"
+	"github.com/omniful/ims-service/internal/outbox"
+	"github.com/omniful/ims-service/internal/ratelimit"
 	"github.com/omniful/ims-service/internal/repository"
 	"github.com/omniful/ims-service/internal/service"
 	"github.com/omniful/ims-service/pkg/constants"
+	"github.com/omniful/ims-service/pkg/imspb"
+	"github.com/omniful/ims-service/pkg/jwt"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -43,19 +49,49 @@ func main() {
 	redisClient := initializeRedis()
 
 	// Initialize repositories in correct order (due to dependencies)
+	tenantRepo := repository.NewTenantRepository(config.DBCluster, redisClient)
+	apiKeyRepo := repository.NewAPIKeyRepository(config.DBCluster, redisClient)
+	rateLimitRepo := repository.NewRateLimitRepository(config.DBCluster, redisClient)
 	hubRepo := repository.NewHubRepository(config.DBCluster, redisClient)
 	skuRepo := repository.NewSKURepository(config.DBCluster, redisClient)
-	inventoryRepo := repository.NewInventoryRepository(config.DBCluster, hubRepo, skuRepo, redisClient)
+	outboxRepo := repository.NewOutboxRepository(config.DBCluster, cfg.Kafka.InventoryTopic)
+	inventoryRepo := repository.NewInventoryRepository(config.DBCluster, hubRepo, skuRepo, outboxRepo, redisClient)
+
+	// Publish inventory.changed events written to the outbox with each inventory change
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	if cfg.Kafka.Enabled {
+		publisher, err := outbox.NewKafkaPublisher(cfg.Kafka.Brokers)
+		if err != nil {
+			logger.Error("Failed to initialize Kafka publisher: " + err.Error())
+		} else {
+			defer publisher.Close()
+			outbox.NewRelay(outboxRepo, publisher, time.Second, 100).Start(relayCtx)
+		}
+	} else {
+		logger.Info("Kafka disabled, inventory events stay in the outbox until it is enabled")
+	}
 
 	// Initialize services
+	tenantService := service.NewTenantService(tenantRepo)
 	hubService := service.NewHubService(hubRepo)
 	skuService := service.NewSKUService(skuRepo)
 	inventoryService := service.NewInventoryService(inventoryRepo, hubRepo, skuRepo)
+	validationService := service.NewValidationService(hubRepo, skuRepo, inventoryRepo)
+	verifier, err := newJWTVerifier(&cfg.Auth)
+	if err != nil {
+		logger.Error("Failed to configure JWT verification: " + err.Error())
+		os.Exit(1)
+	}
+	authService := service.NewAuthService(apiKeyRepo, tenantService, verifier)
+	rateLimitService := service.NewRateLimitService(rateLimitRepo, tenantService, ratelimit.NewLimiter(redisClient), cfg.RateLimit.Limits())
 
 	// Initialize handlers
 	hubHandler := handlers.NewHubHandler(hubService)
 	skuHandler := handlers.NewSKUHandler(skuService)
 	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	validationHandler := handlers.NewValidationHandler(validationService)
+	authHandler := handlers.NewAuthHandler()
 
 	// Initialize server with custom timeouts
 	server := commonsHttp.InitializeServer(
@@ -66,118 +102,31 @@ func main() {
 		false,          // TLS disabled (set to true if needed)
 	)
 
-	// Create a router group for API v1
+	// Create a router group for API v1. The tenant comes from the request credential, or
+	// from the X-Tenant-ID header when authentication is off.
 	api := server.Group("/api/v1")
+	if cfg.Auth.Enforced() {
+		api.Use(handlers.AuthMiddleware(authService))
+	} else {
+		logger.Error("AUTH_MODE=off: API requests are not authenticated and get every scope")
+		api.Use(handlers.TenantMiddleware(tenantService))
+	}
+	// Limit each tenant's request rate per route group, once the tenant is known
+	var grpcRateLimits service.RateLimitService
+	if cfg.RateLimit.Enabled && redisClient != nil {
+		api.Use(handlers.RateLimitMiddleware(rateLimitService))
+		grpcRateLimits = rateLimitService
+	} else {
+		logger.Info("Rate limiting disabled")
+	}
 
+	authHandler.RegisterRoutes(api)
 	// Register real database-backed routes
 	hubHandler.RegisterRoutes(api)
 	skuHandler.RegisterRoutes(api)
 	inventoryHandler.RegisterRoutes(api)
-
-	// Add validation endpoint for OMS integration
-	api.POST("/validate", func(c *gin.Context) {
-		var req struct {
-			SKU      string    `json:"sku" binding:"required"`
-			HubID    string    `json:"hub_id" binding:"required"`
-			TenantID uuid.UUID `json:"tenant_id"`
-		}
-
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(400, gin.H{"error": constants.ErrInvalidRequest})
-			return
-		}
-
-		// Get tenant ID from header if not in body
-		if req.TenantID == uuid.Nil {
-			tenantIDStr := c.GetHeader("tenant_id")
-			if tenantIDStr == "" {
-				c.JSON(400, gin.H{"error": "tenant_id header or field is required"})
-				return
-			}
-			tenantID, err := uuid.Parse(tenantIDStr)
-			if err != nil {
-				c.JSON(400, gin.H{"error": "invalid tenant_id"})
-				return
-			}
-			req.TenantID = tenantID
-		}
-
-		// Real validation logic using database
-		ctx := c.Request.Context()
-
-		// Validate Hub
-		hub, err := hubService.GetHubByCode(ctx, req.TenantID, req.HubID)
-		hubValid := err == nil && hub != nil && hub.IsActive
-
-		// Validate SKU
-		sku, err := skuService.GetSKUByCode(ctx, req.TenantID, req.SKU)
-		skuValid := err == nil && sku != nil && sku.IsActive
-
-		c.JSON(200, gin.H{
-			"sku_valid": skuValid,
-			"hub_valid": hubValid,
-			"valid":     skuValid && hubValid,
-			"message":   "Validation completed",
-		})
-	})
-
-	// Batch validation endpoint
-	api.POST("/validate/batch", func(c *gin.Context) {
-		var req struct {
-			TenantID uuid.UUID `json:"tenant_id"`
-			Orders   []struct {
-				SKU   string `json:"sku"`
-				HubID string `json:"hub_id"`
-			} `json:"orders"`
-		}
-
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(400, gin.H{"error": constants.ErrInvalidRequest})
-			return
-		}
-
-		// Get tenant ID from header if not in body
-		if req.TenantID == uuid.Nil {
-			tenantIDStr := c.GetHeader("tenant_id")
-			if tenantIDStr == "" {
-				c.JSON(400, gin.H{"error": "tenant_id header or field is required"})
-				return
-			}
-			tenantID, err := uuid.Parse(tenantIDStr)
-			if err != nil {
-				c.JSON(400, gin.H{"error": "invalid tenant_id"})
-				return
-			}
-			req.TenantID = tenantID
-		}
-
-		ctx := c.Request.Context()
-		results := make([]gin.H, len(req.Orders))
-
-		for i, order := range req.Orders {
-			// Validate Hub
-			hub, err := hubService.GetHubByCode(ctx, req.TenantID, order.HubID)
-			hubValid := err == nil && hub != nil && hub.IsActive
-
-			// Validate SKU
-			sku, err := skuService.GetSKUByCode(ctx, req.TenantID, order.SKU)
-			skuValid := err == nil && sku != nil && sku.IsActive
-
-			results[i] = gin.H{
-				"sku":       order.SKU,
-				"hub_id":    order.HubID,
-				"sku_valid": skuValid,
-				"hub_valid": hubValid,
-				"valid":     skuValid && hubValid,
-			}
-		}
-
-		c.JSON(200, gin.H{
-			"message": "Batch validation completed",
-			"results": results,
-			"count":   len(results),
-		})
-	})
+	// Validation endpoints for OMS integration
+	validationHandler.RegisterRoutes(api)
 
 	// Basic routes
 	server.GET("/health", func(c *gin.Context) {
@@ -213,6 +162,7 @@ func main() {
 				constants.EndpointSKUs,
 				constants.EndpointInventory,
 				constants.EndpointValidation,
+				constants.EndpointWhoAmI,
 			},
 		})
 	})
@@ -228,6 +178,17 @@ func main() {
 		}
 	}()
 
+	// Serve the hot paths OMS calls over gRPC too, authenticated and limited the same way
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		interceptors := grpcapi.NewInterceptors(authService, tenantService, grpcRateLimits, cfg.Auth.Enforced())
+		grpcServer, err = startGRPCServer(cfg.GRPC.Address, grpcapi.NewServer(inventoryService, validationService), interceptors)
+		if err != nil {
+			logger.Error("Failed to start gRPC server: " + err.Error())
+			os.Exit(1)
+		}
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -240,6 +201,9 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		stopGRPCServer(ctx, grpcServer)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown: ", err)
 		os.Exit(1)
@@ -248,6 +212,61 @@ func main() {
 	logger.Info("Server exiting")
 }
 
+// newJWTVerifier returns the verifier for the configured JWT keys, or nil when none is
+// configured and only API keys are accepted
+func newJWTVerifier(cfg *config.AuthConfig) (*jwt.Verifier, error) {
+	publicKey, err := cfg.RS256PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	verifierConfig := jwt.VerifierConfig{
+		HS256Secret:    cfg.JWTHS256Secret,
+		RS256PublicKey: publicKey,
+		Issuer:         cfg.JWTIssuer,
+		Audience:       cfg.JWTAudience,
+	}
+	if !verifierConfig.Configured() {
+		return nil, nil
+	}
+	return jwt.NewVerifier(verifierConfig)
+}
+
+// startGRPCServer serves inventory on address in the background
+func startGRPCServer(address string, inventory imspb.InventoryServer, interceptors *grpcapi.Interceptors) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(interceptors.Unary()),
+		grpc.ChainStreamInterceptor(interceptors.Stream()),
+	)
+	imspb.RegisterInventoryServer(server, inventory)
+
+	go func() {
+		logger.Info("gRPC server listening on " + address)
+		if err := server.Serve(listener); err != nil {
+			logger.Error("gRPC server stopped: " + err.Error())
+		}
+	}()
+	return server, nil
+}
+
+// stopGRPCServer lets in-flight calls finish until ctx is done, then cancels them
+func stopGRPCServer(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
 func printRoutes(router *gin.Engine) {
 	fmt.Println("\n=== Registered Routes ===")
 	for _, route := range router.Routes() {
@@ -27,27 +27,41 @@ func setupRoutes(router *gin.RouterGroup) {
 	logger.Info("Database and Redis clients are available")
 
 	// Initialize repositories
+	tenantRepo := repository.NewTenantRepository(config.DBCluster, dbRedisClient)
+	apiKeyRepo := repository.NewAPIKeyRepository(config.DBCluster, dbRedisClient)
 	hubRepo := repository.NewHubRepository(config.DBCluster, dbRedisClient)
 	skuRepo := repository.NewSKURepository(config.DBCluster, dbRedisClient)
-	inventoryRepo := repository.NewInventoryRepository(config.DBCluster, hubRepo, skuRepo, dbRedisClient)
+	outboxRepo := repository.NewOutboxRepository(config.DBCluster, "inventory-events")
+	inventoryRepo := repository.NewInventoryRepository(config.DBCluster, hubRepo, skuRepo, outboxRepo, dbRedisClient)
 
 	// Initialize services
+	tenantService := service.NewTenantService(tenantRepo)
 	hubService := service.NewHubService(hubRepo)
 	skuService := service.NewSKUService(skuRepo)
 	inventoryService := service.NewInventoryService(inventoryRepo, hubRepo, skuRepo)
+	validationService := service.NewValidationService(hubRepo, skuRepo, inventoryRepo)
+	authService := service.NewAuthService(apiKeyRepo, tenantService, nil)
 
 	// Initialize handlers
 	hubHandler := handlers.NewHubHandler(hubService)
 	skuHandler := handlers.NewSKUHandler(skuService)
 	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+	validationHandler := handlers.NewValidationHandler(validationService)
+	authHandler := handlers.NewAuthHandler()
+
+	// Authenticate every route with an API key, the tenant comes from the key
+	router.Use(handlers.AuthMiddleware(authService))
 
 	// Register routes
+	authHandler.RegisterRoutes(router)
 	logger.Info("Registering hub routes...")
 	hubHandler.RegisterRoutes(router)
 	logger.Info("Registering SKU routes...")
 	skuHandler.RegisterRoutes(router)
 	logger.Info("Registering inventory routes...")
 	inventoryHandler.RegisterRoutes(router)
+	logger.Info("Registering validation routes...")
+	validationHandler.RegisterRoutes(router)
 
 	logger.Info("All routes registered successfully")
 }
@@ -9,6 +9,10 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/omniful/go_commons v0.6.22
+	github.com/omniful/ims-service/pkg/imspb v0.0.0
+	github.com/omniful/ims-service/pkg/jwt v0.0.0
+	google.golang.org/grpc v1.65.0
+	google.golang.org/protobuf v1.34.2
 	gorm.io/gorm v1.30.0
 )
 
@@ -63,9 +67,11 @@ require (
 	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 	google.golang.org/genproto v0.0.0-20230110181048-76db0878b65f // indirect
-	google.golang.org/grpc v1.65.0 // indirect
-	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	gorm.io/driver/postgres v1.4.5 // indirect
 )
+
+replace github.com/omniful/ims-service/pkg/imspb => ./pkg/imspb
+
+replace github.com/omniful/ims-service/pkg/jwt => ./pkg/jwt
@@ -0,0 +1,226 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	logger "github.com/omniful/go_commons/log"
+	"github.com/omniful/ims-service/internal/auth"
+	"github.com/omniful/ims-service/internal/ratelimit"
+	"github.com/omniful/ims-service/internal/service"
+	"github.com/omniful/ims-service/pkg/constants"
+	"github.com/omniful/ims-service/pkg/imspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys, gRPC lower-cases them
+const (
+	metadataAuthorization = "authorization"
+	metadataRetryAfter    = "retry-after"
+)
+
+// methodScopes is the scope each method needs, as for the matching HTTP routes
+var methodScopes = map[string]string{
+	imspb.Inventory_ValidateBatch_FullMethodName:  auth.ScopeCatalogRead,
+	imspb.Inventory_ValidateStream_FullMethodName: auth.ScopeCatalogRead,
+	imspb.Inventory_GetInventory_FullMethodName:   auth.ScopeInventoryRead,
+	imspb.Inventory_ReserveOrder_FullMethodName:   auth.ScopeInventoryWrite,
+	imspb.Inventory_Release_FullMethodName:        auth.ScopeInventoryWrite,
+	imspb.Inventory_Fulfill_FullMethodName:        auth.ScopeInventoryWrite,
+}
+
+// methodGroups is the rate limit group each method draws from
+var methodGroups = map[string]string{
+	imspb.Inventory_ValidateBatch_FullMethodName:  ratelimit.GroupValidation,
+	imspb.Inventory_ValidateStream_FullMethodName: ratelimit.GroupValidation,
+	imspb.Inventory_GetInventory_FullMethodName:   ratelimit.GroupReads,
+	imspb.Inventory_ReserveOrder_FullMethodName:   ratelimit.GroupWrites,
+	imspb.Inventory_Release_FullMethodName:        ratelimit.GroupWrites,
+	imspb.Inventory_Fulfill_FullMethodName:        ratelimit.GroupWrites,
+}
+
+type principalKey struct{}
+
+// lastRateLimitError is when a failure to take a token was last logged, in Unix seconds
+var lastRateLimitError atomic.Int64
+
+// Interceptors authenticates calls and limits their rate like the HTTP middleware does
+type Interceptors struct {
+	authService   service.AuthService
+	tenantService service.TenantService
+	// rateLimits is nil when rate limiting is disabled
+	rateLimits service.RateLimitService
+	// enforce is false when AUTH_MODE=off, calls then get every scope and name their
+	// tenant in x-tenant-id
+	enforce bool
+}
+
+func NewInterceptors(authService service.AuthService, tenantService service.TenantService, rateLimits service.RateLimitService, enforce bool) *Interceptors {
+	return &Interceptors{
+		authService:   authService,
+		tenantService: tenantService,
+		rateLimits:    rateLimits,
+		enforce:       enforce,
+	}
+}
+
+// Unary authenticates a call, checks its scope and takes a token for it
+func (i *Interceptors) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := i.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if err := i.take(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream authenticates a stream and checks its scope, then takes a token for every
+// message received on it, so a stream of batches is limited like as many calls
+func (i *Interceptors) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &limitedStream{ServerStream: ss, ctx: ctx, interceptors: i, method: info.FullMethod})
+	}
+}
+
+// authorize returns ctx with the principal of the call, failing when it has no valid
+// credential or lacks the scope of method
+func (i *Interceptors) authorize(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ref := firstValue(md, strings.ToLower(constants.HeaderTenantID))
+
+	var principal *auth.Principal
+	if i.enforce {
+		credential := bearerToken(firstValue(md, metadataAuthorization))
+		if credential == "" {
+			credential = firstValue(md, strings.ToLower(constants.HeaderAPIKey))
+		}
+		if credential == "" {
+			return nil, status.Error(codes.Unauthenticated, "an API key or bearer token is required")
+		}
+
+		var err error
+		principal, err = i.authService.Authenticate(ctx, credential)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidCredential) {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			logger.Error("Failed to authenticate gRPC call: " + err.Error())
+			return nil, status.Error(codes.Internal, constants.ErrInternalServer)
+		}
+		if ref != "" && ref != principal.TenantID.String() && ref != principal.TenantCode {
+			return nil, status.Errorf(codes.PermissionDenied, "credential is bound to tenant %s, not %s", principal.TenantCode, ref)
+		}
+	} else {
+		principal = &auth.Principal{Subject: "anonymous", Scopes: auth.AllScopes, Method: auth.MethodNone}
+		if ref != "" {
+			tenant, err := i.tenantService.Resolve(ctx, ref)
+			if err != nil {
+				code := codes.Internal
+				if errors.Is(err, service.ErrTenantNotFound) || errors.Is(err, service.ErrTenantInactive) {
+					code = codes.InvalidArgument
+				}
+				return nil, status.Errorf(code, "invalid tenant %q: %v", ref, err)
+			}
+			principal.TenantID, principal.TenantCode = tenant.ID, tenant.Code
+		}
+	}
+
+	scope, ok := methodScopes[method]
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	if !principal.Allows(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "scope %s is required", scope)
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// take takes a token from the bucket of the call's tenant for the group of method and
+// fails with ResourceExhausted and a retry-after header when it is empty. Calls are let
+// through when Redis cannot be reached.
+func (i *Interceptors) take(ctx context.Context, method string) error {
+	if i.rateLimits == nil {
+		return nil
+	}
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		// Methods reject calls without a tenant
+		return nil
+	}
+
+	group := methodGroups[method]
+	result, err := i.rateLimits.Take(ctx, tenantID, group)
+	if err != nil {
+		if now := time.Now().Unix(); lastRateLimitError.Swap(now) < now-60 {
+			logger.Error("Rate limiting unavailable, letting gRPC calls through: " + err.Error())
+		}
+		return nil
+	}
+	if result.Allowed {
+		return nil
+	}
+
+	retryAfter := result.RetryAfterSeconds()
+	_ = grpc.SetHeader(ctx, metadata.Pairs(metadataRetryAfter, strconv.Itoa(retryAfter)))
+	return status.Errorf(codes.ResourceExhausted, "rate limit for %s exceeded, retry in %ds", group, retryAfter)
+}
+
+// limitedStream takes a rate limit token for every message received
+type limitedStream struct {
+	grpc.ServerStream
+	ctx          context.Context
+	interceptors *Interceptors
+	method       string
+}
+
+func (s *limitedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.interceptors.take(s.ctx, s.method)
+}
+
+// tenantFromContext returns the tenant of the call, bound to its credential or named in
+// x-tenant-id when authentication is off
+func tenantFromContext(ctx context.Context) (uuid.UUID, error) {
+	principal, ok := ctx.Value(principalKey{}).(*auth.Principal)
+	if !ok || principal.TenantID == uuid.Nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "%s metadata is required", strings.ToLower(constants.HeaderTenantID))
+	}
+	return principal.TenantID, nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+func bearerToken(header string) string {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,280 @@
+// Package grpcapi serves the IMS hot paths over gRPC, next to the HTTP API and on the
+// same services.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	logger "github.com/omniful/go_commons/log"
+	"github.com/omniful/ims-service/internal/models"
+	"github.com/omniful/ims-service/internal/service"
+	"github.com/omniful/ims-service/pkg/constants"
+	"github.com/omniful/ims-service/pkg/imspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rollbackTimeout bounds releasing the lines of an order that could not be reserved in full
+const rollbackTimeout = 10 * time.Second
+
+// Server implements the imspb.Inventory service
+type Server struct {
+	imspb.UnimplementedInventoryServer
+	inventory  service.InventoryService
+	validation service.ValidationService
+}
+
+func NewServer(inventory service.InventoryService, validation service.ValidationService) *Server {
+	return &Server{
+		inventory:  inventory,
+		validation: validation,
+	}
+}
+
+func (s *Server) ValidateBatch(ctx context.Context, req *imspb.ValidateBatchRequest) (*imspb.ValidateBatchResponse, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.validateBatch(ctx, tenantID, req)
+}
+
+func (s *Server) ValidateStream(stream imspb.Inventory_ValidateStreamServer) error {
+	ctx := stream.Context()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp, err := s.validateBatch(ctx, tenantID, req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) validateBatch(ctx context.Context, tenantID uuid.UUID, req *imspb.ValidateBatchRequest) (*imspb.ValidateBatchResponse, error) {
+	if len(req.Lines) > constants.MaxValidationBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d lines can be validated at once", constants.MaxValidationBatch)
+	}
+
+	lines := make([]models.ValidationLine, len(req.Lines))
+	for i, line := range req.Lines {
+		lines[i] = models.ValidationLine{SKU: line.Sku, HubID: line.HubId, SellerID: line.SellerId}
+	}
+	results, err := s.validation.ValidateBatch(ctx, tenantID, lines)
+	if err != nil {
+		return nil, internalError("validate batch", err)
+	}
+
+	resp := &imspb.ValidateBatchResponse{
+		BatchId: req.BatchId,
+		Results: make([]*imspb.ValidationResult, len(results)),
+	}
+	for i, result := range results {
+		resp.Results[i] = &imspb.ValidationResult{
+			Sku:           result.SKU,
+			HubId:         result.HubID,
+			SellerId:      result.SellerID,
+			Valid:         result.Valid,
+			SkuValid:      result.SKUValid,
+			HubValid:      result.HubValid,
+			SkuStatus:     result.SKUStatus,
+			HubStatus:     result.HubStatus,
+			SellerMatch:   result.SellerMatch,
+			HubCarriesSku: result.HubCarriesSKU,
+			Errors:        result.Errors,
+		}
+		if result.Valid {
+			resp.ValidCount++
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) GetInventory(ctx context.Context, req *imspb.GetInventoryRequest) (*imspb.GetInventoryResponse, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page := int(req.Page)
+	if page < 1 {
+		page = constants.DefaultPage
+	}
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize > constants.MaxPageSize:
+		pageSize = constants.MaxPageSize
+	case pageSize <= 0:
+		pageSize = constants.DefaultPageSize
+	}
+
+	inventories, total, err := s.inventory.GetInventory(ctx, models.InventoryFilter{
+		TenantID: tenantID.String(),
+		HubCode:  req.HubCode,
+		SellerID: req.SellerId,
+		SkuCodes: req.SkuCodes,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		return nil, internalError("get inventory", err)
+	}
+
+	resp := &imspb.GetInventoryResponse{
+		Items:    make([]*imspb.InventoryItem, len(inventories)),
+		Total:    total,
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	}
+	for i, inv := range inventories {
+		resp.Items[i] = &imspb.InventoryItem{
+			Id:        inv.ID.String(),
+			HubId:     inv.HubID.String(),
+			HubCode:   inv.Hub.Code,
+			SkuId:     inv.SkuID.String(),
+			SkuCode:   inv.SKU.Code,
+			Quantity:  int32(inv.Quantity),
+			Available: int32(inv.Available),
+			Reserved:  int32(inv.Reserved),
+			InTransit: int32(inv.InTransit),
+			Damaged:   int32(inv.Damaged),
+			UpdatedAt: timestamppb.New(inv.UpdatedAt),
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) ReserveOrder(ctx context.Context, req *imspb.ReserveOrderRequest) (*imspb.ReserveOrderResponse, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Lines) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no lines provided")
+	}
+
+	resp := &imspb.ReserveOrderResponse{OrderId: req.OrderId, Reserved: true}
+	for _, line := range req.Lines {
+		result := lineResult(line)
+		resp.Lines = append(resp.Lines, result)
+		if !resp.Reserved && !req.AllowPartial {
+			result.Status = imspb.LineStatus_LINE_STATUS_SKIPPED
+			continue
+		}
+		moveLine(result, func() error {
+			return s.inventory.ReserveInventory(ctx, tenantID, line.HubCode, line.SkuCode, int(line.Quantity))
+		})
+		if result.Status != imspb.LineStatus_LINE_STATUS_OK {
+			resp.Reserved = false
+		}
+	}
+	if resp.Reserved || req.AllowPartial {
+		return resp, nil
+	}
+
+	// All or nothing: give back what was reserved before a line failed, even when the
+	// caller gave up meanwhile, so its deadline does not leave the stock reserved
+	rollbackCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	for _, result := range resp.Lines {
+		if result.Status != imspb.LineStatus_LINE_STATUS_OK {
+			continue
+		}
+		if err := s.inventory.ReleaseInventory(rollbackCtx, tenantID, result.HubCode, result.SkuCode, int(result.Quantity)); err != nil {
+			// The line stays reserved, the caller has to release it
+			logger.Error(fmt.Sprintf("Failed to roll back reservation of %s at %s for order %s: %v", result.SkuCode, result.HubCode, req.OrderId, err))
+			result.Error = "reserved, but releasing it after another line failed did not succeed: " + err.Error()
+			continue
+		}
+		result.Status = imspb.LineStatus_LINE_STATUS_ROLLED_BACK
+	}
+	return resp, nil
+}
+
+func (s *Server) Release(ctx context.Context, req *imspb.StockRequest) (*imspb.StockResponse, error) {
+	return s.moveLines(ctx, req, s.inventory.ReleaseInventory)
+}
+
+func (s *Server) Fulfill(ctx context.Context, req *imspb.StockRequest) (*imspb.StockResponse, error) {
+	return s.moveLines(ctx, req, s.inventory.FulfillInventory)
+}
+
+// moveLines applies move to each line of req on its own, a failed line does not stop the others
+func (s *Server) moveLines(ctx context.Context, req *imspb.StockRequest, move func(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, quantity int) error) (*imspb.StockResponse, error) {
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Lines) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no lines provided")
+	}
+
+	resp := &imspb.StockResponse{OrderId: req.OrderId}
+	for _, line := range req.Lines {
+		result := lineResult(line)
+		resp.Lines = append(resp.Lines, result)
+		moveLine(result, func() error {
+			return move(ctx, tenantID, line.HubCode, line.SkuCode, int(line.Quantity))
+		})
+	}
+	return resp, nil
+}
+
+// moveLine runs fn for the line of result and records its outcome
+func moveLine(result *imspb.LineResult, fn func() error) {
+	if result.HubCode == "" || result.SkuCode == "" || result.Quantity <= 0 {
+		result.Status = imspb.LineStatus_LINE_STATUS_INVALID
+		result.Error = "hub_code, sku_code, and positive quantity are required"
+		return
+	}
+	if err := fn(); err != nil {
+		result.Status = lineStatus(err)
+		result.Error = err.Error()
+		return
+	}
+	result.Status = imspb.LineStatus_LINE_STATUS_OK
+}
+
+func lineResult(line *imspb.StockLine) *imspb.LineResult {
+	return &imspb.LineResult{HubCode: line.HubCode, SkuCode: line.SkuCode, Quantity: line.Quantity}
+}
+
+// lineStatus maps an inventory service error to the status of a line
+func lineStatus(err error) imspb.LineStatus {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "insufficient"):
+		return imspb.LineStatus_LINE_STATUS_INSUFFICIENT
+	case strings.Contains(msg, "not found"):
+		return imspb.LineStatus_LINE_STATUS_NOT_FOUND
+	case strings.Contains(msg, "must be greater than zero"):
+		return imspb.LineStatus_LINE_STATUS_INVALID
+	default:
+		return imspb.LineStatus_LINE_STATUS_FAILED
+	}
+}
+
+func internalError(op string, err error) error {
+	logger.Error(fmt.Sprintf("gRPC %s failed: %v", op, err))
+	return status.Error(codes.Internal, err.Error())
+}
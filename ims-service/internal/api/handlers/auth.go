@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	logger "github.com/omniful/go_commons/log"
+	"github.com/omniful/ims-service/internal/auth"
+	"github.com/omniful/ims-service/internal/service"
+	"github.com/omniful/ims-service/pkg/constants"
+)
+
+// AuthMiddleware authenticates the API key or JWT of every request, sent as a bearer
+// token or in the X-API-Key header. The tenant comes from the credential; a request
+// that also names a tenant in X-Tenant-ID must name the same one.
+func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential := bearerToken(c.GetHeader("Authorization"))
+		if credential == "" {
+			credential = c.GetHeader(constants.HeaderAPIKey)
+		}
+		if credential == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="ims-service"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "an API key or bearer token is required"})
+			return
+		}
+
+		principal, err := authService.Authenticate(c.Request.Context(), credential)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidCredential) {
+				c.Header("WWW-Authenticate", `Bearer realm="ims-service", error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			logger.Error("Failed to authenticate request: " + err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": constants.ErrInternalServer})
+			return
+		}
+
+		if ref := requestedTenant(c); ref != "" && ref != principal.TenantID.String() && ref != principal.TenantCode {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("credential is bound to tenant %s, not %s", principal.TenantCode, ref),
+			})
+			return
+		}
+
+		c.Set(constants.ContextKeyPrincipal, principal)
+		c.Set(constants.ContextKeyTenantID, principal.TenantID)
+		c.Set(constants.ContextKeyUserID, principal.Subject)
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose principal was not granted scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := getPrincipal(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": constants.ErrUnauthorized})
+			return
+		}
+		if !principal.Allows(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("scope %s is required", scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+type AuthHandler struct{}
+
+func NewAuthHandler() *AuthHandler {
+	return &AuthHandler{}
+}
+
+func (h *AuthHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/auth/whoami", h.WhoAmI)
+}
+
+// WhoAmI returns the caller identified by the request credential
+// @Summary Describe the request credential
+// @Description Return the subject, tenant and scopes of the API key or JWT sent with the request
+// @Tags auth
+// @Produce json
+// @Param Authorization header string true "Bearer API key or JWT"
+// @Success 200 {object} auth.Principal
+// @Failure 401 {object} map[string]string
+// @Router /auth/whoami [get]
+func (h *AuthHandler) WhoAmI(c *gin.Context) {
+	principal, ok := getPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": constants.ErrUnauthorized})
+		return
+	}
+	c.JSON(http.StatusOK, principal)
+}
+
+// getPrincipal returns the principal set by AuthMiddleware or TenantMiddleware
+func getPrincipal(c *gin.Context) (*auth.Principal, bool) {
+	value, ok := c.Get(constants.ContextKeyPrincipal)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*auth.Principal)
+	return principal, ok
+}
+
+// requestedTenant returns the tenant the client named in a header, if any
+func requestedTenant(c *gin.Context) string {
+	if ref := strings.TrimSpace(c.GetHeader(constants.HeaderTenantID)); ref != "" {
+		return ref
+	}
+	return strings.TrimSpace(c.GetHeader(constants.HeaderLegacyTenantID))
+}
+
+func bearerToken(header string) string {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/auth"
 	"github.com/omniful/ims-service/internal/models"
 	"github.com/omniful/ims-service/internal/service"
 )
@@ -19,11 +20,11 @@ func NewHubHandler(service service.HubService) *HubHandler {
 
 func (h *HubHandler) RegisterRoutes(r *gin.RouterGroup) {
 	hubs := r.Group("/hubs")
-	hubs.POST("/", h.CreateHub)
-	hubs.GET("/", h.ListHubs)
-	hubs.GET("/:id", h.GetHub)
-	hubs.PUT("/:id", h.UpdateHub)
-	hubs.DELETE("/:id", h.DeleteHub)
+	hubs.POST("/", RequireScope(auth.ScopeCatalogWrite), h.CreateHub)
+	hubs.GET("/", RequireScope(auth.ScopeCatalogRead), h.ListHubs)
+	hubs.GET("/:id", RequireScope(auth.ScopeCatalogRead), h.GetHub)
+	hubs.PUT("/:id", RequireScope(auth.ScopeCatalogWrite), h.UpdateHub)
+	hubs.DELETE("/:id", RequireScope(auth.ScopeCatalogAdmin), h.DeleteHub)
 }
 
 // CreateHubRequest represents the request body for creating a hub
@@ -45,7 +46,7 @@ type CreateHubRequest struct {
 // @Tags hubs
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param request body CreateHubRequest true "Hub details"
 // @Success 201 {object} models.Hub
 // @Failure 400 {object} map[string]string
@@ -53,14 +54,9 @@ type CreateHubRequest struct {
 // @Router /hubs [post]
 func (h *HubHandler) CreateHub(c *gin.Context) {
 	// Get tenant ID from header
-	tenantIDStr := c.GetHeader("tenant_id")
-	if tenantIDStr == "" {
-		c.JSON(400, gin.H{"error": "tenant_id header is required"})
-		return
-	}
-	tenantID, err := uuid.Parse(tenantIDStr)
+	tenantID, err := getTenantID(c)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "invalid tenant_id"})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -100,7 +96,7 @@ func (h *HubHandler) CreateHub(c *gin.Context) {
 // @Tags hubs
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Items per page" default(20)
 // @Success 200 {object} map[string]interface{}
@@ -109,14 +105,9 @@ func (h *HubHandler) CreateHub(c *gin.Context) {
 // @Router /hubs [get]
 func (h *HubHandler) ListHubs(c *gin.Context) {
 	// Get tenant ID from header
-	tenantIDStr := c.GetHeader("tenant_id")
-	if tenantIDStr == "" {
-		c.JSON(400, gin.H{"error": "tenant_id header is required"})
-		return
-	}
-	tenantID, err := uuid.Parse(tenantIDStr)
+	tenantID, err := getTenantID(c)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "invalid tenant_id"})
+		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
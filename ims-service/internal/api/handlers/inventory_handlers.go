@@ -7,7 +7,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/auth"
 	"github.com/omniful/ims-service/internal/models"
 	"github.com/omniful/ims-service/internal/service"
 )
@@ -23,12 +23,13 @@ func NewInventoryHandler(service service.InventoryService) *InventoryHandler {
 func (h *InventoryHandler) RegisterRoutes(r *gin.RouterGroup) {
 	inv := r.Group("/inventory")
 	{
-		inv.POST("/", h.UpsertInventory)
-		inv.GET("/", h.GetInventory)
-		inv.GET("/:hubCode/:skuCode", h.GetInventoryItem)
-		inv.POST("/reserve", h.ReserveInventory)
-		inv.POST("/release", h.ReleaseInventory)
-		inv.POST("/fulfill", h.FulfillInventory)
+		inv.POST("/", RequireScope(auth.ScopeInventoryWrite), h.UpsertInventory)
+		inv.GET("/", RequireScope(auth.ScopeInventoryRead), h.GetInventory)
+		inv.GET("/:hubCode/:skuCode", RequireScope(auth.ScopeInventoryRead), h.GetInventoryItem)
+		inv.POST("/reserve", RequireScope(auth.ScopeInventoryWrite), h.ReserveInventory)
+		inv.POST("/release", RequireScope(auth.ScopeInventoryWrite), h.ReleaseInventory)
+		inv.POST("/fulfill", RequireScope(auth.ScopeInventoryWrite), h.FulfillInventory)
+		inv.POST("/restock", RequireScope(auth.ScopeInventoryWrite), h.RestockInventory)
 	}
 }
 
@@ -42,7 +43,7 @@ type UpsertInventoryRequest struct {
 // @Tags inventory
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param request body UpsertInventoryRequest true "Inventory updates"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
@@ -50,15 +51,9 @@ type UpsertInventoryRequest struct {
 // @Router /inventory [post]
 func (h *InventoryHandler) UpsertInventory(c *gin.Context) {
 	// Get tenant ID from header
-	tenantIDStr := c.GetHeader("X-Tenant-ID")
-	if tenantIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing tenant ID"})
-		return
-	}
-
-	tenantID, err := uuid.Parse(tenantIDStr)
+	tenantID, err := getTenantID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -94,7 +89,7 @@ func (h *InventoryHandler) UpsertInventory(c *gin.Context) {
 // @Tags inventory
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param hub_code query string false "Filter by hub code"
 // @Param seller_id query string false "Filter by seller ID"
 // @Param sku_codes query string false "Comma-separated list of SKU codes to filter by"
@@ -106,15 +101,9 @@ func (h *InventoryHandler) UpsertInventory(c *gin.Context) {
 // @Router /inventory [get]
 func (h *InventoryHandler) GetInventory(c *gin.Context) {
 	// Get tenant ID from header
-	tenantIDStr := c.GetHeader("X-Tenant-ID")
-	if tenantIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing tenant ID"})
-		return
-	}
-
-	tenantID, err := uuid.Parse(tenantIDStr)
+	tenantID, err := getTenantID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tenant ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -184,7 +173,7 @@ func (h *InventoryHandler) GetInventory(c *gin.Context) {
 // @Tags inventory
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param hubCode path string true "Hub code"
 // @Param skuCode path string true "SKU code"
 // @Success 200 {object} models.Inventory
@@ -194,7 +183,7 @@ func (h *InventoryHandler) GetInventory(c *gin.Context) {
 // @Router /inventory/{hubCode}/{skuCode} [get]
 func (h *InventoryHandler) GetInventoryItem(c *gin.Context) {
 	// Get tenant ID from header
-	tenantID, err := getTenantID(c.Request)
+	tenantID, err := getTenantID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -207,7 +196,11 @@ func (h *InventoryHandler) GetInventoryItem(c *gin.Context) {
 	// Get inventory item
 	inventory, err := h.service.GetInventoryItem(c.Request.Context(), tenantID, hubCode, skuCode)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -228,7 +221,7 @@ type ReserveInventoryRequest struct {
 // @Tags inventory
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param request body ReserveInventoryRequest true "Reservation details"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
@@ -237,7 +230,7 @@ type ReserveInventoryRequest struct {
 // @Router /inventory/reserve [post]
 func (h *InventoryHandler) ReserveInventory(c *gin.Context) {
 	// Get tenant ID from header
-	tenantID, err := getTenantID(c.Request)
+	tenantID, err := getTenantID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -287,7 +280,7 @@ type ReleaseInventoryRequest struct {
 // @Tags inventory
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param request body ReleaseInventoryRequest true "Release details"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
@@ -296,7 +289,7 @@ type ReleaseInventoryRequest struct {
 // @Router /inventory/release [post]
 func (h *InventoryHandler) ReleaseInventory(c *gin.Context) {
 	// Get tenant ID from header
-	tenantID, err := getTenantID(c.Request)
+	tenantID, err := getTenantID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -346,7 +339,7 @@ type FulfillInventoryRequest struct {
 // @Tags inventory
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param request body FulfillInventoryRequest true "Fulfillment details"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
@@ -355,7 +348,7 @@ type FulfillInventoryRequest struct {
 // @Router /inventory/fulfill [post]
 func (h *InventoryHandler) FulfillInventory(c *gin.Context) {
 	// Get tenant ID from header
-	tenantID, err := getTenantID(c.Request)
+	tenantID, err := getTenantID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -392,11 +385,58 @@ func (h *InventoryHandler) FulfillInventory(c *gin.Context) {
 	})
 }
 
-// Helper function to get tenant ID from request header
-func getTenantID(r *http.Request) (uuid.UUID, error) {
-	tenantIDStr := r.Header.Get("X-Tenant-ID")
-	if tenantIDStr == "" {
-		return uuid.Nil, fmt.Errorf("missing tenant ID")
+// RestockInventoryRequest represents the request body for restocking returned inventory
+type RestockInventoryRequest struct {
+	HubCode     string `json:"hub_code"`
+	SkuCode     string `json:"sku_code"`
+	Restockable int    `json:"restockable"`
+	Damaged     int    `json:"damaged"`
+}
+
+// RestockInventory takes returned units back into inventory
+// @Summary Restock returned inventory
+// @Description Add restockable returned units to available and damaged units to the non-sellable damaged bucket
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
+// @Param request body RestockInventoryRequest true "Restock details"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /inventory/restock [post]
+func (h *InventoryHandler) RestockInventory(c *gin.Context) {
+	// Get tenant ID from header
+	tenantID, err := getTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Parse request body
+	var req RestockInventoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	// Validate request
+	if req.HubCode == "" || req.SkuCode == "" || req.Restockable < 0 || req.Damaged < 0 || req.Restockable+req.Damaged == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hub_code, sku_code, and a positive restockable or damaged quantity are required"})
+		return
+	}
+
+	// Restock inventory
+	err = h.service.RestockInventory(c.Request.Context(), tenantID, req.HubCode, req.SkuCode, req.Restockable, req.Damaged)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	return uuid.Parse(tenantIDStr)
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Successfully restocked %d and recorded %d damaged of %s at hub %s",
+			req.Restockable, req.Damaged, req.SkuCode, req.HubCode),
+	})
 }
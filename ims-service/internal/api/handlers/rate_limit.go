@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	logger "github.com/omniful/go_commons/log"
+	"github.com/omniful/ims-service/internal/ratelimit"
+	"github.com/omniful/ims-service/internal/service"
+	"github.com/omniful/ims-service/pkg/constants"
+)
+
+// lastRateLimitError is when a failure to take a token was last logged, in Unix seconds
+var lastRateLimitError atomic.Int64
+
+// RateLimitMiddleware takes a token from the tenant's bucket for the route group of each
+// request and rejects the request with 429 and Retry-After when the bucket is empty. It
+// runs after the tenant is known. Requests are let through when Redis cannot be reached.
+func RateLimitMiddleware(rateLimits service.RateLimitService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, err := getTenantID(c)
+		if err != nil {
+			// Handlers reject requests without a tenant
+			c.Next()
+			return
+		}
+
+		group := routeGroup(c)
+		result, err := rateLimits.Take(c.Request.Context(), tenantID, group)
+		if err != nil {
+			if now := time.Now().Unix(); lastRateLimitError.Swap(now) < now-60 {
+				logger.Error("Rate limiting unavailable, letting requests through: " + err.Error())
+			}
+			c.Next()
+			return
+		}
+
+		c.Header(constants.HeaderRateLimitLimit, strconv.Itoa(result.Limit.Burst))
+		c.Header(constants.HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			retryAfter := result.RetryAfterSeconds()
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": fmt.Sprintf("rate limit for %s exceeded, retry in %ds", group, retryAfter),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// routeGroup returns the rate limit group of a request. Validation is a POST but is
+// limited on its own, being what bulk uploads call most.
+func routeGroup(c *gin.Context) string {
+	path := c.FullPath()
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	switch {
+	case strings.HasPrefix(path, constants.EndpointValidation):
+		return ratelimit.GroupValidation
+	case c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead:
+		return ratelimit.GroupReads
+	default:
+		return ratelimit.GroupWrites
+	}
+}
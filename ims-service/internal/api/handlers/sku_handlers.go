@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/auth"
 	"github.com/omniful/ims-service/internal/models"
 	"github.com/omniful/ims-service/internal/service"
 )
@@ -20,12 +21,12 @@ func NewSKUHandler(service service.SKUService) *SKUHandler {
 
 func (h *SKUHandler) RegisterRoutes(r *gin.RouterGroup) {
 	skus := r.Group("/skus")
-	skus.POST("/", h.CreateSKU)
-	skus.GET("/", h.ListSKUs)
-	skus.GET("/:id", h.GetSKU)
-	skus.GET("/code/:code", h.GetSKUByCode)
-	skus.PUT("/:id", h.UpdateSKU)
-	skus.DELETE("/:id", h.DeleteSKU)
+	skus.POST("/", RequireScope(auth.ScopeCatalogWrite), h.CreateSKU)
+	skus.GET("/", RequireScope(auth.ScopeCatalogRead), h.ListSKUs)
+	skus.GET("/:id", RequireScope(auth.ScopeCatalogRead), h.GetSKU)
+	skus.GET("/code/:code", RequireScope(auth.ScopeCatalogRead), h.GetSKUByCode)
+	skus.PUT("/:id", RequireScope(auth.ScopeCatalogWrite), h.UpdateSKU)
+	skus.DELETE("/:id", RequireScope(auth.ScopeCatalogAdmin), h.DeleteSKU)
 }
 
 // CreateSKURequest represents the request body for creating a SKU
@@ -49,21 +50,17 @@ type CreateSKURequest struct {
 // @Tags skus
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param request body CreateSKURequest true "SKU details"
 // @Success 201 {object} models.SKU
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /skus [post]
 func (h *SKUHandler) CreateSKU(c *gin.Context) {
-	tenantIDStr := c.GetHeader("tenant_id")
-	if tenantIDStr == "" {
-		c.JSON(400, gin.H{"error": "tenant_id header is required"})
-		return
-	}
-	tenantID, err := uuid.Parse(tenantIDStr)
+	// Get tenant ID from header
+	tenantID, err := getTenantID(c)
 	if err != nil {
-		c.JSON(400, gin.H{"error": "invalid tenant_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -108,7 +105,7 @@ func (h *SKUHandler) CreateSKU(c *gin.Context) {
 // @Tags skus
 // @Accept json
 // @Produce json
-// @Param tenant_id header string true "Tenant ID"
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
 // @Param seller_id query string false "Filter by seller ID"
 // @Param is_active query boolean false "Filter by active status"
 // @Param page query int false "Page number" default(1)
@@ -119,7 +116,7 @@ func (h *SKUHandler) CreateSKU(c *gin.Context) {
 // @Router /skus [get]
 func (h *SKUHandler) ListSKUs(c *gin.Context) {
 	// Get tenant ID from header
-	tenantID, err := getTenantID(c.Request)
+	tenantID, err := getTenantID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -222,7 +219,7 @@ func (h *SKUHandler) GetSKU(c *gin.Context) {
 // @Router /skus/code/{code} [get]
 func (h *SKUHandler) GetSKUByCode(c *gin.Context) {
 	// Get tenant ID from header
-	tenantID, err := getTenantID(c.Request)
+	tenantID, err := getTenantID(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/auth"
+	"github.com/omniful/ims-service/internal/service"
+	"github.com/omniful/ims-service/pkg/constants"
+)
+
+// TenantMiddleware resolves the tenant named in the X-Tenant-ID header, by code or UUID,
+// for the handlers behind it. The legacy tenant_id header is read when X-Tenant-ID is
+// absent. Requests naming no tenant are passed on, handlers that need one reject them.
+// It is only used when authentication is off, every request is then granted all scopes.
+func TenantMiddleware(tenants service.TenantService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := &auth.Principal{Subject: "anonymous", Scopes: auth.AllScopes, Method: auth.MethodNone}
+		c.Set(constants.ContextKeyPrincipal, principal)
+
+		ref := requestedTenant(c)
+		if ref == "" {
+			c.Next()
+			return
+		}
+
+		tenant, err := tenants.Resolve(c.Request.Context(), ref)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, service.ErrTenantNotFound) || errors.Is(err, service.ErrTenantInactive) {
+				status = http.StatusBadRequest
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": fmt.Sprintf("invalid tenant %q: %v", ref, err)})
+			return
+		}
+
+		principal.TenantID, principal.TenantCode = tenant.ID, tenant.Code
+		c.Set(constants.ContextKeyTenantID, tenant.ID)
+		c.Next()
+	}
+}
+
+// getTenantID returns the tenant of the request, bound to its credential by AuthMiddleware
+// or resolved by TenantMiddleware
+func getTenantID(c *gin.Context) (uuid.UUID, error) {
+	if value, ok := c.Get(constants.ContextKeyTenantID); ok {
+		if tenantID, ok := value.(uuid.UUID); ok {
+			return tenantID, nil
+		}
+	}
+	return uuid.Nil, errors.New(constants.ErrTenantRequired)
+}
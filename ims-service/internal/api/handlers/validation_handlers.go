@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omniful/ims-service/internal/auth"
+	"github.com/omniful/ims-service/internal/models"
+	"github.com/omniful/ims-service/internal/service"
+	"github.com/omniful/ims-service/pkg/constants"
+)
+
+type ValidationHandler struct {
+	service service.ValidationService
+}
+
+func NewValidationHandler(service service.ValidationService) *ValidationHandler {
+	return &ValidationHandler{service: service}
+}
+
+func (h *ValidationHandler) RegisterRoutes(r *gin.RouterGroup) {
+	// Validation only reads the catalog, it is sent as a POST to carry the lines
+	r.POST("/validate", RequireScope(auth.ScopeCatalogRead), h.Validate)
+	r.POST("/validate/batch", RequireScope(auth.ScopeCatalogRead), h.ValidateBatch)
+}
+
+type ValidateRequest struct {
+	SKU      string `json:"sku" binding:"required"`
+	HubID    string `json:"hub_id" binding:"required"`
+	SellerID string `json:"seller_id"`
+}
+
+type ValidateBatchRequest struct {
+	Orders []models.ValidationLine `json:"orders"`
+}
+
+// Validate handles validation of a single order line
+// @Summary Validate an order line
+// @Description Check that a hub and SKU exist and are active, and that the SKU belongs to the seller
+// @Tags validation
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
+// @Param request body ValidateRequest true "Order line"
+// @Success 200 {object} models.ValidationResult
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /validate [post]
+func (h *ValidationHandler) Validate(c *gin.Context) {
+	var req ValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": constants.ErrInvalidRequest})
+		return
+	}
+
+	tenantID, err := getTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.service.ValidateBatch(c.Request.Context(), tenantID, []models.ValidationLine{
+		{SKU: req.SKU, HubID: req.HubID, SellerID: req.SellerID},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := results[0]
+	c.JSON(http.StatusOK, gin.H{
+		"sku_valid":       result.SKUValid,
+		"hub_valid":       result.HubValid,
+		"valid":           result.Valid,
+		"sku_status":      result.SKUStatus,
+		"hub_status":      result.HubStatus,
+		"seller_match":    result.SellerMatch,
+		"hub_carries_sku": result.HubCarriesSKU,
+		"errors":          result.Errors,
+		"message":         "Validation completed",
+	})
+}
+
+// ValidateBatch handles validation of many order lines at once
+// @Summary Validate order lines in bulk
+// @Description Validate order lines with set-based lookups, returning one result per line in order
+// @Tags validation
+// @Accept json
+// @Produce json
+// @Param X-Tenant-ID header string false "Tenant code or ID, taken from the credential when omitted"
+// @Param request body ValidateBatchRequest true "Order lines"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /validate/batch [post]
+func (h *ValidationHandler) ValidateBatch(c *gin.Context) {
+	var req ValidateBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": constants.ErrInvalidRequest})
+		return
+	}
+	if len(req.Orders) > constants.MaxValidationBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d orders can be validated at once", constants.MaxValidationBatch)})
+		return
+	}
+
+	tenantID, err := getTenantID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.service.ValidateBatch(c.Request.Context(), tenantID, req.Orders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	valid := 0
+	for _, result := range results {
+		if result.Valid {
+			valid++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Batch validation completed",
+		"results":     results,
+		"count":       len(results),
+		"valid_count": valid,
+	})
+}
@@ -2,16 +2,22 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/caarlos0/env/v7"
+	"github.com/omniful/ims-service/internal/ratelimit"
 )
 
 type Config struct {
-	Env      string `env:"ENV" envDefault:"development"`
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
+	Env       string `env:"ENV" envDefault:"development"`
+	Server    ServerConfig
+	GRPC      GRPCConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Kafka     KafkaConfig
+	Auth      AuthConfig
+	RateLimit RateLimitConfig
 }
 
 type ServerConfig struct {
@@ -19,6 +25,13 @@ type ServerConfig struct {
 	GracefulShutdownTimeout time.Duration `env:"GRACEFUL_SHUTDOWN_TIMEOUT" envDefault:"10s"`
 }
 
+// GRPCConfig configures the gRPC server for the hot paths OMS calls, run next to the
+// HTTP server
+type GRPCConfig struct {
+	Enabled bool   `env:"GRPC_ENABLED" envDefault:"true"`
+	Address string `env:"GRPC_ADDRESS" envDefault:":9081"`
+}
+
 type DatabaseConfig struct {
 	Host     string `env:"DB_HOST" envDefault:"localhost"`
 	Port     string `env:"DB_PORT" envDefault:"5432"`
@@ -34,6 +47,58 @@ type RedisConfig struct {
 	DB       int    `env:"REDIS_DB" envDefault:"0"`
 }
 
+type KafkaConfig struct {
+	Enabled        bool     `env:"KAFKA_ENABLED" envDefault:"false"`
+	Brokers        []string `env:"KAFKA_BROKERS" envDefault:"localhost:9092" envSeparator:","`
+	InventoryTopic string   `env:"KAFKA_INVENTORY_TOPIC" envDefault:"inventory-events"`
+}
+
+// AuthConfig configures how API requests are authenticated. API keys are always
+// accepted when Mode is enforce; JWTs once a signing key is configured.
+type AuthConfig struct {
+	Mode                  string `env:"AUTH_MODE" envDefault:"enforce"` // enforce or off
+	JWTHS256Secret        string `env:"AUTH_JWT_HS256_SECRET"`
+	JWTRS256PublicKey     string `env:"AUTH_JWT_RS256_PUBLIC_KEY"`
+	JWTRS256PublicKeyFile string `env:"AUTH_JWT_RS256_PUBLIC_KEY_FILE"`
+	JWTIssuer             string `env:"AUTH_JWT_ISSUER"`
+	JWTAudience           string `env:"AUTH_JWT_AUDIENCE" envDefault:"ims-service"`
+}
+
+// Enforced reports whether requests must carry a credential
+func (a *AuthConfig) Enforced() bool {
+	return a.Mode != "off"
+}
+
+// RS256PublicKey returns the PEM public key for RS256 JWTs, read from the file when one
+// is configured
+func (a *AuthConfig) RS256PublicKey() ([]byte, error) {
+	if a.JWTRS256PublicKeyFile != "" {
+		return os.ReadFile(a.JWTRS256PublicKeyFile)
+	}
+	return []byte(a.JWTRS256PublicKey), nil
+}
+
+// RateLimitConfig holds the default token bucket of each route group, per tenant. Rates
+// are requests per second; tenants can be given other limits with cmd/ratelimit.
+type RateLimitConfig struct {
+	Enabled         bool    `env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	ReadRate        float64 `env:"RATE_LIMIT_READ_RATE" envDefault:"50"`
+	ReadBurst       int     `env:"RATE_LIMIT_READ_BURST" envDefault:"100"`
+	WriteRate       float64 `env:"RATE_LIMIT_WRITE_RATE" envDefault:"20"`
+	WriteBurst      int     `env:"RATE_LIMIT_WRITE_BURST" envDefault:"40"`
+	ValidationRate  float64 `env:"RATE_LIMIT_VALIDATION_RATE" envDefault:"10"`
+	ValidationBurst int     `env:"RATE_LIMIT_VALIDATION_BURST" envDefault:"20"`
+}
+
+// Limits returns the default limit of each route group
+func (r *RateLimitConfig) Limits() map[string]ratelimit.Limit {
+	return map[string]ratelimit.Limit{
+		ratelimit.GroupReads:      {Rate: r.ReadRate, Burst: r.ReadBurst},
+		ratelimit.GroupWrites:     {Rate: r.WriteRate, Burst: r.WriteBurst},
+		ratelimit.GroupValidation: {Rate: r.ValidationRate, Burst: r.ValidationBurst},
+	}
+}
+
 func LoadConfig() (*Config, error) {
 	cfg := &Config{}
 
@@ -54,6 +119,25 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err := env.Parse(&cfg.Kafka); err != nil {
+		return nil, err
+	}
+
+	if err := env.Parse(&cfg.Auth); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.Mode != "enforce" && cfg.Auth.Mode != "off" {
+		return nil, fmt.Errorf("AUTH_MODE must be enforce or off, got %q", cfg.Auth.Mode)
+	}
+
+	if err := env.Parse(&cfg.GRPC); err != nil {
+		return nil, err
+	}
+
+	if err := env.Parse(&cfg.RateLimit); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,6 +23,55 @@ type Tenant struct {
 	IsActive    bool   `gorm:"default:true" json:"is_active"`
 }
 
+// MarshalBinary encodes the tenant as JSON so it can be cached in Redis
+func (t *Tenant) MarshalBinary() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// UnmarshalBinary decodes a tenant cached in Redis
+func (t *Tenant) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, t)
+}
+
+// APIKey is a tenant-bound credential. Only the SHA-256 of the key is stored, the key
+// itself is shown once when it is created.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TenantID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"tenant_id"`
+	Name       string     `gorm:"not null;size:255" json:"name"`
+	Prefix     string     `gorm:"not null;size:20" json:"prefix"`
+	KeyHash    string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	Scopes     string     `gorm:"type:text;not null" json:"scopes"` // comma separated
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Usable reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// MarshalBinary encodes the API key as JSON so it can be cached in Redis, keyed by its hash
+func (k *APIKey) MarshalBinary() ([]byte, error) {
+	return json.Marshal(k)
+}
+
+// UnmarshalBinary decodes an API key cached in Redis
+func (k *APIKey) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, k)
+}
+
+// TenantRateLimit overrides the default rate limit of a route group for one tenant
+type TenantRateLimit struct {
+	TenantID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"tenant_id"`
+	RouteGroup string    `gorm:"primaryKey;size:20" json:"route_group"`
+	Rate       float64   `gorm:"not null" json:"rate"` // tokens per second
+	Burst      int       `gorm:"not null" json:"burst"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
 type Hub struct {
 	BaseModel
 	TenantID    uuid.UUID `gorm:"type:uuid;not null;index" json:"tenant_id"`
@@ -36,6 +86,16 @@ type Hub struct {
 	PostalCode  string    `gorm:"size:20" json:"postal_code"`
 }
 
+// MarshalBinary encodes the hub as JSON so it can be cached in Redis
+func (h *Hub) MarshalBinary() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+// UnmarshalBinary decodes a hub cached in Redis
+func (h *Hub) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, h)
+}
+
 type Seller struct {
 	BaseModel
 	TenantID uuid.UUID `gorm:"type:uuid;not null;index" json:"tenant_id"`
@@ -64,6 +124,16 @@ type SKU struct {
 	DimensionUnit string    `gorm:"size:20" json:"dimension_unit,omitempty"`
 }
 
+// MarshalBinary encodes the SKU as JSON so it can be cached in Redis
+func (s *SKU) MarshalBinary() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// UnmarshalBinary decodes a SKU cached in Redis
+func (s *SKU) UnmarshalBinary(data []byte) error {
+	return json.Unmarshal(data, s)
+}
+
 type Inventory struct {
 	BaseModel
 	TenantID  uuid.UUID `gorm:"type:uuid;not null;index" json:"tenant_id"`
@@ -73,10 +143,143 @@ type Inventory struct {
 	Available int       `gorm:"not null;default:0" json:"available"`
 	Reserved  int       `gorm:"not null;default:0" json:"reserved"`
 	InTransit int       `gorm:"not null;default:0" json:"in_transit"`
+	Damaged   int       `gorm:"not null;default:0" json:"damaged"` // held at the hub but not sellable, not part of Quantity
 	SKU       SKU       `gorm:"foreignKey:SkuID" json:"sku,omitempty"`
 	Hub       Hub       `gorm:"foreignKey:HubID" json:"hub,omitempty"`
 }
 
+// Levels returns the current quantities of the inventory item
+func (i *Inventory) Levels() InventoryLevels {
+	return InventoryLevels{
+		Quantity:  i.Quantity,
+		Available: i.Available,
+		Reserved:  i.Reserved,
+		InTransit: i.InTransit,
+		Damaged:   i.Damaged,
+	}
+}
+
+// SetLevels overwrites the quantities of the inventory item
+func (i *Inventory) SetLevels(levels InventoryLevels) {
+	i.Quantity = levels.Quantity
+	i.Available = levels.Available
+	i.Reserved = levels.Reserved
+	i.InTransit = levels.InTransit
+	i.Damaged = levels.Damaged
+}
+
+// InventoryLevels is a snapshot of the quantities of an inventory item. Changes are
+// expressed with the same shape, holding the amount to add to each quantity.
+type InventoryLevels struct {
+	Quantity  int `json:"quantity"`
+	Available int `json:"available"`
+	Reserved  int `json:"reserved"`
+	InTransit int `json:"in_transit"`
+	Damaged   int `json:"damaged"`
+}
+
+// Add returns the levels after applying delta
+func (l InventoryLevels) Add(delta InventoryLevels) InventoryLevels {
+	return InventoryLevels{
+		Quantity:  l.Quantity + delta.Quantity,
+		Available: l.Available + delta.Available,
+		Reserved:  l.Reserved + delta.Reserved,
+		InTransit: l.InTransit + delta.InTransit,
+		Damaged:   l.Damaged + delta.Damaged,
+	}
+}
+
+// Inventory change causes
+const (
+	InventoryCauseStockUpdate = "stock_update"
+	InventoryCauseReserve     = "reserve"
+	InventoryCauseRelease     = "release"
+	InventoryCauseFulfill     = "fulfill"
+	InventoryCauseAdjustment  = "adjustment"
+	InventoryCauseReturn      = "return"
+)
+
+// InventoryChangedEvent is published whenever the quantities of an inventory item change
+type InventoryChangedEvent struct {
+	TenantID   uuid.UUID       `json:"tenant_id"`
+	TenantCode string          `json:"tenant_code,omitempty"`
+	HubID      uuid.UUID       `json:"hub_id"`
+	HubCode    string          `json:"hub_code"`
+	SkuID      uuid.UUID       `json:"sku_id"`
+	SkuCode    string          `json:"sku_code"`
+	Cause      string          `json:"cause"`
+	Before     InventoryLevels `json:"before"`
+	After      InventoryLevels `json:"after"`
+	ChangedAt  time.Time       `json:"changed_at"`
+}
+
+// Outbox event statuses
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+)
+
+// OutboxEvent is an event waiting to be published, written in the same transaction as
+// the change that produced it
+type OutboxEvent struct {
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"event_id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	TenantCode  string     `gorm:"size:100" json:"tenant_code,omitempty"`
+	AggregateID string     `gorm:"not null;size:255" json:"aggregate_id"`
+	EventType   string     `gorm:"not null;size:100" json:"event_type"`
+	Topic       string     `gorm:"not null;size:255" json:"topic"`
+	Key         string     `gorm:"not null;size:255" json:"key"`
+	Payload     string     `gorm:"type:text;not null" json:"payload"`
+	Status      string     `gorm:"not null;size:20;default:pending" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+}
+
+// TableName keeps the outbox table name explicit
+func (OutboxEvent) TableName() string {
+	return "inventory_outbox"
+}
+
+// Validation statuses of a hub or SKU code
+const (
+	CodeStatusActive   = "active"
+	CodeStatusInactive = "inactive"
+	CodeStatusMissing  = "missing"
+)
+
+// ValidationLine is an order line to validate
+type ValidationLine struct {
+	SKU      string `json:"sku"`
+	HubID    string `json:"hub_id"`
+	SellerID string `json:"seller_id,omitempty"` // ID or code of the seller the SKU must belong to
+}
+
+// ValidationResult is the outcome of validating one order line. A line is valid when its
+// hub and SKU are active and the SKU belongs to the requested seller. HubCarriesSKU is
+// informational, a hub without an inventory record for the SKU can still take backorders.
+type ValidationResult struct {
+	SKU           string   `json:"sku"`
+	HubID         string   `json:"hub_id"`
+	SellerID      string   `json:"seller_id,omitempty"`
+	Valid         bool     `json:"valid"`
+	SKUValid      bool     `json:"sku_valid"`
+	HubValid      bool     `json:"hub_valid"`
+	SKUStatus     string   `json:"sku_status"`
+	HubStatus     string   `json:"hub_status"`
+	SellerMatch   *bool    `json:"seller_match,omitempty"`
+	HubCarriesSKU bool     `json:"hub_carries_sku"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// HubSKU identifies the inventory of a SKU at a hub
+type HubSKU struct {
+	HubID uuid.UUID
+	SkuID uuid.UUID
+}
+
 // InventoryUpdate represents a single inventory update operation
 type InventoryUpdate struct {
 	HubCode  string `json:"hub_code" validate:"required"`
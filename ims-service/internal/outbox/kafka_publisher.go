@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	gokafka "github.com/omniful/go_commons/kafka"
+	"github.com/omniful/go_commons/pubsub"
+)
+
+// KafkaPublisher publishes outbox events with the go_commons Kafka producer
+type KafkaPublisher struct {
+	producer *gokafka.ProducerClient
+}
+
+// NewKafkaPublisher creates a publisher connected to brokers
+func NewKafkaPublisher(brokers []string) (*KafkaPublisher, error) {
+	producer := gokafka.NewProducer(
+		gokafka.WithBrokers(brokers),
+		gokafka.WithClientID("ims-service-producer"),
+		gokafka.WithKafkaVersion("2.8.1"),
+	)
+	if producer == nil {
+		return nil, fmt.Errorf("failed to create kafka producer")
+	}
+	return &KafkaPublisher{producer: producer}, nil
+}
+
+// Publish publishes a raw message to topic
+func (p *KafkaPublisher) Publish(ctx context.Context, topic, key string, value []byte, headers map[string]string) error {
+	msg := &pubsub.Message{
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: headers,
+	}
+	if err := p.producer.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("kafka publish to %s failed: %w", topic, err)
+	}
+	return nil
+}
+
+// Close closes the underlying producer
+func (p *KafkaPublisher) Close() {
+	p.producer.Close()
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logger "github.com/omniful/go_commons/log"
+	"github.com/omniful/ims-service/internal/models"
+	"github.com/omniful/ims-service/internal/repository"
+)
+
+// maxRelayBackoff caps how long the relay waits after a failed publish
+const maxRelayBackoff = time.Minute
+
+// Event envelope headers, following the CloudEvents Kafka binding in binary mode
+const (
+	headerSpecVersion   = "ce_specversion"
+	headerEventID       = "ce_id"
+	headerEventSource   = "ce_source"
+	headerEventType     = "ce_type"
+	headerEventTime     = "ce_time"
+	headerEventSubject  = "ce_subject"
+	headerTenantID      = "ce_tenantid"
+	headerTenantCode    = "ce_tenantcode"
+	headerSchemaVersion = "ce_schemaversion"
+	headerContentType   = "content-type"
+)
+
+// Publisher publishes a raw message to a topic
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, value []byte, headers map[string]string) error
+}
+
+// Relay publishes pending outbox events in the order they were written. Events are
+// marked sent only after a successful publish, so delivery is at-least-once.
+type Relay struct {
+	repo      repository.OutboxRepository
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+// NewRelay creates a relay that polls the outbox every interval
+func NewRelay(repo repository.OutboxRepository, publisher Publisher, interval time.Duration, batchSize int) *Relay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &Relay{repo: repo, publisher: publisher, interval: interval, batchSize: batchSize}
+}
+
+// Start runs the relay until ctx is cancelled
+func (r *Relay) Start(ctx context.Context) {
+	go func() {
+		logger.Info(fmt.Sprintf("Outbox relay started (interval %s, batch %d)", r.interval, r.batchSize))
+		backoff := r.interval
+
+		for {
+			sent, err := r.repo.PublishPending(ctx, r.batchSize, func(event *models.OutboxEvent) error {
+				return r.publisher.Publish(ctx, event.Topic, event.Key, []byte(event.Payload), Headers(event))
+			})
+			wait := r.interval
+			switch {
+			case err != nil:
+				logger.Error(fmt.Sprintf("Outbox relay: %v (retrying in %s)", err, backoff))
+				wait = backoff
+				backoff *= 2
+				if backoff > maxRelayBackoff {
+					backoff = maxRelayBackoff
+				}
+			case sent == r.batchSize:
+				// More events are waiting, keep draining
+				backoff = r.interval
+				wait = 0
+			default:
+				backoff = r.interval
+			}
+
+			select {
+			case <-ctx.Done():
+				logger.Info("Outbox relay stopped")
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// Headers returns the envelope headers of an outbox event. ce_tenantid is the tenant UUID,
+// ce_tenantcode its code when known.
+func Headers(event *models.OutboxEvent) map[string]string {
+	headers := map[string]string{
+		headerSpecVersion:   "1.0",
+		headerEventID:       event.EventID.String(),
+		headerEventSource:   "ims-service",
+		headerEventType:     event.EventType,
+		headerEventTime:     event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		headerEventSubject:  event.AggregateID,
+		headerTenantID:      event.TenantID.String(),
+		headerSchemaVersion: "1",
+		headerContentType:   "application/json",
+	}
+	if event.TenantCode != "" {
+		headers[headerTenantCode] = event.TenantCode
+	}
+	return headers
+}
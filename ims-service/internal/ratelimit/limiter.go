@@ -0,0 +1,107 @@
+// Package ratelimit limits request rates with token buckets kept in Redis, so every IMS
+// instance draws from the same buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Route groups, each with its own bucket per tenant
+const (
+	GroupReads      = "reads"
+	GroupWrites     = "writes"
+	GroupValidation = "validation"
+)
+
+// Groups lists every route group
+var Groups = []string{GroupReads, GroupWrites, GroupValidation}
+
+// Limit is a token bucket refilled at Rate tokens per second and holding up to Burst
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// RetryAfter is how long until the request would be allowed, zero when it is
+	RetryAfter time.Duration
+}
+
+// takeScript refills the bucket for the time elapsed since it was last used, then takes
+// cost tokens if it holds enough. It uses the Redis clock so instances need not agree on
+// the time. Returns allowed (0 or 1), the tokens left and the milliseconds to wait.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= cost then
+  tokens = tokens - cost
+  allowed = 1
+else
+  wait = math.ceil((cost - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, math.floor(tokens), wait}
+`)
+
+// Limiter takes tokens from buckets kept in Redis
+type Limiter struct {
+	redis *redis.Client
+}
+
+// NewLimiter creates a limiter keeping its buckets in client
+func NewLimiter(client *redis.Client) *Limiter {
+	return &Limiter{redis: client}
+}
+
+// Take takes one token from the bucket named key, which holds limit
+func (l *Limiter) Take(ctx context.Context, key string, limit Limit) (*Result, error) {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return nil, fmt.Errorf("invalid rate limit %v/s burst %d", limit.Rate, limit.Burst)
+	}
+	values, err := takeScript.Run(ctx, l.redis, []string{key}, limit.Rate, limit.Burst, 1).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds for the Retry-After header,
+// at least 1
+func (r *Result) RetryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(r.RetryAfter.Seconds())))
+}
+
+// Key returns the Redis key of a tenant's bucket for a route group
+func Key(tenantID, group string) string {
+	return fmt.Sprintf("ratelimit:%s:%s", tenantID, group)
+}
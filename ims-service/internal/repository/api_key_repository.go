@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/db/sql/postgres"
+	"github.com/omniful/ims-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrAPIKeyNotFound is returned when no API key has the given hash or ID
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	// GetByHash returns the key whose SHA-256 is hash, revoked and expired keys included
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	List(ctx context.Context, tenantID uuid.UUID) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	dbCluster *postgres.DbCluster
+	redis     *redis.Client
+}
+
+func NewAPIKeyRepository(dbCluster *postgres.DbCluster, redis *redis.Client) APIKeyRepository {
+	return &apiKeyRepository{
+		dbCluster: dbCluster,
+		redis:     redis,
+	}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	db := r.dbCluster.GetMasterDB(ctx)
+	if err := db.WithContext(ctx).Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	// Try to get from cache first
+	cacheKey := apiKeyCacheKey(hash)
+	var key models.APIKey
+	if err := r.redis.Get(ctx, cacheKey).Scan(&key); err == nil {
+		key.KeyHash = hash
+		return &key, nil
+	}
+
+	// Get from database
+	db := r.dbCluster.GetMasterDB(ctx)
+	key = models.APIKey{}
+	if err := db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	// Cache the key, revoking it evicts the entry
+	r.redis.Set(context.Background(), cacheKey, &key, 5*time.Minute)
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, tenantID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	db := r.dbCluster.GetMasterDB(ctx)
+	if err := db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at").
+		Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	db := r.dbCluster.GetMasterDB(ctx)
+	key := models.APIKey{}
+	if err := db.WithContext(ctx).Where("id = ?", id).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w with id: %s", ErrAPIKeyNotFound, id)
+		}
+		return fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	if err := db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	// Invalidate cache
+	r.redis.Del(context.Background(), apiKeyCacheKey(key.KeyHash))
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	db := r.dbCluster.GetMasterDB(ctx)
+	if err := db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error; err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
+
+func apiKeyCacheKey(hash string) string {
+	return fmt.Sprintf("apikey:%s", hash)
+}
@@ -16,6 +16,9 @@ type HubRepository interface {
 	Create(ctx context.Context, hub *models.Hub) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Hub, error)
 	GetByCode(ctx context.Context, tenantID uuid.UUID, code string) (*models.Hub, error)
+	// GetByCodes returns the hubs with the given distinct codes keyed by code, reading the cache
+	// with one MGET and the rest with one query. Unknown codes are left out.
+	GetByCodes(ctx context.Context, tenantID uuid.UUID, codes []string) (map[string]*models.Hub, error)
 	List(ctx context.Context, tenantID uuid.UUID, page, pageSize int) ([]models.Hub, int64, error)
 	Update(ctx context.Context, hub *models.Hub) error
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -106,6 +109,51 @@ func (r *hubRepository) GetByCode(ctx context.Context, tenantID uuid.UUID, code
 	return &hub, nil
 }
 
+func (r *hubRepository) GetByCodes(ctx context.Context, tenantID uuid.UUID, codes []string) (map[string]*models.Hub, error) {
+	hubs := make(map[string]*models.Hub, len(codes))
+	if len(codes) == 0 {
+		return hubs, nil
+	}
+
+	// Resolve what we can from the cache in one round trip
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = fmt.Sprintf("hub:code:%s:%s", tenantID, code)
+	}
+	cached, err := r.redis.MGet(ctx, keys...).Result()
+	var missing []string
+	for i, code := range codes {
+		if err == nil {
+			if value, ok := cached[i].(string); ok {
+				hub := &models.Hub{}
+				if hub.UnmarshalBinary([]byte(value)) == nil {
+					hubs[code] = hub
+					continue
+				}
+			}
+		}
+		missing = append(missing, code)
+	}
+	if len(missing) == 0 {
+		return hubs, nil
+	}
+
+	// Get the rest from database
+	var found []models.Hub
+	if err := r.dbCluster.GetMasterDB(ctx).WithContext(ctx).
+		Where("tenant_id = ? AND code IN ?", tenantID, missing).
+		Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get hubs: %w", err)
+	}
+	for i := range found {
+		hub := &found[i]
+		hubs[hub.Code] = hub
+		r.cacheHub(hub)
+	}
+
+	return hubs, nil
+}
+
 func (r *hubRepository) List(ctx context.Context, tenantID uuid.UUID, page, pageSize int) ([]models.Hub, int64, error) {
 	var hubs []models.Hub
 	var count int64
@@ -12,6 +12,7 @@ import (
 	"github.com/omniful/go_commons/db/sql/postgres"
 	"github.com/omniful/ims-service/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InventoryRepository interface {
@@ -29,21 +30,27 @@ type InventoryRepository interface {
 	UpdateInTransitQuantity(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, delta int) error
 	// GetInventoryWithLock gets an inventory item with a row lock for update
 	GetInventoryWithLock(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string) (*models.Inventory, error)
+	// CarriedBy reports which of the given hubs hold an inventory record for which of the given SKUs
+	CarriedBy(ctx context.Context, tenantID uuid.UUID, hubIDs, skuIDs []uuid.UUID) (map[models.HubSKU]bool, error)
+	// ApplyDelta atomically adds delta to an inventory item and records an inventory.changed event
+	ApplyDelta(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, delta models.InventoryLevels, cause string) (*models.Inventory, error)
 }
 
 type inventoryRepository struct {
-	dbCluster *postgres.DbCluster
-	hubRepo   HubRepository
-	skuRepo   SKURepository
-	redis     *redis.Client
+	dbCluster  *postgres.DbCluster
+	hubRepo    HubRepository
+	skuRepo    SKURepository
+	outboxRepo OutboxRepository
+	redis      *redis.Client
 }
 
-func NewInventoryRepository(dbCluster *postgres.DbCluster, hubRepo HubRepository, skuRepo SKURepository, redis *redis.Client) InventoryRepository {
+func NewInventoryRepository(dbCluster *postgres.DbCluster, hubRepo HubRepository, skuRepo SKURepository, outboxRepo OutboxRepository, redis *redis.Client) InventoryRepository {
 	return &inventoryRepository{
-		dbCluster: dbCluster,
-		hubRepo:   hubRepo,
-		skuRepo:   skuRepo,
-		redis:     redis,
+		dbCluster:  dbCluster,
+		hubRepo:    hubRepo,
+		skuRepo:    skuRepo,
+		outboxRepo: outboxRepo,
+		redis:      redis,
 	}
 }
 
@@ -75,19 +82,24 @@ func (r *inventoryRepository) UpsertInventory(ctx context.Context, tenantID uuid
 			return fmt.Errorf("invalid SKU code %s: %w", update.SkuCode, err)
 		}
 
-		// Try to update existing record
-		result := tx.Model(&models.Inventory{}).
+		// Lock the existing record so the event carries the quantity it replaced
+		var inv models.Inventory
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("tenant_id = ? AND hub_id = ? AND sku_id = ?", tenantID, hub.ID, sku.ID).
-			Update("quantity", update.Quantity)
+			First(&inv).Error
 
-		if result.Error != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to update inventory: %w", result.Error)
-		}
-
-		// If no rows were updated, insert new record
-		if result.RowsAffected == 0 {
-			inv := models.Inventory{
+		var before models.InventoryLevels
+		switch {
+		case err == nil:
+			before = inv.Levels()
+			if err := tx.Model(&inv).Update("quantity", update.Quantity).Error; err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to update inventory: %w", err)
+			}
+			inv.Quantity = update.Quantity
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// If no record exists, insert a new one
+			inv = models.Inventory{
 				TenantID: tenantID,
 				HubID:    hub.ID,
 				SkuID:    sku.ID,
@@ -97,6 +109,27 @@ func (r *inventoryRepository) UpsertInventory(ctx context.Context, tenantID uuid
 				tx.Rollback()
 				return fmt.Errorf("failed to insert inventory: %w", err)
 			}
+		default:
+			tx.Rollback()
+			return fmt.Errorf("failed to update inventory: %w", err)
+		}
+
+		if before == inv.Levels() {
+			continue
+		}
+		if err := r.outboxRepo.AddInventoryChanged(tx, &models.InventoryChangedEvent{
+			TenantID:  tenantID,
+			HubID:     hub.ID,
+			HubCode:   hub.Code,
+			SkuID:     sku.ID,
+			SkuCode:   sku.Code,
+			Cause:     models.InventoryCauseStockUpdate,
+			Before:    before,
+			After:     inv.Levels(),
+			ChangedAt: time.Now(),
+		}); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
 
@@ -239,61 +272,37 @@ func (r *inventoryRepository) GetInventoryItem(ctx context.Context, tenantID uui
 	return &inv, nil
 }
 
-func (r *inventoryRepository) UpdateAvailableQuantity(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, delta int) error {
-	// Get inventory item
-	inv, err := r.GetInventoryItem(ctx, tenantID, hubCode, skuCode)
-	if err != nil {
-		return fmt.Errorf("failed to get inventory: %w", err)
+func (r *inventoryRepository) CarriedBy(ctx context.Context, tenantID uuid.UUID, hubIDs, skuIDs []uuid.UUID) (map[models.HubSKU]bool, error) {
+	carried := make(map[models.HubSKU]bool)
+	if len(hubIDs) == 0 || len(skuIDs) == 0 {
+		return carried, nil
 	}
 
-	// Update available quantity atomically
-	result := r.dbCluster.GetMasterDB(ctx).Model(&models.Inventory{}).
-		Where("tenant_id = ? AND hub_id = ? AND sku_id = ?",
-			tenantID, inv.HubID, inv.SkuID).
-		Updates(map[string]interface{}{
-			"available": gorm.Expr("available + ?", delta),
-		})
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to update available quantity: %w", result.Error)
+	var pairs []models.HubSKU
+	if err := r.dbCluster.GetMasterDB(ctx).WithContext(ctx).
+		Model(&models.Inventory{}).
+		Select("hub_id, sku_id").
+		Where("tenant_id = ? AND hub_id IN ? AND sku_id IN ?", tenantID, hubIDs, skuIDs).
+		Scan(&pairs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get carried SKUs: %w", err)
 	}
-
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("inventory record is out of date, please refresh and try again")
+	for _, pair := range pairs {
+		carried[pair] = true
 	}
+	return carried, nil
+}
 
-	// Invalidate cache
-	r.invalidateCache(ctx, tenantID, hubCode, skuCode)
-
+func (r *inventoryRepository) UpdateAvailableQuantity(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, delta int) error {
+	if _, err := r.ApplyDelta(ctx, tenantID, hubCode, skuCode, models.InventoryLevels{Available: delta}, models.InventoryCauseAdjustment); err != nil {
+		return fmt.Errorf("failed to update available quantity: %w", err)
+	}
 	return nil
 }
 
 func (r *inventoryRepository) UpdateReservedQuantity(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, delta int) error {
-	// Get inventory item
-	inv, err := r.GetInventoryItem(ctx, tenantID, hubCode, skuCode)
-	if err != nil {
-		return fmt.Errorf("failed to get inventory: %w", err)
-	}
-
-	// Update reserved quantity atomically
-	result := r.dbCluster.GetMasterDB(ctx).Model(&models.Inventory{}).
-		Where("tenant_id = ? AND hub_id = ? AND sku_id = ?",
-			tenantID, inv.HubID, inv.SkuID).
-		Updates(map[string]interface{}{
-			"reserved": gorm.Expr("reserved + ?", delta),
-		})
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to update reserved quantity: %w", result.Error)
-	}
-
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("inventory record is out of date, please refresh and try again")
+	if _, err := r.ApplyDelta(ctx, tenantID, hubCode, skuCode, models.InventoryLevels{Reserved: delta}, models.InventoryCauseAdjustment); err != nil {
+		return fmt.Errorf("failed to update reserved quantity: %w", err)
 	}
-
-	// Invalidate cache
-	r.invalidateCache(ctx, tenantID, hubCode, skuCode)
-
 	return nil
 }
 
@@ -328,31 +337,9 @@ func (r *inventoryRepository) invalidateCache(ctx context.Context, tenantID uuid
 }
 
 func (r *inventoryRepository) UpdateInTransitQuantity(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, delta int) error {
-	// Get inventory item
-	inv, err := r.GetInventoryItem(ctx, tenantID, hubCode, skuCode)
-	if err != nil {
-		return fmt.Errorf("failed to get inventory: %w", err)
-	}
-
-	// Update in-transit quantity atomically
-	result := r.dbCluster.GetMasterDB(ctx).Model(&models.Inventory{}).
-		Where("tenant_id = ? AND hub_id = ? AND sku_id = ?",
-			tenantID, inv.HubID, inv.SkuID).
-		Updates(map[string]interface{}{
-			"in_transit": gorm.Expr("in_transit + ?", delta),
-		})
-
-	if result.Error != nil {
-		return fmt.Errorf("failed to update in-transit quantity: %w", result.Error)
-	}
-
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("inventory record is out of date, please refresh and try again")
+	if _, err := r.ApplyDelta(ctx, tenantID, hubCode, skuCode, models.InventoryLevels{InTransit: delta}, models.InventoryCauseAdjustment); err != nil {
+		return fmt.Errorf("failed to update in-transit quantity: %w", err)
 	}
-
-	// Invalidate cache
-	r.invalidateCache(ctx, tenantID, hubCode, skuCode)
-
 	return nil
 }
 
@@ -392,3 +379,84 @@ func (r *inventoryRepository) GetInventoryWithLock(ctx context.Context, tenantID
 
 	return &inv, nil
 }
+
+func (r *inventoryRepository) ApplyDelta(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, delta models.InventoryLevels, cause string) (*models.Inventory, error) {
+	hub, err := r.hubRepo.GetByCode(ctx, tenantID, hubCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hub: %w", err)
+	}
+
+	sku, err := r.skuRepo.GetByCode(ctx, tenantID, skuCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SKU: %w", err)
+	}
+
+	var inv models.Inventory
+	err = r.dbCluster.GetMasterDB(ctx).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent changes are applied one after another
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tenant_id = ? AND hub_id = ? AND sku_id = ?", tenantID, hub.ID, sku.ID).
+			First(&inv).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			inv = models.Inventory{TenantID: tenantID, HubID: hub.ID, SkuID: sku.ID}
+			err = tx.Create(&inv).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock inventory: %w", err)
+		}
+
+		before := inv.Levels()
+		after := before.Add(delta)
+		if err := checkLevels(before, after, delta); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&inv).Updates(map[string]interface{}{
+			"quantity":   after.Quantity,
+			"available":  after.Available,
+			"reserved":   after.Reserved,
+			"in_transit": after.InTransit,
+			"damaged":    after.Damaged,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update inventory: %w", err)
+		}
+		inv.SetLevels(after)
+
+		return r.outboxRepo.AddInventoryChanged(tx, &models.InventoryChangedEvent{
+			TenantID:  tenantID,
+			HubID:     hub.ID,
+			HubCode:   hub.Code,
+			SkuID:     sku.ID,
+			SkuCode:   sku.Code,
+			Cause:     cause,
+			Before:    before,
+			After:     after,
+			ChangedAt: time.Now(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Invalidate cache
+	r.invalidateCache(ctx, tenantID, hubCode, skuCode)
+
+	return &inv, nil
+}
+
+// checkLevels rejects a change that would take any quantity below zero
+func checkLevels(before, after, delta models.InventoryLevels) error {
+	switch {
+	case after.Available < 0:
+		return fmt.Errorf("insufficient available quantity. available: %d, requested: %d", before.Available, -delta.Available)
+	case after.Reserved < 0:
+		return fmt.Errorf("insufficient reserved quantity. reserved: %d, requested: %d", before.Reserved, -delta.Reserved)
+	case after.Quantity < 0:
+		return fmt.Errorf("insufficient quantity. quantity: %d, requested: %d", before.Quantity, -delta.Quantity)
+	case after.InTransit < 0:
+		return fmt.Errorf("insufficient in-transit quantity. in_transit: %d, requested: %d", before.InTransit, -delta.InTransit)
+	case after.Damaged < 0:
+		return fmt.Errorf("insufficient damaged quantity. damaged: %d, requested: %d", before.Damaged, -delta.Damaged)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/db/sql/postgres"
+	"github.com/omniful/ims-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EventInventoryChanged is the type of the event published when inventory quantities change
+const EventInventoryChanged = "inventory.changed"
+
+type OutboxRepository interface {
+	// AddInventoryChanged records an inventory.changed event in tx, published once tx commits.
+	// The event carries the tenant's code as well as its ID.
+	AddInventoryChanged(tx *gorm.DB, event *models.InventoryChangedEvent) error
+	// PublishPending passes up to limit pending events to publish in the order they were
+	// written, stopping at the first failure. It returns how many events were published.
+	PublishPending(ctx context.Context, limit int, publish func(event *models.OutboxEvent) error) (int, error)
+	// CountPending returns the number of events not yet published
+	CountPending(ctx context.Context) (int64, error)
+}
+
+type outboxRepository struct {
+	dbCluster *postgres.DbCluster
+	topic     string
+}
+
+func NewOutboxRepository(dbCluster *postgres.DbCluster, topic string) OutboxRepository {
+	return &outboxRepository{
+		dbCluster: dbCluster,
+		topic:     topic,
+	}
+}
+
+func (r *outboxRepository) AddInventoryChanged(tx *gorm.DB, event *models.InventoryChangedEvent) error {
+	if event.TenantCode == "" {
+		var codes []string
+		if err := tx.Model(&models.Tenant{}).Where("id = ?", event.TenantID).Pluck("code", &codes).Error; err != nil {
+			return fmt.Errorf("failed to look up tenant code: %w", err)
+		}
+		if len(codes) > 0 {
+			event.TenantCode = codes[0]
+		}
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory event: %w", err)
+	}
+
+	// Keyed by hub and SKU so changes to the same item are consumed in order
+	key := fmt.Sprintf("%s:%s:%s", event.TenantID, event.HubCode, event.SkuCode)
+	outboxEvent := &models.OutboxEvent{
+		EventID:     uuid.New(),
+		TenantID:    event.TenantID,
+		TenantCode:  event.TenantCode,
+		AggregateID: key,
+		EventType:   EventInventoryChanged,
+		Topic:       r.topic,
+		Key:         key,
+		Payload:     string(payload),
+		Status:      models.OutboxStatusPending,
+		CreatedAt:   event.ChangedAt,
+	}
+	if err := tx.Create(outboxEvent).Error; err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) PublishPending(ctx context.Context, limit int, publish func(event *models.OutboxEvent) error) (int, error) {
+	var (
+		sent       int
+		publishErr error
+	)
+	err := r.dbCluster.GetMasterDB(ctx).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the batch keeps a second relay from publishing the same events concurrently
+		var events []models.OutboxEvent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ?", models.OutboxStatusPending).
+			Order("id").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return fmt.Errorf("failed to read outbox: %w", err)
+		}
+
+		for i := range events {
+			event := &events[i]
+			if publishErr = publish(event); publishErr != nil {
+				if err := tx.Model(event).Updates(map[string]interface{}{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": publishErr.Error(),
+				}).Error; err != nil {
+					return fmt.Errorf("failed to record outbox failure: %w", err)
+				}
+				return nil
+			}
+
+			// A crash before commit republishes the event, consumers must tolerate duplicates
+			if err := tx.Model(event).Updates(map[string]interface{}{
+				"status":     models.OutboxStatusSent,
+				"sent_at":    time.Now(),
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": "",
+			}).Error; err != nil {
+				return fmt.Errorf("failed to mark outbox event sent: %w", err)
+			}
+			sent++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sent, publishErr
+}
+
+func (r *outboxRepository) CountPending(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.dbCluster.GetMasterDB(ctx).WithContext(ctx).
+		Model(&models.OutboxEvent{}).
+		Where("status = ?", models.OutboxStatusPending).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count pending outbox events: %w", err)
+	}
+	return count, nil
+}
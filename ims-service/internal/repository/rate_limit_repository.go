@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/db/sql/postgres"
+	"github.com/omniful/ims-service/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+type RateLimitRepository interface {
+	// ListByTenant returns the rate limit overrides of a tenant, cached for a minute
+	ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]models.TenantRateLimit, error)
+	Upsert(ctx context.Context, limit *models.TenantRateLimit) error
+	Delete(ctx context.Context, tenantID uuid.UUID, group string) error
+}
+
+type rateLimitRepository struct {
+	dbCluster *postgres.DbCluster
+	redis     *redis.Client
+}
+
+func NewRateLimitRepository(dbCluster *postgres.DbCluster, redis *redis.Client) RateLimitRepository {
+	return &rateLimitRepository{
+		dbCluster: dbCluster,
+		redis:     redis,
+	}
+}
+
+func (r *rateLimitRepository) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]models.TenantRateLimit, error) {
+	// Try to get from cache first, every request of the tenant reads it
+	cacheKey := rateLimitCacheKey(tenantID)
+	var limits []models.TenantRateLimit
+	if data, err := r.redis.Get(ctx, cacheKey).Bytes(); err == nil {
+		if err := json.Unmarshal(data, &limits); err == nil {
+			return limits, nil
+		}
+	}
+
+	// Get from database
+	db := r.dbCluster.GetMasterDB(ctx)
+	if err := db.WithContext(ctx).Where("tenant_id = ?", tenantID).Find(&limits).Error; err != nil {
+		return nil, fmt.Errorf("failed to list rate limits: %w", err)
+	}
+
+	// Cache the overrides, tenants without any are cached as an empty list
+	if data, err := json.Marshal(limits); err == nil {
+		r.redis.Set(context.Background(), cacheKey, data, time.Minute)
+	}
+
+	return limits, nil
+}
+
+func (r *rateLimitRepository) Upsert(ctx context.Context, limit *models.TenantRateLimit) error {
+	db := r.dbCluster.GetMasterDB(ctx)
+	if err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "route_group"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "burst", "updated_at"}),
+	}).Create(limit).Error; err != nil {
+		return fmt.Errorf("failed to save rate limit: %w", err)
+	}
+
+	// Invalidate cache
+	r.redis.Del(context.Background(), rateLimitCacheKey(limit.TenantID))
+
+	return nil
+}
+
+func (r *rateLimitRepository) Delete(ctx context.Context, tenantID uuid.UUID, group string) error {
+	db := r.dbCluster.GetMasterDB(ctx)
+	if err := db.WithContext(ctx).
+		Where("tenant_id = ? AND route_group = ?", tenantID, group).
+		Delete(&models.TenantRateLimit{}).Error; err != nil {
+		return fmt.Errorf("failed to delete rate limit: %w", err)
+	}
+
+	// Invalidate cache
+	r.redis.Del(context.Background(), rateLimitCacheKey(tenantID))
+
+	return nil
+}
+
+func rateLimitCacheKey(tenantID uuid.UUID) string {
+	return fmt.Sprintf("tenant:ratelimits:%s", tenantID.String())
+}
@@ -15,6 +15,9 @@ type SKURepository interface {
 	Create(ctx context.Context, sku *models.SKU) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.SKU, error)
 	GetByCode(ctx context.Context, tenantID uuid.UUID, code string) (*models.SKU, error)
+	// GetByCodes returns the SKUs with the given distinct codes keyed by code, reading the cache
+	// with one MGET and the rest with one query. Unknown codes are left out.
+	GetByCodes(ctx context.Context, tenantID uuid.UUID, codes []string) (map[string]*models.SKU, error)
 	List(ctx context.Context, filter models.SKUFilter) ([]models.SKU, int64, error)
 	Update(ctx context.Context, sku *models.SKU) error
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -115,6 +118,52 @@ func (r *skuRepository) GetByCode(ctx context.Context, tenantID uuid.UUID, code
 	return &sku, nil
 }
 
+func (r *skuRepository) GetByCodes(ctx context.Context, tenantID uuid.UUID, codes []string) (map[string]*models.SKU, error) {
+	skus := make(map[string]*models.SKU, len(codes))
+	if len(codes) == 0 {
+		return skus, nil
+	}
+
+	// Resolve what we can from the cache in one round trip
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = fmt.Sprintf("sku:code:%s:%s", tenantID, code)
+	}
+	cached, err := r.redis.MGet(ctx, keys...).Result()
+	var missing []string
+	for i, code := range codes {
+		if err == nil {
+			if value, ok := cached[i].(string); ok {
+				sku := &models.SKU{}
+				if sku.UnmarshalBinary([]byte(value)) == nil {
+					skus[code] = sku
+					continue
+				}
+			}
+		}
+		missing = append(missing, code)
+	}
+	if len(missing) == 0 {
+		return skus, nil
+	}
+
+	// Get the rest from database
+	var found []models.SKU
+	if err := r.dbCluster.GetMasterDB(ctx).WithContext(ctx).
+		Where("tenant_id = ? AND code IN ?", tenantID, missing).
+		Preload("Seller").
+		Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SKUs: %w", err)
+	}
+	for i := range found {
+		sku := &found[i]
+		skus[sku.Code] = sku
+		r.cacheSKU(sku)
+	}
+
+	return skus, nil
+}
+
 func (r *skuRepository) List(ctx context.Context, filter models.SKUFilter) ([]models.SKU, int64, error) {
 	var skus []models.SKU
 	var count int64
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/db/sql/postgres"
+	"github.com/omniful/ims-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrTenantNotFound is returned when no tenant has the requested ID or code
+var ErrTenantNotFound = errors.New("tenant not found")
+
+type TenantRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Tenant, error)
+	GetByCode(ctx context.Context, code string) (*models.Tenant, error)
+}
+
+type tenantRepository struct {
+	dbCluster *postgres.DbCluster
+	redis     *redis.Client
+}
+
+func NewTenantRepository(dbCluster *postgres.DbCluster, redis *redis.Client) TenantRepository {
+	return &tenantRepository{
+		dbCluster: dbCluster,
+		redis:     redis,
+	}
+}
+
+func (r *tenantRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Tenant, error) {
+	// Try to get from cache first
+	cacheKey := fmt.Sprintf("tenant:%s", id.String())
+	var tenant models.Tenant
+	if err := r.redis.Get(ctx, cacheKey).Scan(&tenant); err == nil {
+		return &tenant, nil
+	}
+
+	// Get from database
+	tenant = models.Tenant{BaseModel: models.BaseModel{ID: id}}
+	db := r.dbCluster.GetMasterDB(ctx)
+	if err := db.WithContext(ctx).First(&tenant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w with id: %s", ErrTenantNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	// Cache the tenant
+	r.cacheTenant(&tenant)
+
+	return &tenant, nil
+}
+
+func (r *tenantRepository) GetByCode(ctx context.Context, code string) (*models.Tenant, error) {
+	// Try to get from cache first
+	cacheKey := fmt.Sprintf("tenant:code:%s", code)
+	var tenant models.Tenant
+	if err := r.redis.Get(ctx, cacheKey).Scan(&tenant); err == nil {
+		return &tenant, nil
+	}
+
+	// Get from database
+	db := r.dbCluster.GetMasterDB(ctx)
+	tenant = models.Tenant{}
+	if err := db.WithContext(ctx).Where("code = ?", code).First(&tenant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w with code: %s", ErrTenantNotFound, code)
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	// Cache the tenant
+	r.cacheTenant(&tenant)
+
+	return &tenant, nil
+}
+
+func (r *tenantRepository) cacheTenant(tenant *models.Tenant) {
+	if tenant == nil {
+		return
+	}
+
+	ctx := context.Background()
+	cacheKey := fmt.Sprintf("tenant:%s", tenant.ID.String())
+	codeCacheKey := fmt.Sprintf("tenant:code:%s", tenant.Code)
+
+	// Tenants rarely change, a short TTL bounds how long a deactivation takes to apply
+	r.redis.Set(ctx, cacheKey, tenant, 5*time.Minute)
+	r.redis.Set(ctx, codeCacheKey, tenant, 5*time.Minute)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/auth"
+	"github.com/omniful/ims-service/internal/models"
+	"github.com/omniful/ims-service/internal/repository"
+	"github.com/omniful/ims-service/pkg/jwt"
+)
+
+// lastUsedInterval throttles last_used_at writes for a busy key
+const lastUsedInterval = time.Minute
+
+type AuthService interface {
+	// Authenticate returns the principal of an API key or JWT. Credentials that are
+	// unknown, expired, revoked or bound to an inactive tenant fail with an error
+	// wrapping auth.ErrInvalidCredential.
+	Authenticate(ctx context.Context, credential string) (*auth.Principal, error)
+	// CreateAPIKey creates a key for the tenant named by tenantRef and returns it with
+	// its record. The key is not stored and cannot be shown again.
+	CreateAPIKey(ctx context.Context, tenantRef, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error)
+	ListAPIKeys(ctx context.Context, tenantRef string) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+}
+
+type authService struct {
+	apiKeyRepo    repository.APIKeyRepository
+	tenantService TenantService
+	verifier      *jwt.Verifier
+	lastUsed      sync.Map // key ID -> time.Time of the last recorded use
+}
+
+// NewAuthService creates the auth service. JWTs are rejected when verifier is nil.
+func NewAuthService(apiKeyRepo repository.APIKeyRepository, tenantService TenantService, verifier *jwt.Verifier) AuthService {
+	return &authService{
+		apiKeyRepo:    apiKeyRepo,
+		tenantService: tenantService,
+		verifier:      verifier,
+	}
+}
+
+func (s *authService) Authenticate(ctx context.Context, credential string) (*auth.Principal, error) {
+	credential = strings.TrimSpace(credential)
+	if credential == "" {
+		return nil, fmt.Errorf("%w: no credential", auth.ErrInvalidCredential)
+	}
+	if jwt.IsJWT(credential) {
+		return s.authenticateJWT(ctx, credential)
+	}
+	return s.authenticateAPIKey(ctx, credential)
+}
+
+func (s *authService) authenticateJWT(ctx context.Context, token string) (*auth.Principal, error) {
+	if s.verifier == nil {
+		return nil, fmt.Errorf("%w: JWTs are not accepted", auth.ErrInvalidCredential)
+	}
+	claims, err := s.verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := auth.ParseScopes(claims.Scope)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidCredential, err)
+	}
+	tenant, err := s.resolveTenant(ctx, claims.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Principal{
+		Subject:    claims.Subject,
+		TenantID:   tenant.ID,
+		TenantCode: tenant.Code,
+		Scopes:     scopes,
+		Method:     auth.MethodJWT,
+	}, nil
+}
+
+func (s *authService) authenticateAPIKey(ctx context.Context, credential string) (*auth.Principal, error) {
+	key, err := s.apiKeyRepo.GetByHash(ctx, auth.HashAPIKey(credential))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, fmt.Errorf("%w: unknown api key", auth.ErrInvalidCredential)
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !key.Usable(now) {
+		return nil, fmt.Errorf("%w: api key %s is revoked or expired", auth.ErrInvalidCredential, key.Prefix)
+	}
+	scopes, err := auth.ParseScopes(key.Scopes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidCredential, err)
+	}
+	tenant, err := s.resolveTenant(ctx, key.TenantID.String())
+	if err != nil {
+		return nil, err
+	}
+	s.touch(key.ID, now)
+
+	keyID := key.ID
+	return &auth.Principal{
+		Subject:    key.Name,
+		TenantID:   tenant.ID,
+		TenantCode: tenant.Code,
+		Scopes:     scopes,
+		Method:     auth.MethodAPIKey,
+		KeyID:      &keyID,
+	}, nil
+}
+
+// resolveTenant returns the active tenant a credential is bound to
+func (s *authService) resolveTenant(ctx context.Context, ref string) (*models.Tenant, error) {
+	tenant, err := s.tenantService.Resolve(ctx, ref)
+	if err != nil {
+		if errors.Is(err, ErrTenantNotFound) || errors.Is(err, ErrTenantInactive) {
+			return nil, fmt.Errorf("%w: %v", auth.ErrInvalidCredential, err)
+		}
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// touch records the use of a key at most once per lastUsedInterval, without holding up
+// the request
+func (s *authService) touch(id uuid.UUID, now time.Time) {
+	if last, ok := s.lastUsed.Load(id); ok && now.Sub(last.(time.Time)) < lastUsedInterval {
+		return
+	}
+	s.lastUsed.Store(id, now)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.apiKeyRepo.TouchLastUsed(ctx, id, now)
+	}()
+}
+
+func (s *authService) CreateAPIKey(ctx context.Context, tenantRef, name string, scopes []string, expiresAt *time.Time) (string, *models.APIKey, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("api key name is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if _, err := auth.ParseScopes(scope); err != nil {
+			return "", nil, err
+		}
+	}
+	tenant, err := s.tenantService.Resolve(ctx, tenantRef)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := &models.APIKey{
+		TenantID:  tenant.ID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return secret, key, nil
+}
+
+func (s *authService) ListAPIKeys(ctx context.Context, tenantRef string) ([]models.APIKey, error) {
+	tenant, err := s.tenantService.Resolve(ctx, tenantRef)
+	if err != nil {
+		return nil, err
+	}
+	return s.apiKeyRepo.List(ctx, tenant.ID)
+}
+
+func (s *authService) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	return s.apiKeyRepo.Revoke(ctx, id)
+}
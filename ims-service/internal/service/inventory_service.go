@@ -23,6 +23,9 @@ type InventoryService interface {
 	ReleaseInventory(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, quantity int) error
 	// FulfillInventory marks the specified quantity as fulfilled and updates available quantity
 	FulfillInventory(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, quantity int) error
+	// RestockInventory takes returned units back in, restockable units as available and
+	// damaged units into the non-sellable damaged bucket
+	RestockInventory(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, restockable, damaged int) error
 }
 
 type inventoryService struct {
@@ -210,30 +213,12 @@ func (s *inventoryService) ReserveInventory(ctx context.Context, tenantID uuid.U
 		return errors.New("quantity must be greater than zero")
 	}
 
-	// Get current inventory
-	inventory, err := s.GetInventoryItem(ctx, tenantID, hubCode, skuCode)
-	if err != nil {
-		return fmt.Errorf("failed to get inventory: %w", err)
-	}
-
-	// Check if there's enough available quantity
-	if inventory.Available < quantity {
-		return fmt.Errorf("insufficient available quantity. available: %d, requested: %d",
-			inventory.Available, quantity)
-	}
-
-	// Update reserved quantity
-	if err := s.repo.UpdateReservedQuantity(ctx, tenantID, hubCode, skuCode, quantity); err != nil {
+	// Move quantity from available to reserved, failing if not enough is available
+	delta := models.InventoryLevels{Available: -quantity, Reserved: quantity}
+	if _, err := s.repo.ApplyDelta(ctx, tenantID, hubCode, skuCode, delta, models.InventoryCauseReserve); err != nil {
 		return fmt.Errorf("failed to reserve inventory: %w", err)
 	}
 
-	// Update available quantity
-	if err := s.repo.UpdateAvailableQuantity(ctx, tenantID, hubCode, skuCode, -quantity); err != nil {
-		// Try to rollback the reservation if available quantity update fails
-		_ = s.repo.UpdateReservedQuantity(ctx, tenantID, hubCode, skuCode, -quantity)
-		return fmt.Errorf("failed to update available quantity: %w", err)
-	}
-
 	return nil
 }
 
@@ -243,30 +228,12 @@ func (s *inventoryService) ReleaseInventory(ctx context.Context, tenantID uuid.U
 		return errors.New("quantity must be greater than zero")
 	}
 
-	// Get current inventory
-	inventory, err := s.GetInventoryItem(ctx, tenantID, hubCode, skuCode)
-	if err != nil {
-		return fmt.Errorf("failed to get inventory: %w", err)
-	}
-
-	// Check if there's enough reserved quantity
-	if inventory.Reserved < quantity {
-		return fmt.Errorf("insufficient reserved quantity. reserved: %d, requested: %d",
-			inventory.Reserved, quantity)
-	}
-
-	// Update reserved quantity
-	if err := s.repo.UpdateReservedQuantity(ctx, tenantID, hubCode, skuCode, -quantity); err != nil {
+	// Move quantity from reserved back to available, failing if not enough is reserved
+	delta := models.InventoryLevels{Available: quantity, Reserved: -quantity}
+	if _, err := s.repo.ApplyDelta(ctx, tenantID, hubCode, skuCode, delta, models.InventoryCauseRelease); err != nil {
 		return fmt.Errorf("failed to release inventory: %w", err)
 	}
 
-	// Update available quantity
-	if err := s.repo.UpdateAvailableQuantity(ctx, tenantID, hubCode, skuCode, quantity); err != nil {
-		// Try to rollback the release if available quantity update fails
-		_ = s.repo.UpdateReservedQuantity(ctx, tenantID, hubCode, skuCode, quantity)
-		return fmt.Errorf("failed to update available quantity: %w", err)
-	}
-
 	return nil
 }
 
@@ -276,32 +243,24 @@ func (s *inventoryService) FulfillInventory(ctx context.Context, tenantID uuid.U
 		return errors.New("quantity must be greater than zero")
 	}
 
-	// Get current inventory
-	inventory, err := s.GetInventoryItem(ctx, tenantID, hubCode, skuCode)
-	if err != nil {
-		return fmt.Errorf("failed to get inventory: %w", err)
+	// Fulfilled units leave both the reservation and the total quantity
+	delta := models.InventoryLevels{Quantity: -quantity, Reserved: -quantity}
+	if _, err := s.repo.ApplyDelta(ctx, tenantID, hubCode, skuCode, delta, models.InventoryCauseFulfill); err != nil {
+		return fmt.Errorf("failed to fulfill inventory: %w", err)
 	}
 
-	// Check if there's enough reserved quantity
-	if inventory.Reserved < quantity {
-		return fmt.Errorf("insufficient reserved quantity. reserved: %d, requested: %d",
-			inventory.Reserved, quantity)
-	}
+	return nil
+}
 
-	// Update reserved quantity
-	if err := s.repo.UpdateReservedQuantity(ctx, tenantID, hubCode, skuCode, -quantity); err != nil {
-		return fmt.Errorf("failed to update reserved quantity: %w", err)
+func (s *inventoryService) RestockInventory(ctx context.Context, tenantID uuid.UUID, hubCode, skuCode string, restockable, damaged int) error {
+	// Validate inputs
+	if restockable < 0 || damaged < 0 || restockable+damaged == 0 {
+		return errors.New("restockable and damaged must not be negative and at least one must be greater than zero")
 	}
 
-	// Update total quantity
-	if err := s.repo.UpsertInventory(ctx, tenantID, []models.InventoryUpdate{{
-		HubCode:  hubCode,
-		SkuCode:  skuCode,
-		Quantity: inventory.Quantity - quantity,
-	}}); err != nil {
-		// Try to rollback the reserved quantity update
-		_ = s.repo.UpdateReservedQuantity(ctx, tenantID, hubCode, skuCode, quantity)
-		return fmt.Errorf("failed to update total quantity: %w", err)
+	delta := models.InventoryLevels{Quantity: restockable, Available: restockable, Damaged: damaged}
+	if _, err := s.repo.ApplyDelta(ctx, tenantID, hubCode, skuCode, delta, models.InventoryCauseReturn); err != nil {
+		return fmt.Errorf("failed to restock inventory: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/models"
+	"github.com/omniful/ims-service/internal/ratelimit"
+	"github.com/omniful/ims-service/internal/repository"
+)
+
+type RateLimitService interface {
+	// Take takes a token from the tenant's bucket for a route group
+	Take(ctx context.Context, tenantID uuid.UUID, group string) (*ratelimit.Result, error)
+	// LimitsFor returns the limit of each route group for the tenant named by tenantRef,
+	// overrides included
+	LimitsFor(ctx context.Context, tenantRef string) (map[string]ratelimit.Limit, error)
+	SetLimit(ctx context.Context, tenantRef, group string, limit ratelimit.Limit) error
+	// ResetLimit drops the tenant's override, the group's default applies again
+	ResetLimit(ctx context.Context, tenantRef, group string) error
+}
+
+type rateLimitService struct {
+	rateLimitRepo repository.RateLimitRepository
+	tenantService TenantService
+	limiter       *ratelimit.Limiter
+	defaults      map[string]ratelimit.Limit
+}
+
+// NewRateLimitService creates the rate limit service. limiter may be nil for services
+// that only manage limits.
+func NewRateLimitService(rateLimitRepo repository.RateLimitRepository, tenantService TenantService, limiter *ratelimit.Limiter, defaults map[string]ratelimit.Limit) RateLimitService {
+	return &rateLimitService{
+		rateLimitRepo: rateLimitRepo,
+		tenantService: tenantService,
+		limiter:       limiter,
+		defaults:      defaults,
+	}
+}
+
+func (s *rateLimitService) Take(ctx context.Context, tenantID uuid.UUID, group string) (*ratelimit.Result, error) {
+	if s.limiter == nil {
+		return nil, fmt.Errorf("rate limiter not configured")
+	}
+	limits, err := s.limits(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	limit, ok := limits[group]
+	if !ok {
+		return nil, fmt.Errorf("unknown route group %q", group)
+	}
+	return s.limiter.Take(ctx, ratelimit.Key(tenantID.String(), group), limit)
+}
+
+func (s *rateLimitService) LimitsFor(ctx context.Context, tenantRef string) (map[string]ratelimit.Limit, error) {
+	tenant, err := s.tenantService.Resolve(ctx, tenantRef)
+	if err != nil {
+		return nil, err
+	}
+	return s.limits(ctx, tenant.ID)
+}
+
+// limits returns the defaults with the tenant's overrides applied
+func (s *rateLimitService) limits(ctx context.Context, tenantID uuid.UUID) (map[string]ratelimit.Limit, error) {
+	overrides, err := s.rateLimitRepo.ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	limits := make(map[string]ratelimit.Limit, len(s.defaults))
+	for group, limit := range s.defaults {
+		limits[group] = limit
+	}
+	for _, override := range overrides {
+		limits[override.RouteGroup] = ratelimit.Limit{Rate: override.Rate, Burst: override.Burst}
+	}
+	return limits, nil
+}
+
+func (s *rateLimitService) SetLimit(ctx context.Context, tenantRef, group string, limit ratelimit.Limit) error {
+	if _, ok := s.defaults[group]; !ok {
+		return fmt.Errorf("unknown route group %q", group)
+	}
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return fmt.Errorf("rate and burst must be positive")
+	}
+	tenant, err := s.tenantService.Resolve(ctx, tenantRef)
+	if err != nil {
+		return err
+	}
+	return s.rateLimitRepo.Upsert(ctx, &models.TenantRateLimit{
+		TenantID:   tenant.ID,
+		RouteGroup: group,
+		Rate:       limit.Rate,
+		Burst:      limit.Burst,
+		UpdatedAt:  time.Now(),
+	})
+}
+
+func (s *rateLimitService) ResetLimit(ctx context.Context, tenantRef, group string) error {
+	tenant, err := s.tenantService.Resolve(ctx, tenantRef)
+	if err != nil {
+		return err
+	}
+	return s.rateLimitRepo.Delete(ctx, tenant.ID, group)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/models"
+	"github.com/omniful/ims-service/internal/repository"
+)
+
+// Errors Resolve returns for a tenant that does not exist or may not be used
+var (
+	ErrTenantNotFound = repository.ErrTenantNotFound
+	ErrTenantInactive = errors.New("tenant is inactive")
+)
+
+type TenantService interface {
+	// Resolve returns the active tenant named by ref, its UUID or its code
+	Resolve(ctx context.Context, ref string) (*models.Tenant, error)
+}
+
+type tenantService struct {
+	tenantRepo repository.TenantRepository
+}
+
+func NewTenantService(tenantRepo repository.TenantRepository) TenantService {
+	return &tenantService{
+		tenantRepo: tenantRepo,
+	}
+}
+
+func (s *tenantService) Resolve(ctx context.Context, ref string) (*models.Tenant, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("tenant is required")
+	}
+
+	var (
+		tenant *models.Tenant
+		err    error
+	)
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		tenant, err = s.tenantRepo.GetByID(ctx, id)
+	} else {
+		tenant, err = s.tenantRepo.GetByCode(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.IsActive {
+		return nil, fmt.Errorf("%w: %s", ErrTenantInactive, tenant.Code)
+	}
+	return tenant, nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/omniful/ims-service/internal/models"
+	"github.com/omniful/ims-service/internal/repository"
+)
+
+type ValidationService interface {
+	// ValidateBatch validates order lines with one lookup for all their hubs, one for all
+	// their SKUs and one for the inventory linking them, returning one result per line
+	ValidateBatch(ctx context.Context, tenantID uuid.UUID, lines []models.ValidationLine) ([]models.ValidationResult, error)
+}
+
+type validationService struct {
+	hubRepo       repository.HubRepository
+	skuRepo       repository.SKURepository
+	inventoryRepo repository.InventoryRepository
+}
+
+func NewValidationService(hubRepo repository.HubRepository, skuRepo repository.SKURepository, inventoryRepo repository.InventoryRepository) ValidationService {
+	return &validationService{
+		hubRepo:       hubRepo,
+		skuRepo:       skuRepo,
+		inventoryRepo: inventoryRepo,
+	}
+}
+
+func (s *validationService) ValidateBatch(ctx context.Context, tenantID uuid.UUID, lines []models.ValidationLine) ([]models.ValidationResult, error) {
+	if tenantID == uuid.Nil {
+		return nil, fmt.Errorf("tenant ID is required")
+	}
+
+	hubs, err := s.hubRepo.GetByCodes(ctx, tenantID, distinctCodes(lines, func(l models.ValidationLine) string { return l.HubID }))
+	if err != nil {
+		return nil, err
+	}
+	skus, err := s.skuRepo.GetByCodes(ctx, tenantID, distinctCodes(lines, func(l models.ValidationLine) string { return l.SKU }))
+	if err != nil {
+		return nil, err
+	}
+
+	hubIDs := make([]uuid.UUID, 0, len(hubs))
+	for _, hub := range hubs {
+		hubIDs = append(hubIDs, hub.ID)
+	}
+	skuIDs := make([]uuid.UUID, 0, len(skus))
+	for _, sku := range skus {
+		skuIDs = append(skuIDs, sku.ID)
+	}
+	carried, err := s.inventoryRepo.CarriedBy(ctx, tenantID, hubIDs, skuIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ValidationResult, len(lines))
+	for i, line := range lines {
+		result := models.ValidationResult{
+			SKU:       line.SKU,
+			HubID:     line.HubID,
+			SellerID:  line.SellerID,
+			HubStatus: models.CodeStatusMissing,
+			SKUStatus: models.CodeStatusMissing,
+		}
+
+		hub, hubFound := hubs[line.HubID]
+		switch {
+		case !hubFound:
+			result.Errors = append(result.Errors, fmt.Sprintf("hub %s not found", line.HubID))
+		case !hub.IsActive:
+			result.HubStatus = models.CodeStatusInactive
+			result.Errors = append(result.Errors, fmt.Sprintf("hub %s is inactive", line.HubID))
+		default:
+			result.HubStatus = models.CodeStatusActive
+			result.HubValid = true
+		}
+
+		sku, skuFound := skus[line.SKU]
+		switch {
+		case !skuFound:
+			result.Errors = append(result.Errors, fmt.Sprintf("SKU %s not found", line.SKU))
+		case !sku.IsActive:
+			result.SKUStatus = models.CodeStatusInactive
+			result.Errors = append(result.Errors, fmt.Sprintf("SKU %s is inactive", line.SKU))
+		default:
+			result.SKUStatus = models.CodeStatusActive
+			result.SKUValid = true
+		}
+
+		if skuFound && line.SellerID != "" {
+			match := sku.SellerID.String() == line.SellerID || (sku.Seller.Code != "" && sku.Seller.Code == line.SellerID)
+			result.SellerMatch = &match
+			if !match {
+				result.SKUValid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("SKU %s does not belong to seller %s", line.SKU, line.SellerID))
+			}
+		}
+
+		if hubFound && skuFound {
+			result.HubCarriesSKU = carried[models.HubSKU{HubID: hub.ID, SkuID: sku.ID}]
+		}
+
+		result.Valid = result.HubValid && result.SKUValid
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// distinctCodes returns the distinct non-empty codes picked from lines, in first-seen order
+func distinctCodes(lines []models.ValidationLine, code func(models.ValidationLine) string) []string {
+	seen := make(map[string]bool, len(lines))
+	codes := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if c := code(line); c != "" && !seen[c] {
+			seen[c] = true
+			codes = append(codes, c)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,21 @@
+-- Outbox of inventory events, written in the same transaction as the inventory change
+-- and published to Kafka by the outbox relay
+CREATE TABLE IF NOT EXISTS inventory_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    tenant_id UUID NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP
+);
+
+-- The relay reads pending events in the order they were written
+CREATE INDEX IF NOT EXISTS idx_inventory_outbox_pending ON inventory_outbox(id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_inventory_outbox_sent_at ON inventory_outbox(sent_at) WHERE status = 'sent';
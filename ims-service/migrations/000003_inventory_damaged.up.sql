@@ -0,0 +1,3 @@
+-- Non-sellable bucket for returned units graded as damaged. Damaged units are held at
+-- the hub but are not part of quantity or available.
+ALTER TABLE inventories ADD COLUMN IF NOT EXISTS damaged INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+-- Tenants can be named by code as well as UUID. The default tenant is the one OMS uses
+-- when a request names none.
+INSERT INTO tenants (id, name, code, description, is_active)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default', 'default', 'Tenant used when a request names none', true)
+ON CONFLICT (code) DO NOTHING;
+
+-- Inventory events carry the tenant's code next to its UUID
+ALTER TABLE inventory_outbox ADD COLUMN IF NOT EXISTS tenant_code VARCHAR(100);
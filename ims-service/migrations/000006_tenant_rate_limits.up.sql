@@ -0,0 +1,10 @@
+-- Per-tenant overrides of the default rate limit of a route group (reads, writes or
+-- validation). Tenants without a row use the limits configured with RATE_LIMIT_*.
+CREATE TABLE IF NOT EXISTS tenant_rate_limits (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    route_group VARCHAR(20) NOT NULL,
+    rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+    burst INTEGER NOT NULL CHECK (burst > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, route_group)
+);
@@ -5,6 +5,17 @@ const (
 	ContextKeyRequestID = "request_id"
 	ContextKeyUserID    = "user_id"
 	ContextKeyTenantID  = "tenant_id"
+	ContextKeyPrincipal = "principal"
+
+	// HeaderTenantID carries the tenant of a request, as its code or UUID
+	HeaderTenantID = "X-Tenant-ID"
+	// HeaderLegacyTenantID is the tenant header hub and SKU routes read before X-Tenant-ID
+	HeaderLegacyTenantID = "tenant_id"
+	// HeaderAPIKey carries an API key for clients that cannot send an Authorization header
+	HeaderAPIKey = "X-API-Key"
+	// Rate limit headers, the bucket size and the requests left in it
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
 
 	// API Response Messages
 	MsgHubCreated    = "Hub created successfully"
@@ -31,10 +42,12 @@ const (
 	ErrDatabaseConnection = "Database connection error"
 	ErrRedisConnection    = "Redis connection error"
 	ErrInternalServer     = "Internal server error"
+	ErrTenantRequired     = "X-Tenant-ID header is required"
 
 	// Cache keys
 	CacheKeyHubPrefix       = "hub:"
 	CacheKeySKUPrefix       = "sku:"
+	CacheKeyTenantPrefix    = "tenant:"
 	CacheKeyTenantHubs      = "tenant:hubs:%s"
 	CacheKeyHubInventory    = "hub:inventory:%s"
 	CacheKeyInventoryPrefix = "inventory:"
@@ -60,6 +73,8 @@ const (
 	DefaultPageSize          = 20
 	MaxPageSize              = 100
 	DefaultPage              = 1
+	// MaxValidationBatch caps the number of lines validated in one request
+	MaxValidationBatch = 10000
 
 	// API Endpoints
 	EndpointHealth     = "/health"
@@ -67,6 +82,7 @@ const (
 	EndpointSKUs       = "/api/v1/skus"
 	EndpointInventory  = "/api/v1/inventory"
 	EndpointValidation = "/api/v1/validate"
+	EndpointWhoAmI     = "/api/v1/auth/whoami"
 
 	// Validation
 	MaxNameLength        = 255
@@ -0,0 +1,16 @@
+package imsclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// WhoAmI returns the principal IMS identifies credential as, an API key or JWT. An
+// unknown, expired or revoked credential is answered with a 401 APIError.
+func (c *Client) WhoAmI(ctx context.Context, credential string) (*Principal, error) {
+	var principal Principal
+	if err := c.do(WithCredential(ctx, credential), http.MethodGet, "/api/v1/auth/whoami", nil, &principal); err != nil {
+		return nil, err
+	}
+	return &principal, nil
+}
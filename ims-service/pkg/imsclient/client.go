@@ -0,0 +1,342 @@
+// Package imsclient is the Go client for the IMS HTTP API. It has no dependencies outside
+// the standard library so other services can use it without pulling in the IMS server.
+package imsclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable is returned when IMS could not be reached or kept failing after retries,
+// or the circuit breaker is open. Callers decide how to degrade, nothing is simulated.
+var ErrUnavailable = errors.New("ims unavailable")
+
+// ErrCircuitOpen is returned without calling IMS while the circuit breaker is open. It
+// wraps ErrUnavailable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit breaker open", ErrUnavailable)
+
+// ErrRateLimited is returned when IMS kept answering 429 for the tenant of a request. It
+// wraps ErrUnavailable so callers degrade as for an outage, but it does not count
+// towards the circuit breaker: IMS is up and only limiting that tenant.
+var ErrRateLimited = fmt.Errorf("%w: rate limited", ErrUnavailable)
+
+// APIError is a response IMS rejected with a 4xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ims returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// IsUnavailable reports whether err means IMS could not answer, as opposed to IMS
+// rejecting the request
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// IsNotFound reports whether IMS answered err with 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether IMS answered err with 409, such as a reservation beyond the
+// available quantity
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// IsUnauthorized reports whether IMS rejected the credential of a request with 401
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// Config configures a Client. Zero values fall back to the defaults below.
+type Config struct {
+	BaseURL string
+	// Timeout bounds each attempt, default 10s
+	Timeout time.Duration
+	// MaxRetries is how many times a failed attempt is retried, default 2. Set it
+	// negative to disable retries.
+	MaxRetries int
+	// RetryBaseDelay is the first backoff, doubled on every retry with full jitter,
+	// default 100ms
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the backoff, default 2s
+	RetryMaxDelay time.Duration
+	// BreakerThreshold is the number of consecutive failed calls that opens the circuit
+	// breaker, default 5
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a trial call, default 30s
+	BreakerCooldown time.Duration
+	// TenantFromContext returns the tenant to send with a request, its code or UUID. By
+	// default the tenant set with WithTenant is used.
+	TenantFromContext func(ctx context.Context) string
+	// Credential returns the API key or JWT sent as a bearer token with a request, IMS
+	// takes the tenant from it. A credential set with WithCredential takes precedence.
+	Credential func(ctx context.Context) string
+	// HTTPClient overrides the HTTP client, its Timeout is left as is
+	HTTPClient *http.Client
+}
+
+// Client calls the IMS HTTP API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	tenantFunc func(ctx context.Context) string
+	credential func(ctx context.Context) string
+	breaker    *breaker
+}
+
+// New creates a client for the IMS at cfg.BaseURL
+func New(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBaseDelay <= 0 {
+		cfg.RetryBaseDelay = 100 * time.Millisecond
+	}
+	if cfg.RetryMaxDelay <= 0 {
+		cfg.RetryMaxDelay = 2 * time.Second
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = 5
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = 30 * time.Second
+	}
+	if cfg.TenantFromContext == nil {
+		cfg.TenantFromContext = TenantFromContext
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	return &Client{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		httpClient: httpClient,
+		maxRetries: cfg.MaxRetries,
+		baseDelay:  cfg.RetryBaseDelay,
+		maxDelay:   cfg.RetryMaxDelay,
+		tenantFunc: cfg.TenantFromContext,
+		credential: cfg.Credential,
+		breaker:    newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}
+}
+
+// BreakerState returns the state of the circuit breaker: closed, open or half_open
+func (c *Client) BreakerState() string {
+	return c.breaker.current()
+}
+
+// TenantHeader carries the tenant of every request, as its code or UUID
+const TenantHeader = "X-Tenant-ID"
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx whose requests are sent for tenantID
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant set with WithTenant
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey{}).(string)
+	return tenantID
+}
+
+type credentialKey struct{}
+
+// WithCredential returns a copy of ctx whose requests are authenticated with credential,
+// an API key or JWT. The credential names its own tenant, so no tenant header is sent
+// with these requests.
+func WithCredential(ctx context.Context, credential string) context.Context {
+	return context.WithValue(ctx, credentialKey{}, credential)
+}
+
+// credentialFor returns the credential to send with a request made with ctx, and
+// whether it was set with WithCredential
+func (c *Client) credentialFor(ctx context.Context) (string, bool) {
+	if credential, _ := ctx.Value(credentialKey{}).(string); credential != "" {
+		return credential, true
+	}
+	if c.credential != nil {
+		return c.credential(ctx), false
+	}
+	return "", false
+}
+
+// do sends a request and decodes a successful response into out. Reads are retried on
+// any transient failure; writes only when IMS cannot have applied them, so a reservation
+// is never made twice.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal %s %s request: %w", method, path, err)
+		}
+	}
+	// Validation is a read even though it is sent as a POST
+	idempotent := method != http.MethodPost || strings.HasPrefix(path, "/api/v1/validate")
+
+	return c.Call(ctx, func(ctx context.Context) (bool, time.Duration, error) {
+		return c.attempt(ctx, method, path, payload, out, idempotent)
+	})
+}
+
+// Attempt makes one call to IMS for Call. It reports whether a failure may be retried and
+// how long IMS asked to wait first. Failures IMS may recover from wrap ErrUnavailable, or
+// ErrRateLimited when IMS is limiting the tenant.
+type Attempt func(ctx context.Context) (retry bool, retryAfter time.Duration, err error)
+
+// Call runs attempt behind the circuit breaker of the client and retries it with its
+// backoff, like every request to the HTTP API. Other transports to IMS, such as gRPC, use
+// it to share the breaker and retry policy of the client.
+func (c *Client) Call(ctx context.Context, attempt Attempt) error {
+	if !c.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	var (
+		lastErr error
+		wait    time.Duration
+	)
+	for n := 0; n <= c.maxRetries; n++ {
+		if n > 0 {
+			select {
+			case <-ctx.Done():
+				c.breaker.record(errors.Is(lastErr, ErrRateLimited))
+				return fmt.Errorf("%w: %v (last error: %v)", ErrUnavailable, ctx.Err(), lastErr)
+			case <-time.After(max(c.backoff(n), wait)):
+			}
+		}
+
+		retry, retryAfter, err := attempt(ctx)
+		if err == nil {
+			c.breaker.record(true)
+			return nil
+		}
+		if !IsUnavailable(err) {
+			// IMS answered, so it is up even though it rejected the request
+			c.breaker.record(true)
+			return err
+		}
+		lastErr = err
+		// Waiting longer than a backoff for IMS to accept the request is left to the caller
+		if wait = retryAfter; !retry || wait > c.maxDelay {
+			break
+		}
+	}
+	c.breaker.record(errors.Is(lastErr, ErrRateLimited))
+	return lastErr
+}
+
+// attempt sends one request, reporting whether a failure may be retried and how long IMS
+// asked to wait first
+func (c *Client) attempt(ctx context.Context, method, path string, payload []byte, out interface{}, idempotent bool) (bool, time.Duration, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to create %s %s request: %w", method, path, err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	credential, explicit := c.credentialFor(ctx)
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	if tenantID := c.tenantFunc(ctx); tenantID != "" && !explicit {
+		req.Header.Set(TenantHeader, tenantID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return idempotent || notSent(err), 0, fmt.Errorf("%w: %s %s: %v", ErrUnavailable, method, path, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// Rejected before processing, safe to retry writes too
+		return true, retryAfter(resp.Header), fmt.Errorf("%w: %s %s returned status %d", ErrRateLimited, method, path, resp.StatusCode)
+	case resp.StatusCode == http.StatusServiceUnavailable:
+		return true, retryAfter(resp.Header), fmt.Errorf("%w: %s %s returned status %d", ErrUnavailable, method, path, resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return idempotent, 0, fmt.Errorf("%w: %s %s returned status %d: %s", ErrUnavailable, method, path, resp.StatusCode, errorMessage(resp.Body))
+	case resp.StatusCode >= 400:
+		return false, 0, &APIError{StatusCode: resp.StatusCode, Message: errorMessage(resp.Body)}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return false, 0, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, 0, fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return false, 0, nil
+}
+
+// retryAfter reads a Retry-After header given in seconds, zero when absent
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// backoff returns the delay before a retry, exponential with full jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.baseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.maxDelay {
+		delay = c.maxDelay
+	}
+	return time.Duration(rand.Int64N(int64(delay) + 1))
+}
+
+// notSent reports whether a transport error happened before the request reached IMS
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// errorMessage reads the error field of an IMS error body, or the body itself
+func errorMessage(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 4096))
+	var errorResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &errorResp) == nil && errorResp.Error != "" {
+		return errorResp.Error
+	}
+	return strings.TrimSpace(string(data))
+}
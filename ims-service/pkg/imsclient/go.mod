@@ -0,0 +1,3 @@
+module github.com/omniful/ims-service/pkg/imsclient
+
+go 1.22
@@ -0,0 +1,73 @@
+package imsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CreateHub creates a hub
+func (c *Client) CreateHub(ctx context.Context, req CreateHubRequest) (*Hub, error) {
+	var hub Hub
+	if err := c.do(ctx, http.MethodPost, "/api/v1/hubs/", req, &hub); err != nil {
+		return nil, err
+	}
+	return &hub, nil
+}
+
+// ListHubs returns a page of hubs
+func (c *Client) ListHubs(ctx context.Context, page, pageSize int) (*HubList, error) {
+	var list HubList
+	path := "/api/v1/hubs/" + pageQuery(url.Values{}, page, pageSize)
+	if err := c.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetHub returns a hub by ID
+func (c *Client) GetHub(ctx context.Context, id string) (*Hub, error) {
+	var hub Hub
+	if err := c.do(ctx, http.MethodGet, "/api/v1/hubs/"+url.PathEscape(id), nil, &hub); err != nil {
+		return nil, err
+	}
+	return &hub, nil
+}
+
+// UpdateHub changes a hub
+func (c *Client) UpdateHub(ctx context.Context, id string, req UpdateHubRequest) (*Hub, error) {
+	var hub Hub
+	if err := c.do(ctx, http.MethodPut, "/api/v1/hubs/"+url.PathEscape(id), req, &hub); err != nil {
+		return nil, err
+	}
+	return &hub, nil
+}
+
+// DeleteHub deletes a hub
+func (c *Client) DeleteHub(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/hubs/"+url.PathEscape(id), nil, nil)
+}
+
+// Health returns the IMS health report
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var health Health
+	if err := c.do(ctx, http.MethodGet, "/health", nil, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// pageQuery adds pagination to query and encodes it, empty when nothing is set
+func pageQuery(query url.Values, page, pageSize int) string {
+	if page > 0 {
+		query.Set("page", fmt.Sprint(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", fmt.Sprint(pageSize))
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
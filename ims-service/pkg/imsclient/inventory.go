@@ -0,0 +1,79 @@
+package imsclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UpsertInventory sets the stock of SKUs at hubs, creating inventory items as needed
+func (c *Client) UpsertInventory(ctx context.Context, updates []InventoryUpdate) (*MutationResponse, error) {
+	var resp MutationResponse
+	body := map[string]interface{}{"updates": updates}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/inventory/", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetInventory returns a page of inventory items matching filter
+func (c *Client) GetInventory(ctx context.Context, filter InventoryFilter) (*InventoryList, error) {
+	query := url.Values{}
+	if filter.HubCode != "" {
+		query.Set("hub_code", filter.HubCode)
+	}
+	if filter.SellerID != "" {
+		query.Set("seller_id", filter.SellerID)
+	}
+	if len(filter.SkuCodes) > 0 {
+		query.Set("sku_codes", strings.Join(filter.SkuCodes, ","))
+	}
+
+	var list InventoryList
+	path := "/api/v1/inventory/" + pageQuery(query, filter.Page, filter.PageSize)
+	if err := c.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetInventoryItem returns the stock of a SKU at a hub
+func (c *Client) GetInventoryItem(ctx context.Context, hubCode, skuCode string) (*Inventory, error) {
+	var inventory Inventory
+	path := "/api/v1/inventory/" + url.PathEscape(hubCode) + "/" + url.PathEscape(skuCode)
+	if err := c.do(ctx, http.MethodGet, path, nil, &inventory); err != nil {
+		return nil, err
+	}
+	return &inventory, nil
+}
+
+// ReserveInventory moves quantity from available to reserved. IMS answers 409 when not
+// enough is available, see IsConflict.
+func (c *Client) ReserveInventory(ctx context.Context, req StockRequest) (*MutationResponse, error) {
+	return c.mutate(ctx, "/api/v1/inventory/reserve", req)
+}
+
+// ReleaseInventory moves reserved quantity back to available
+func (c *Client) ReleaseInventory(ctx context.Context, req StockRequest) (*MutationResponse, error) {
+	return c.mutate(ctx, "/api/v1/inventory/release", req)
+}
+
+// FulfillInventory deducts reserved quantity once it has left the hub
+func (c *Client) FulfillInventory(ctx context.Context, req StockRequest) (*MutationResponse, error) {
+	return c.mutate(ctx, "/api/v1/inventory/fulfill", req)
+}
+
+// RestockInventory takes returned units back in, restockable units as available stock and
+// damaged units into the damaged bucket
+func (c *Client) RestockInventory(ctx context.Context, req RestockRequest) (*MutationResponse, error) {
+	return c.mutate(ctx, "/api/v1/inventory/restock", req)
+}
+
+func (c *Client) mutate(ctx context.Context, path string, req interface{}) (*MutationResponse, error) {
+	var resp MutationResponse
+	if err := c.do(ctx, http.MethodPost, path, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,67 @@
+package imsclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateSKU creates a SKU
+func (c *Client) CreateSKU(ctx context.Context, req CreateSKURequest) (*SKU, error) {
+	var sku SKU
+	if err := c.do(ctx, http.MethodPost, "/api/v1/skus/", req, &sku); err != nil {
+		return nil, err
+	}
+	return &sku, nil
+}
+
+// ListSKUs returns a page of SKUs matching filter
+func (c *Client) ListSKUs(ctx context.Context, filter SKUFilter) (*SKUList, error) {
+	query := url.Values{}
+	if filter.SellerID != "" {
+		query.Set("seller_id", filter.SellerID)
+	}
+	if filter.IsActive != nil {
+		query.Set("is_active", strconv.FormatBool(*filter.IsActive))
+	}
+
+	var list SKUList
+	path := "/api/v1/skus/" + pageQuery(query, filter.Page, filter.PageSize)
+	if err := c.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetSKU returns a SKU by ID
+func (c *Client) GetSKU(ctx context.Context, id string) (*SKU, error) {
+	var sku SKU
+	if err := c.do(ctx, http.MethodGet, "/api/v1/skus/"+url.PathEscape(id), nil, &sku); err != nil {
+		return nil, err
+	}
+	return &sku, nil
+}
+
+// GetSKUByCode returns a SKU by its code
+func (c *Client) GetSKUByCode(ctx context.Context, code string) (*SKU, error) {
+	var sku SKU
+	if err := c.do(ctx, http.MethodGet, "/api/v1/skus/code/"+url.PathEscape(code), nil, &sku); err != nil {
+		return nil, err
+	}
+	return &sku, nil
+}
+
+// UpdateSKU changes a SKU
+func (c *Client) UpdateSKU(ctx context.Context, id string, req UpdateSKURequest) (*SKU, error) {
+	var sku SKU
+	if err := c.do(ctx, http.MethodPut, "/api/v1/skus/"+url.PathEscape(id), req, &sku); err != nil {
+		return nil, err
+	}
+	return &sku, nil
+}
+
+// DeleteSKU deletes a SKU
+func (c *Client) DeleteSKU(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/skus/"+url.PathEscape(id), nil, nil)
+}
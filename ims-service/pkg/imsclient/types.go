@@ -0,0 +1,246 @@
+package imsclient
+
+import "time"
+
+// Hub is a hub as returned by IMS
+type Hub struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	Address     string    `json:"address"`
+	City        string    `json:"city"`
+	State       string    `json:"state"`
+	Country     string    `json:"country"`
+	PostalCode  string    `json:"postal_code"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Seller is the seller a SKU belongs to
+type Seller struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	IsActive bool   `json:"is_active"`
+}
+
+// SKU is a SKU as returned by IMS
+type SKU struct {
+	ID            string    `json:"id"`
+	TenantID      string    `json:"tenant_id"`
+	SellerID      string    `json:"seller_id"`
+	Code          string    `json:"code"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	IsActive      bool      `json:"is_active"`
+	Barcode       string    `json:"barcode"`
+	Seller        Seller    `json:"seller"`
+	Weight        float64   `json:"weight,omitempty"`
+	WeightUnit    string    `json:"weight_unit,omitempty"`
+	Length        float64   `json:"length,omitempty"`
+	Width         float64   `json:"width,omitempty"`
+	Height        float64   `json:"height,omitempty"`
+	DimensionUnit string    `json:"dimension_unit,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Inventory is the stock of a SKU at a hub
+type Inventory struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	HubID     string    `json:"hub_id"`
+	SkuID     string    `json:"sku_id"`
+	Quantity  int       `json:"quantity"`
+	Available int       `json:"available"`
+	Reserved  int       `json:"reserved"`
+	InTransit int       `json:"in_transit"`
+	Damaged   int       `json:"damaged"`
+	SKU       SKU       `json:"sku"`
+	Hub       Hub       `json:"hub"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Pagination describes one page of a list
+type Pagination struct {
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"page_size"`
+	Pages    int   `json:"pages"`
+}
+
+// HubList is a page of hubs
+type HubList struct {
+	Data       []Hub      `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// SKUList is a page of SKUs
+type SKUList struct {
+	Data       []SKU      `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// InventoryList is a page of inventory items
+type InventoryList struct {
+	Data       []Inventory `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
+
+// CreateHubRequest creates a hub
+type CreateHubRequest struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IsActive    bool   `json:"is_active"`
+	Address     string `json:"address,omitempty"`
+	City        string `json:"city,omitempty"`
+	State       string `json:"state,omitempty"`
+	Country     string `json:"country,omitempty"`
+	PostalCode  string `json:"postal_code,omitempty"`
+}
+
+// UpdateHubRequest changes the fields of a hub that are set
+type UpdateHubRequest struct {
+	Code        *string `json:"code,omitempty"`
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+	Address     *string `json:"address,omitempty"`
+	City        *string `json:"city,omitempty"`
+	State       *string `json:"state,omitempty"`
+	Country     *string `json:"country,omitempty"`
+	PostalCode  *string `json:"postal_code,omitempty"`
+}
+
+// CreateSKURequest creates a SKU
+type CreateSKURequest struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description,omitempty"`
+	SellerID      string  `json:"seller_id"`
+	IsActive      bool    `json:"is_active"`
+	Weight        float64 `json:"weight,omitempty"`
+	WeightUnit    string  `json:"weight_unit,omitempty"`
+	Length        float64 `json:"length,omitempty"`
+	Width         float64 `json:"width,omitempty"`
+	Height        float64 `json:"height,omitempty"`
+	DimensionUnit string  `json:"dimension_unit,omitempty"`
+}
+
+// UpdateSKURequest changes the fields of a SKU that are set
+type UpdateSKURequest struct {
+	Code          *string  `json:"code,omitempty"`
+	Name          *string  `json:"name,omitempty"`
+	Description   *string  `json:"description,omitempty"`
+	SellerID      *string  `json:"seller_id,omitempty"`
+	IsActive      *bool    `json:"is_active,omitempty"`
+	Weight        *float64 `json:"weight,omitempty"`
+	WeightUnit    *string  `json:"weight_unit,omitempty"`
+	Length        *float64 `json:"length,omitempty"`
+	Width         *float64 `json:"width,omitempty"`
+	Height        *float64 `json:"height,omitempty"`
+	DimensionUnit *string  `json:"dimension_unit,omitempty"`
+}
+
+// SKUFilter narrows ListSKUs
+type SKUFilter struct {
+	SellerID string
+	IsActive *bool
+	Page     int
+	PageSize int
+}
+
+// InventoryFilter narrows GetInventory
+type InventoryFilter struct {
+	HubCode  string
+	SellerID string
+	SkuCodes []string
+	Page     int
+	PageSize int
+}
+
+// InventoryUpdate sets the stock of a SKU at a hub
+type InventoryUpdate struct {
+	HubCode  string `json:"hub_code"`
+	SkuCode  string `json:"sku_code"`
+	Quantity int    `json:"quantity"`
+}
+
+// StockRequest moves quantity of a SKU at a hub, for reserve, release and fulfil
+type StockRequest struct {
+	HubCode  string `json:"hub_code"`
+	SkuCode  string `json:"sku_code"`
+	Quantity int    `json:"quantity"`
+}
+
+// RestockRequest takes returned units back in at a hub
+type RestockRequest struct {
+	HubCode     string `json:"hub_code"`
+	SkuCode     string `json:"sku_code"`
+	Restockable int    `json:"restockable"`
+	Damaged     int    `json:"damaged"`
+}
+
+// MutationResponse is the body IMS returns for inventory changes
+type MutationResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// Statuses of a SKU or hub code in validation results
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+	StatusMissing  = "missing"
+)
+
+// ValidationLine is an order line to validate. SellerID is optional, an ID or code.
+type ValidationLine struct {
+	SKU      string `json:"sku"`
+	HubID    string `json:"hub_id"`
+	SellerID string `json:"seller_id,omitempty"`
+}
+
+// ValidationResult is the IMS verdict for one order line
+type ValidationResult struct {
+	SKU           string   `json:"sku"`
+	HubID         string   `json:"hub_id"`
+	SellerID      string   `json:"seller_id,omitempty"`
+	Valid         bool     `json:"valid"`
+	SKUValid      bool     `json:"sku_valid"`
+	HubValid      bool     `json:"hub_valid"`
+	SKUStatus     string   `json:"sku_status"`
+	HubStatus     string   `json:"hub_status"`
+	SellerMatch   *bool    `json:"seller_match,omitempty"`
+	HubCarriesSKU bool     `json:"hub_carries_sku"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// Principal is the caller a credential identifies: its subject, the tenant it is bound
+// to and the scopes it was granted
+type Principal struct {
+	Subject    string   `json:"subject"`
+	TenantID   string   `json:"tenant_id"`
+	TenantCode string   `json:"tenant_code"`
+	Scopes     []string `json:"scopes"`
+	Method     string   `json:"method"`
+	KeyID      string   `json:"key_id,omitempty"`
+}
+
+// Health is the IMS health report
+type Health struct {
+	Status    string `json:"status"`
+	Service   string `json:"service"`
+	Timestamp string `json:"timestamp"`
+	Redis     string `json:"redis"`
+	Database  string `json:"database"`
+	Version   string `json:"version"`
+}
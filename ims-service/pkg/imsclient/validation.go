@@ -0,0 +1,33 @@
+package imsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Validate checks a single order line
+func (c *Client) Validate(ctx context.Context, line ValidationLine) (*ValidationResult, error) {
+	var result ValidationResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/validate", line, &result); err != nil {
+		return nil, err
+	}
+	result.SKU, result.HubID, result.SellerID = line.SKU, line.HubID, line.SellerID
+	return &result, nil
+}
+
+// ValidateBatch checks order lines with one call, returning one result per line in the
+// same order
+func (c *Client) ValidateBatch(ctx context.Context, lines []ValidationLine) ([]ValidationResult, error) {
+	var resp struct {
+		Results []ValidationResult `json:"results"`
+	}
+	body := map[string]interface{}{"orders": lines}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/validate/batch", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(lines) {
+		return nil, fmt.Errorf("batch validation returned %d results for %d lines", len(resp.Results), len(lines))
+	}
+	return resp.Results, nil
+}
@@ -0,0 +1,4 @@
+// Package imspb is the generated Go code of the IMS gRPC API in inventory.proto, the
+// server interface IMS implements and the client OMS calls it with. Regenerate it with
+// make proto after changing inventory.proto.
+package imspb
@@ -0,0 +1,3 @@
+module github.com/omniful/ims-service/pkg/jwt
+
+go 1.22
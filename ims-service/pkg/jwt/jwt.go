@@ -0,0 +1,244 @@
+// Package jwt signs and verifies the JWTs IMS and OMS accept, HS256 and RS256 only. It has
+// no dependencies outside the standard library, like imsclient, so both services share it.
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCredential is returned for a token that is malformed, badly signed, expired or
+// not meant for the verifier. The services use it for rejected API keys too.
+var ErrInvalidCredential = errors.New("invalid credential")
+
+// clockSkew is the leeway allowed when checking exp and nbf
+const clockSkew = 30 * time.Second
+
+// Claims are the JWT claims IMS and OMS read and write. The tenant is named by code or
+// UUID, scopes as a space separated string as in OAuth.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Tenant    string   `json:"tenant"`
+	Scope     string   `json:"scope"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+}
+
+// Audience is the aud claim, read as a string or a list of strings
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// MarshalJSON writes a single audience as a string, as most verifiers expect
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// VerifierConfig configures the keys and claims a Verifier accepts. At least one of
+// HS256Secret and RS256PublicKey must be set.
+type VerifierConfig struct {
+	HS256Secret string
+	// RS256PublicKey is a PEM encoded RSA public key or certificate
+	RS256PublicKey []byte
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+}
+
+// Configured reports whether cfg has a key, services without one accept no JWTs
+func (cfg VerifierConfig) Configured() bool {
+	return cfg.HS256Secret != "" || len(cfg.RS256PublicKey) > 0
+}
+
+// Verifier checks JWT signatures and claims
+type Verifier struct {
+	secret   []byte
+	rsaKey   *rsa.PublicKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewVerifier creates a Verifier for cfg
+func NewVerifier(cfg VerifierConfig) (*Verifier, error) {
+	v := &Verifier{issuer: cfg.Issuer, audience: cfg.Audience, now: time.Now}
+	if cfg.HS256Secret != "" {
+		v.secret = []byte(cfg.HS256Secret)
+	}
+	if len(cfg.RS256PublicKey) > 0 {
+		key, err := parseRSAPublicKey(cfg.RS256PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKey = key
+	}
+	if v.secret == nil && v.rsaKey == nil {
+		return nil, errors.New("no JWT key configured")
+	}
+	return v, nil
+}
+
+// Verify checks the signature and claims of token and returns its claims. Rejected tokens
+// fail with an error wrapping ErrInvalidCredential.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredential)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidCredential)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidCredential)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	// The algorithm must match a configured key, so "none" and key confusion are refused
+	switch {
+	case header.Alg == "HS256" && v.secret != nil:
+		if !hmac.Equal(signature, hs256(v.secret, signed)) {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidCredential)
+		}
+	case header.Alg == "RS256" && v.rsaKey != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(v.rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidCredential)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidCredential, header.Alg)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidCredential)
+	}
+	if err := v.checkClaims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *Verifier) checkClaims(claims *Claims) error {
+	now := v.now()
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("%w: token has no expiry", ErrInvalidCredential)
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidCredential)
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("%w: token not valid yet", ErrInvalidCredential)
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidCredential, claims.Issuer)
+	}
+	if v.audience != "" && !contains(claims.Audience, v.audience) {
+		return fmt.Errorf("%w: token not meant for %q", ErrInvalidCredential, v.audience)
+	}
+	if claims.Tenant == "" {
+		return fmt.Errorf("%w: token names no tenant", ErrInvalidCredential)
+	}
+	return nil
+}
+
+// SignHS256 returns claims as a JWT signed with secret
+func SignHS256(claims Claims, secret []byte) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(hs256(secret, []byte(signed))), nil
+}
+
+// IsJWT reports whether a credential looks like a JWT rather than an API key
+func IsJWT(credential string) bool {
+	return strings.Count(credential, ".") == 2
+}
+
+func hs256(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("RS256 public key is not PEM encoded")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RS256 certificate: %w", err)
+		}
+		key, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("RS256 certificate does not hold an RSA key")
+		}
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RS256 public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("RS256 public key is not an RSA key")
+	}
+	return key, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// Command quota shows and changes the daily upload quotas of a tenant. Without -files,
+// -rows or -reset it only shows them with today's usage.
+//
+//	go run ./cmd/quota -tenant acme [-files 100] [-rows 50000] [-reset]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"oms-service/config"
+	"oms-service/internal/orders"
+	"oms-service/internal/quota"
+)
+
+func main() {
+	tenantID := flag.String("tenant", "", "tenant to show or change")
+	files := flag.Int("files", -1, "files the tenant may upload per day, 0 for unlimited")
+	rows := flag.Int("rows", -1, "rows the tenant may upload per day, 0 for unlimited")
+	reset := flag.Bool("reset", false, "remove the tenant's quotas so the defaults apply")
+	flag.Parse()
+	if *tenantID == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.LoadConfig()
+	if err := orders.InitializeMongoDB(cfg.GetMongoDBConnectionString()); err != nil {
+		log.Fatalf("❌ MongoDB initialization failed: %v", err)
+	}
+	if err := quota.Initialize(orders.GetMongoClient(), quota.Limits{
+		FilesPerDay: cfg.UploadQuotaFilesPerDay,
+		RowsPerDay:  cfg.UploadQuotaRowsPerDay,
+	}); err != nil {
+		log.Fatalf("❌ Upload quota store initialization failed: %v", err)
+	}
+
+	ctx := context.Background()
+	switch {
+	case *reset:
+		if err := quota.DeleteLimits(ctx, *tenantID); err != nil {
+			log.Fatalf("❌ Failed to reset upload quotas: %v", err)
+		}
+		log.Printf("✅ Upload quotas of tenant %s reset to the defaults", *tenantID)
+	case *files >= 0 || *rows >= 0:
+		// Keep the quota that is not given
+		limits := *quota.LimitsFor(ctx, *tenantID)
+		limits.TenantID = *tenantID
+		if *files >= 0 {
+			limits.FilesPerDay = *files
+		}
+		if *rows >= 0 {
+			limits.RowsPerDay = *rows
+		}
+		if err := quota.SaveLimits(ctx, &limits); err != nil {
+			log.Fatalf("❌ Failed to save upload quotas: %v", err)
+		}
+	}
+
+	usage, err := quota.UsageFor(ctx, *tenantID)
+	if err != nil {
+		log.Fatalf("❌ Failed to read upload usage: %v", err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(map[string]interface{}{
+		"limits": quota.LimitsFor(ctx, *tenantID),
+		"usage":  usage,
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"oms-service/internal/allocation"
+	"oms-service/internal/tenant"
+)
+
+// handleAllocationPolicy reads (GET) or replaces (PUT) the inventory allocation policy
+// of the tenant named in the X-Tenant-ID header
+func handleAllocationPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenant.FromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(allocation.PolicyFor(r.Context(), tenantID))
+	case http.MethodPut:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			fmt.Fprintf(w, "Failed to read policy: %v", err)
+			return
+		}
+
+		// Start from the defaults so omitted settings keep their default values
+		policy := allocation.DefaultPolicy()
+		if err := json.Unmarshal(body, policy); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid policy JSON: %v", err)
+			return
+		}
+		policy.TenantID = tenantID
+		if err := policy.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid policy: %v", err)
+			return
+		}
+
+		if err := allocation.SavePolicy(r.Context(), policy); err != nil {
+			if errors.Is(err, allocation.ErrNotInitialized) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			fmt.Fprintf(w, "Failed to save policy: %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policy)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"oms-service/internal/amendment"
+	"oms-service/internal/orders"
+	"oms-service/internal/tenant"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
+)
+
+// handleOrderAmendment serves PATCH /orders/{order_id}/lines, which changes quantities,
+// removes lines (quantity 0) and adds lines to an order and adjusts its IMS reservations:
+//
+//	{"lines": [{"sku": "SKU001", "quantity": 3}, {"sku": "SKU002", "hub_id": "HUB001", "quantity": 1}], "reason": "..."}
+func handleOrderAmendment(service *amendment.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		orderID := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/orders/"), "/"), "/")[0]
+
+		var req amendment.Request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize)).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid amendment JSON: %v", err)
+			return
+		}
+
+		ctx := tenant.WithID(r.Context(), tenant.FromRequest(r))
+		result, err := service.Amend(ctx, orderID, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, orders.ErrOrderNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, amendment.ErrInvalidAmendment):
+				w.WriteHeader(http.StatusBadRequest)
+			case imsclient.IsUnavailable(err):
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case errors.Is(err, amendment.ErrNotAmendable), errors.Is(err, amendment.ErrReservationFailed),
+				errors.Is(err, orders.ErrOrderModified):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			fmt.Fprintf(w, "%v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"oms-service/internal/backlog"
+	"oms-service/internal/kafka"
+	"oms-service/internal/tenant"
+)
+
+// handleOnHoldBacklog serves the on-hold backlog admin API:
+//
+//	GET  /admin/on-hold?hub_id=&sku=&overdue=&limit= list waiting orders in retry order
+//	POST /admin/on-hold/retry?hub_id=&sku=           re-run finalization for waiting orders now
+//	PUT  /admin/on-hold/{order_id}/priority          set the priority of a waiting order
+//
+// Requests are scoped to the X-Tenant-ID tenant when the header is present.
+func handleOnHoldBacklog(retrier *kafka.BacklogRetrier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/on-hold"), "/")
+		parts := strings.Split(path, "/")
+		query := r.URL.Query()
+
+		tenantID := ""
+		if r.Header.Get(tenant.Header) != "" {
+			tenantID = tenant.FromRequest(r)
+		}
+
+		switch {
+		case path == "" && r.Method == http.MethodGet:
+			limit, _ := strconv.ParseInt(query.Get("limit"), 10, 64)
+			entries, err := backlog.List(r.Context(), backlog.Filter{
+				HubID:       query.Get("hub_id"),
+				SKU:         query.Get("sku"),
+				TenantID:    tenantID,
+				OverdueOnly: query.Get("overdue") == "true",
+				Limit:       limit,
+			})
+			if err != nil {
+				writeBacklogError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"orders":      entries,
+				"count":       len(entries),
+				"retry_order": backlog.RetryOrder(),
+			})
+		case path == "retry" && r.Method == http.MethodPost:
+			if retrier == nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, "Order finalizer not running (Kafka disabled)")
+				return
+			}
+			finalized, err := retrier.RetryWaiting(r.Context(), tenantID, query.Get("hub_id"), query.Get("sku"))
+			if err != nil {
+				writeBacklogError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"finalized": finalized})
+		case len(parts) == 2 && parts[1] == "priority" && r.Method == http.MethodPut:
+			var req struct {
+				Priority int `json:"priority"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "Invalid priority: %v", err)
+				return
+			}
+			entry, err := backlog.SetPriority(r.Context(), parts[0], req.Priority)
+			if err != nil {
+				writeBacklogError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entry)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// writeBacklogError maps backlog errors to HTTP responses
+func writeBacklogError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, backlog.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, backlog.ErrNotInitialized):
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	fmt.Fprintf(w, "%v", err)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"oms-service/internal/deadletter"
+	"oms-service/internal/kafka"
+)
+
+// handleDeadLetters serves the Kafka dead-letter admin API:
+//
+//	GET  /admin/dlq?topic=&status=&limit= list dead letters, newest first
+//	GET  /admin/dlq/{id}                  inspect a dead letter
+//	POST /admin/dlq/{id}/replay           publish it back to its original topic
+func handleDeadLetters(store *deadletter.Store, publisher kafka.MessagePublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if store == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "Dead letter store not available (MongoDB not connected)")
+			return
+		}
+
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/dlq"), "/")
+		parts := strings.Split(path, "/")
+
+		switch {
+		case path == "" && r.Method == http.MethodGet:
+			limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+			records, err := store.List(r.Context(), deadletter.Filter{
+				Topic:  r.URL.Query().Get("topic"),
+				Status: r.URL.Query().Get("status"),
+				Limit:  limit,
+			})
+			if err != nil {
+				writeDeadLetterError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"dead_letters": records,
+				"count":        len(records),
+			})
+		case len(parts) == 1 && path != "" && r.Method == http.MethodGet:
+			record, err := store.Get(r.Context(), parts[0])
+			if err != nil {
+				writeDeadLetterError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(record)
+		case len(parts) == 2 && parts[1] == "replay" && r.Method == http.MethodPost:
+			if publisher == nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintf(w, "Kafka not available, cannot replay")
+				return
+			}
+			record, err := store.Replay(r.Context(), parts[0], publisher)
+			if err != nil {
+				writeDeadLetterError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(record)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// writeDeadLetterError maps dead letter store errors to HTTP responses
+func writeDeadLetterError(w http.ResponseWriter, err error) {
+	if errors.Is(err, deadletter.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	fmt.Fprintf(w, "%v", err)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"oms-service/config"
+	"oms-service/internal/processor"
+	"oms-service/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// dryRunReportTTL is how long the error report of a dry run can be downloaded
+const dryRunReportTTL = time.Hour
+
+// dryRunUpload validates a spooled upload without writing orders, events or invalid
+// records and responds with the summary. Rejected rows are kept in a local error report
+// that can be downloaded from /dry-runs/{id}/errors for dryRunReportTTL.
+func dryRunUpload(w http.ResponseWriter, r *http.Request, cfg *config.Config, upload *spooledUpload) {
+	defer os.Remove(upload.Path)
+
+	file, err := os.Open(upload.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Failed to read file: %v", err)
+		return
+	}
+	defer file.Close()
+
+	dryRunID := uuid.NewString()
+	reportPath := dryRunReportPath(cfg, dryRunID)
+	reportFile, err := os.Create(reportPath)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Failed to create error report: %v", err)
+		return
+	}
+
+	ctx := tenant.WithID(r.Context(), tenant.FromRequest(r))
+	report, err := processor.DryRunCSV(ctx, file, upload.Filename, reportFile)
+	reportFile.Close()
+	if err != nil {
+		os.Remove(reportPath)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintf(w, "Dry run of %s failed: %v", upload.Filename, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"dry_run_id": dryRunID,
+		"filename":   upload.Filename,
+		"summary":    report,
+	}
+	if report.Rejected > 0 {
+		response["error_report_url"] = "/dry-runs/" + dryRunID + "/errors"
+		time.AfterFunc(dryRunReportTTL, func() { os.Remove(reportPath) })
+	} else {
+		os.Remove(reportPath)
+	}
+	log.Printf("🧪 Dry run %s of %s: %d rows, %d would be created, %d rejected",
+		dryRunID, upload.Filename, report.TotalRows, report.Created, report.Rejected)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleDryRunReports serves the error report of a dry run:
+//
+//	GET /dry-runs/{id}/errors   rejected rows as CSV, in the layout of a correction template
+func handleDryRunReports(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/dry-runs/"), "/"), "/")
+		if len(parts) != 2 || parts[1] != "errors" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if _, err := uuid.Parse(parts[0]); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		file, err := os.Open(dryRunReportPath(cfg, parts[0]))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "Error report not found or expired")
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=dry-run-%s-errors.csv", parts[0]))
+		http.ServeContent(w, r, "", time.Time{}, file)
+	}
+}
+
+// dryRunReportPath returns where the error report of a dry run is kept
+func dryRunReportPath(cfg *config.Config, dryRunID string) string {
+	return filepath.Join(cfg.TempDirectory, "dry-run-"+dryRunID+".csv")
+}
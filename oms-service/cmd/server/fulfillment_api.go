@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"oms-service/internal/fulfillment"
+	"oms-service/internal/orders"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
+)
+
+// handleOrderFulfilment serves the fulfilment API of an order:
+//
+//	GET  /orders/{order_id}/shipments                    shipments with their status history
+//	POST /orders/{order_id}/{pick|pack|ship}             move every open shipment of the order
+//	POST /orders/{order_id}/shipments/{id}/{pick|pack|ship} move one shipment
+//
+// ship takes {"carrier": "...", "tracking_number": "..."} and fulfils the shipped stock in
+// IMS. Repeating a request returns the current state without changing it.
+func handleOrderFulfilment(service *fulfillment.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/orders/"), "/")
+		parts := strings.Split(path, "/")
+
+		switch {
+		case len(parts) == 2 && parts[1] == "shipments" && r.Method == http.MethodGet:
+			shipments, err := service.Shipments(r.Context(), parts[0])
+			if err != nil {
+				writeFulfilmentError(w, err)
+				return
+			}
+			if shipments == nil {
+				shipments = []orders.Shipment{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"order_id":  parts[0],
+				"shipments": shipments,
+			})
+		case (len(parts) == 2 || len(parts) == 4 && parts[1] == "shipments") && r.Method == http.MethodPost:
+			var req fulfillment.Request
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+			if err != nil {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				fmt.Fprintf(w, "Failed to read request: %v", err)
+				return
+			}
+			if len(body) > 0 {
+				if err := json.Unmarshal(body, &req); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, "Invalid request JSON: %v", err)
+					return
+				}
+			}
+			req.Action = parts[len(parts)-1]
+			if len(parts) == 4 {
+				req.ShipmentID = parts[2]
+			}
+
+			order, err := service.Advance(r.Context(), parts[0], req)
+			if err != nil {
+				writeFulfilmentError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(order)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// writeFulfilmentError maps fulfilment errors to HTTP responses
+func writeFulfilmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, orders.ErrOrderNotFound), errors.Is(err, orders.ErrShipmentNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, fulfillment.ErrUnknownAction):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, fulfillment.ErrTrackingRequired):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, fulfillment.ErrInvalidTransition), errors.Is(err, fulfillment.ErrNoShipments),
+		errors.Is(err, fulfillment.ErrOrderHeld), errors.Is(err, orders.ErrShipmentChanged):
+		w.WriteHeader(http.StatusConflict)
+	case imsclient.IsUnavailable(err):
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	fmt.Fprintf(w, "%v", err)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"oms-service/internal/kafka"
+	"oms-service/internal/orders"
+	"oms-service/internal/tenant"
+)
+
+// handleOrderHolds serves the holds of an order:
+//
+//	GET  /orders/{order_id}/holds                     all holds, active and released
+//	POST /orders/{order_id}/holds                     place a hold ({"type": "fraud_review", "reason": "...", "placed_by": "..."})
+//	POST /orders/{order_id}/holds/{hold_id}/release   release a hold ({"released_by": "...", "note": "..."})
+//
+// placed_by and released_by default to the X-User-ID header. Releasing the last active
+// hold re-runs finalization when finalizer is running.
+func handleOrderHolds(finalizer *kafka.OrderFinalizerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/orders/"), "/"), "/")
+		orderID := parts[0]
+		ctx := tenant.WithID(r.Context(), tenant.FromRequest(r))
+
+		switch {
+		case len(parts) == 2 && r.Method == http.MethodGet:
+			order, err := orders.GetOrder(ctx, orderID)
+			if err != nil {
+				writeHoldError(w, err)
+				return
+			}
+			holds := order.Holds
+			if holds == nil {
+				holds = []orders.Hold{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"order_id": orderID,
+				"holds":    holds,
+				"active":   len(order.ActiveHolds()),
+			})
+		case len(parts) == 2 && r.Method == http.MethodPost:
+			var req struct {
+				Type     string `json:"type"`
+				Reason   string `json:"reason"`
+				PlacedBy string `json:"placed_by"`
+			}
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize)).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "Invalid hold JSON: %v", err)
+				return
+			}
+			if req.PlacedBy == "" {
+				req.PlacedBy = r.Header.Get("X-User-ID")
+			}
+			if !orders.ValidHoldType(req.Type) || req.Reason == "" || req.PlacedBy == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "type (fraud_review, address_verification, payment_pending or manual), reason and placed_by are required")
+				return
+			}
+
+			hold, err := orders.PlaceHold(ctx, orderID, req.Type, req.Reason, req.PlacedBy)
+			if err != nil {
+				writeHoldError(w, err)
+				return
+			}
+			log.Printf("⏸️ Hold %s (%s) placed on order %s by %s: %s", hold.HoldID, hold.Type, orderID, hold.PlacedBy, hold.Reason)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(hold)
+		case len(parts) == 4 && parts[3] == "release" && r.Method == http.MethodPost:
+			var req struct {
+				ReleasedBy string `json:"released_by"`
+				Note       string `json:"note"`
+			}
+			if r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, "Invalid release JSON: %v", err)
+					return
+				}
+			}
+			if req.ReleasedBy == "" {
+				req.ReleasedBy = r.Header.Get("X-User-ID")
+			}
+			if req.ReleasedBy == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "released_by is required")
+				return
+			}
+
+			if err := orders.ReleaseHold(ctx, orderID, parts[2], req.ReleasedBy, req.Note); err != nil {
+				writeHoldError(w, err)
+				return
+			}
+			log.Printf("▶️ Hold %s of order %s released by %s", parts[2], orderID, req.ReleasedBy)
+
+			order, err := orders.GetOrder(ctx, orderID)
+			if err != nil {
+				writeHoldError(w, err)
+				return
+			}
+			reevaluated := false
+			if len(order.ActiveHolds()) == 0 && finalizer != nil {
+				if _, err := finalizer.Reevaluate(ctx, orderID); err != nil {
+					log.Printf("⚠️ Re-evaluation of order %s after hold release failed: %v", orderID, err)
+				} else {
+					reevaluated = true
+					if order, err = orders.GetOrder(ctx, orderID); err != nil {
+						writeHoldError(w, err)
+						return
+					}
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"order":       order,
+				"reevaluated": reevaluated,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// writeHoldError maps hold errors to HTTP responses
+func writeHoldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, orders.ErrOrderNotFound), errors.Is(err, orders.ErrHoldNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	fmt.Fprintf(w, "%v", err)
+}
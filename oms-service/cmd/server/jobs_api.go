@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"oms-service/config"
+	"oms-service/internal/jobs"
+	"oms-service/internal/quota"
+	"oms-service/internal/s3"
+)
+
+// handleJobs serves upload job details, their invalid rows and resubmissions:
+//
+//	GET  /jobs/{id}              job with its lineage and resubmissions
+//	GET  /jobs/{id}/invalid-rows rejected rows with structured errors
+//	GET  /jobs/{id}/corrections  rejected rows as an editable CSV template
+//	POST /jobs/{id}/resubmit     upload a corrected template as a child job
+func handleJobs(cfg *config.Config, s3Client *s3.SimpleS3Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/"), "/")
+		if parts[0] == "" || len(parts) > 2 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "Job ID required")
+			return
+		}
+		jobID := parts[0]
+		action := ""
+		if len(parts) == 2 {
+			action = parts[1]
+		}
+
+		method := http.MethodGet
+		if action == "resubmit" {
+			method = http.MethodPost
+		}
+		if r.Method != method {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		job, err := jobs.GetJob(r.Context(), jobID)
+		if err != nil {
+			writeJobError(w, err)
+			return
+		}
+
+		switch action {
+		case "":
+			writeJobDetails(r.Context(), w, job)
+		case "invalid-rows":
+			rows, err := jobs.GetInvalidRows(r.Context(), job.JobID)
+			if err != nil {
+				writeJobError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"job_id":       job.JobID,
+				"invalid_rows": rows,
+				"count":        len(rows),
+			})
+		case "corrections":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"corrections_%s.csv\"", job.JobID))
+			if _, err := jobs.WriteCorrectionTemplate(r.Context(), w, job); err != nil {
+				log.Printf("❌ Failed to write correction template for job %s: %v", job.JobID, err)
+			}
+		case "resubmit":
+			resubmitJob(w, r, cfg, s3Client, job)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "Unknown job action: %s", action)
+		}
+	}
+}
+
+// writeJobDetails responds with a job, the chain of jobs it descends from and its resubmissions
+func writeJobDetails(ctx context.Context, w http.ResponseWriter, job *jobs.Job) {
+	lineage, err := jobs.GetLineage(ctx, job.JobID)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	children, err := jobs.GetChildJobs(ctx, job.JobID)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job":         job,
+		"lineage":     lineage,
+		"resubmitted": children,
+	})
+}
+
+// resubmitJob accepts a corrected template and reprocesses only the rows that failed in job
+func resubmitJob(w http.ResponseWriter, r *http.Request, cfg *config.Config, s3Client *s3.SimpleS3Client, job *jobs.Job) {
+	if job.Status == jobs.StatusProcessing {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "Job %s is still processing", job.JobID)
+		return
+	}
+
+	upload, err := spoolUpload(w, r, cfg.TempDirectory, cfg.MaxFileSize)
+	if err != nil {
+		writeUploadError(w, err, cfg.MaxFileSize)
+		return
+	}
+	if err := quota.ConsumeFile(r.Context(), job.TenantID); err != nil {
+		os.Remove(upload.Path)
+		writeQuotaExceeded(w, err)
+		return
+	}
+
+	key, err := storeUpload(context.Background(), s3Client, upload)
+	if err != nil {
+		os.Remove(upload.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "Failed to read file: %v", err)
+		return
+	}
+
+	child, err := createUploadJob(context.Background(), upload, key, job.JobID, job.TenantID)
+	if err != nil {
+		os.Remove(upload.Path)
+		writeJobError(w, err)
+		return
+	}
+	processUpload(upload, key, child, child.TenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":        child.JobID,
+		"parent_job_id": job.JobID,
+		"status":        child.Status,
+	})
+}
+
+// writeJobError maps job store errors to HTTP responses
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, jobs.ErrNotInitialized):
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	fmt.Fprintf(w, "%v", err)
+}
@@ -1,19 +1,31 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"oms-service/config"
+	"oms-service/internal/allocation"
+	"oms-service/internal/amendment"
+	"oms-service/internal/auth"
+	"oms-service/internal/backlog"
+	"oms-service/internal/deadletter"
+	"oms-service/internal/fulfillment"
+	"oms-service/internal/ims"
+	"oms-service/internal/jobs"
 	"oms-service/internal/kafka"
 	"oms-service/internal/orders"
+	"oms-service/internal/outbox"
 	"oms-service/internal/processor"
+	"oms-service/internal/quota"
+	"oms-service/internal/returns"
 	"oms-service/internal/s3"
 	"oms-service/internal/sqs"
+	"oms-service/internal/tenant"
+	"oms-service/internal/upload"
+	"oms-service/internal/validation"
 	"os"
 	"strings"
 	"time"
@@ -32,6 +44,7 @@ func main() {
 	// Initialize MongoDB using orders package
 	log.Println("🔗 Initializing MongoDB for orders...")
 	mongoURI := cfg.GetMongoDBConnectionString()
+	var dlqStore *deadletter.Store
 	err = orders.InitializeMongoDB(mongoURI)
 	if err != nil {
 		log.Printf("❌ MongoDB initialization failed: %v", err)
@@ -39,6 +52,50 @@ func main() {
 	} else {
 		log.Println("✅ MongoDB connected successfully")
 
+		// Initialize upload job tracking
+		if err := jobs.Initialize(orders.GetMongoClient()); err != nil {
+			log.Printf("⚠️ Upload job tracking initialization failed: %v", err)
+		}
+
+		// Initialize the order event outbox
+		if err := outbox.Initialize(orders.GetMongoClient()); err != nil {
+			log.Printf("⚠️ Outbox initialization failed: %v", err)
+		}
+
+		// Initialize the Kafka dead letter store
+		dlqStore, err = deadletter.NewStore(orders.GetMongoClient())
+		if err != nil {
+			log.Printf("⚠️ Dead letter store initialization failed: %v", err)
+		}
+
+		// Initialize the backlog of on_hold orders waiting for stock
+		if err := backlog.Initialize(orders.GetMongoClient()); err != nil {
+			log.Printf("⚠️ On-hold backlog initialization failed: %v", err)
+		}
+
+		// Initialize per-tenant allocation policies
+		if err := allocation.Initialize(orders.GetMongoClient()); err != nil {
+			log.Printf("⚠️ Allocation policy store initialization failed, using all-or-nothing allocation: %v", err)
+		}
+
+		// Initialize customer returns
+		if err := returns.Initialize(orders.GetMongoClient()); err != nil {
+			log.Printf("⚠️ Returns store initialization failed: %v", err)
+		}
+
+		// Initialize per-tenant validation rules
+		if err := validation.Initialize(orders.GetMongoClient()); err != nil {
+			log.Printf("⚠️ Validation rule store initialization failed, using default rules: %v", err)
+		}
+
+		// Initialize per-tenant daily upload quotas
+		if err := quota.Initialize(orders.GetMongoClient(), quota.Limits{
+			FilesPerDay: cfg.UploadQuotaFilesPerDay,
+			RowsPerDay:  cfg.UploadQuotaRowsPerDay,
+		}); err != nil {
+			log.Printf("⚠️ Upload quota store initialization failed, quotas are not enforced: %v", err)
+		}
+
 		// Create sample orders for demonstration
 		log.Println("📊 Creating sample orders...")
 		err = orders.CreateSampleOrders()
@@ -57,7 +114,22 @@ func main() {
 
 	// Initialize IMS client for SKU/Hub validation
 	log.Println("Initializing IMS client...")
-	processor.InitializeIMSClient(cfg.IMSServiceURL)
+	imsClient := ims.NewClient(cfg)
+	processor.InitializeIMSClient(imsClient)
+	var grpcClient *ims.GRPCClient
+	if cfg.IMSGRPCAddress != "" {
+		// Validate CSV batches and move the stock of orders over gRPC, behind the circuit
+		// breaker of the HTTP client; the JSON API stays in use for everything else
+		grpcClient, err = ims.NewGRPCClient(cfg, imsClient)
+		if err != nil {
+			log.Printf("⚠️ IMS gRPC client unavailable, using HTTP: %v", err)
+			grpcClient = nil
+		} else {
+			defer grpcClient.Close()
+			processor.InitializeBatchValidator(grpcClient)
+			log.Printf("IMS gRPC client initialized for %s", cfg.IMSGRPCAddress)
+		}
+	}
 	log.Printf("IMS client initialized for URL: %s", cfg.IMSServiceURL) // Initialize Kafka with proper configuration and graceful error handling
 	log.Println("Initializing Kafka...")
 	os.Setenv("KAFKA_ENABLED", "true") // Try to enable Kafka
@@ -70,14 +142,38 @@ func main() {
 		log.Println("Kafka initialized successfully")
 	} // Initialize Kafka consumer for order events (if Kafka is available)
 	kafkaEnabled := os.Getenv("KAFKA_ENABLED") == "true"
+	var eventPublisher kafka.MessagePublisher
+	var backlogRetrier *kafka.BacklogRetrier
+	var orderFinalizer *kafka.OrderFinalizerHandler
 	if kafkaEnabled {
 		log.Println("🔄 Initializing Kafka consumer...")
 		kafkaConsumer := kafka.NewDefaultConsumer()
 
+		// Failed messages go through retry topics with backoff, then to a dead-letter topic
+		eventProducer, err := kafka.NewKafkaProducer(cfg.KafkaBrokers)
+		if err != nil {
+			log.Printf("⚠️ Kafka event producer initialization failed: %v", err)
+		} else {
+			eventPublisher = eventProducer
+			kafkaConsumer.SetRetryPublisher(eventProducer)
+		}
+		kafkaConsumer.SetRetryPolicy(kafka.DefaultRetryPolicy())
+		if dlqStore != nil {
+			kafkaConsumer.SetDeadLetterStore(dlqStore)
+		}
+
 		// Register order finalizer handler with inventory management
-		orderFinalizer := kafka.NewOrderFinalizerHandler(cfg.IMSServiceURL)
+		orderFinalizer = kafka.NewOrderFinalizerHandler(imsClient)
+		if grpcClient != nil {
+			orderFinalizer.SetOrderStock(grpcClient)
+		}
 		kafkaConsumer.RegisterOrderEventHandler(orderFinalizer)
 
+		// Retry on_hold orders when IMS reports new stock, and sweep the backlog periodically
+		backlogRetrier = kafka.NewBacklogRetrier(orderFinalizer, cfg.OnHoldRetryInterval)
+		kafkaConsumer.RegisterHandler(kafka.InventoryEventsTopic(), backlogRetrier)
+		backlogRetrier.Start(context.Background())
+
 		// Start Kafka consumer in background
 		go func() {
 			err := kafkaConsumer.Subscribe(context.Background())
@@ -90,6 +186,13 @@ func main() {
 		log.Println("🔄 Kafka consumer disabled - Kafka not available")
 	}
 
+	// Relay order events written to the outbox with each order
+	if eventPublisher != nil {
+		outbox.NewRelay(eventPublisher, time.Second, 100).Start(context.Background())
+	} else {
+		log.Println("📮 Outbox relay disabled - Kafka not available, events stay pending")
+	}
+
 	// S3 setup - using go_commons S3 client
 	s3Client, err := s3.NewSimpleS3Client()
 	if err != nil {
@@ -122,6 +225,10 @@ func main() {
 	}
 
 	log.Printf("Using queue URL: %s", *queue.Url)
+	// Create SQS client for publishing (RE-ENABLED with compression fix)
+	sqsClient := sqs.New(queue)
+	log.Println("SQS client initialized successfully with compression disabled")
+
 	// Start SQS consumer using go_commons with improved handler
 	go func() {
 		log.Println("Starting SQS consumer with go_commons...")
@@ -144,19 +251,8 @@ func main() {
 						if key, ok := messageData["key"]; ok {
 							log.Printf("🚀 [SQS Consumer] Starting CSV processing from S3: bucket=%s, key=%s", bucket, key)
 							// Process CSV from S3 using the distributed logic
-							go func(b, k string) {
-								err := processor.ProcessCSVFromS3Real(ctx, b, k)
-								if err != nil {
-									log.Printf("❌ [CSV Processor] Real S3 processing failed: %v", err)
-									log.Printf("🔄 [CSV Processor] Falling back to standard processing")
-									err = processor.ProcessCSVFromS3(ctx, b, k)
-									if err != nil {
-										log.Printf("❌ [CSV Processor] Standard processing also failed: %v", err)
-									}
-								} else {
-									log.Printf("✅ [CSV Processor] Real S3 processing completed successfully for %s", k)
-								}
-							}(bucket, key)
+							ctx := tenant.WithID(ctx, messageData["tenant_id"])
+							go processUploadMessage(ctx, sqsClient, messageData)
 						}
 					}
 				}
@@ -180,10 +276,6 @@ func main() {
 			log.Printf("SQS consumer error: %v", err)
 		}
 	}()
-
-	// Create SQS client for publishing (RE-ENABLED with compression fix)
-	sqsClient := sqs.New(queue)
-	log.Println("SQS client initialized successfully with compression disabled")
 	// HTTP handlers
 	http.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
@@ -204,6 +296,8 @@ func main() {
 		<strong>Service Status:</strong> ✅ Running on port 8088<br>
 		<strong>Endpoints:</strong><br>
 		• POST /upload - Upload CSV files<br>
+		• POST /orders - Submit a JSON order<br>
+		• POST /orders/bulk - Submit NDJSON orders<br>
 		• GET /health - Health check<br>
 		• GET /stats - Order statistics<br>
 		• GET /invalid-files - List invalid files
@@ -218,72 +312,50 @@ func main() {
 			return
 		}
 
-		file, header, err := r.FormFile("file")
+		upload, err := spoolUpload(w, r, cfg.TempDirectory, cfg.MaxFileSize)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			fmt.Fprintf(w, "Failed to get file: %v", err)
+			writeUploadError(w, err, cfg.MaxFileSize)
+			return
+		}
+		if r.URL.Query().Get("dry_run") == "true" {
+			dryRunUpload(w, r, cfg, upload)
+			return
+		}
+		tenantID := tenant.FromRequest(r)
+		if err := quota.ConsumeFile(r.Context(), tenantID); err != nil {
+			os.Remove(upload.Path)
+			writeQuotaExceeded(w, err)
 			return
 		}
-		defer file.Close()
-
-		// Generate unique filename with timestamp
-		timestamp := time.Now().Format("20060102-150405")
-		filename := fmt.Sprintf("%s-%s", timestamp, header.Filename)
 
-		// Upload to S3
-		bucketName := "oms-orders"
-		// Read file content
-		fileBytes, err := io.ReadAll(file)
+		bucketName := uploadBucket
+		filename, err := storeUpload(context.Background(), s3Client, upload)
 		if err != nil {
+			os.Remove(upload.Path)
 			w.WriteHeader(http.StatusInternalServerError)
 			fmt.Fprintf(w, "Failed to read file: %v", err)
 			return
 		}
 
-		// Upload the file using SimpleS3Client
-		log.Printf("File received successfully: %s (%d bytes)", filename, len(fileBytes))
-		// Upload to S3 using go_commons
-		err = s3Client.Upload(context.Background(), bucketName, filename, bytes.NewReader(fileBytes), "text/csv")
-		s3UploadSuccessful := (err == nil)
-
-		if s3UploadSuccessful {
-			log.Printf("File uploaded to S3 successfully: bucket=%s, key=%s", bucketName, filename)
-		} else {
-			log.Printf("⚠️ S3 upload skipped (LocalStack not available), processing directly")
+		// Track the upload so invalid rows can be corrected and resubmitted
+		job, err := createUploadJob(context.Background(), upload, filename, "", tenantID)
+		if err != nil {
+			log.Printf("⚠️ Upload job tracking unavailable: %v", err)
 		}
 
 		// Send SQS message for processing using improved go_commons integration
-		message := map[string]string{
-			"bucket":            bucketName,
-			"key":               filename,
-			"original_filename": header.Filename,
-		}
-		messageBytes, _ := json.Marshal(message)
-		sqsMessage := &commonsqs.Message{
-			Value: messageBytes,
-		}
-		// Try SQS processing first
-		sqsErr := sqsClient.Publish(context.Background(), sqsMessage)
-		if sqsErr != nil {
-			log.Printf("Failed to send SQS message: %v", sqsErr)
-		} else {
-			log.Printf("SQS message sent successfully: %s", string(messageBytes))
-		}
+		publishUpload(context.Background(), sqsClient, bucketName, filename, upload.Filename, tenantID)
 
 		// ALWAYS process CSV directly to ensure it gets processed
 		// This ensures your files are processed regardless of S3/SQS/Kafka status
-		log.Printf("🔄 Processing CSV directly to ensure completion: %s", filename)
-		go func() {
-			processingErr := processor.ProcessCSVContentDirectly(context.Background(), fileBytes, filename)
-			if processingErr != nil {
-				log.Printf("❌ CSV processing error: %v", processingErr)
-			} else {
-				log.Printf("✅ CSV processing completed successfully - stats should update now")
-			}
-		}()
+		processUpload(upload, filename, job, tenantID)
 
 		// Response
-		fmt.Fprintf(w, "File uploaded and processing started: %s", header.Filename)
+		if job != nil {
+			fmt.Fprintf(w, "File uploaded and processing started: %s (job_id: %s)", upload.Filename, job.JobID)
+			return
+		}
+		fmt.Fprintf(w, "File uploaded and processing started: %s", upload.Filename)
 	})
 	// Stats endpoint
 	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
@@ -293,10 +365,11 @@ func main() {
 		}
 
 		// Get order statistics from the new orders package
-		stats, err := orders.GetOrderStats()
+		ctx := tenant.WithID(r.Context(), tenant.FromRequest(r))
+		stats, err := orders.GetOrderStats(ctx)
 		if err != nil {
 			// Fallback to legacy processor stats
-			legacyStats, legacyErr := processor.GetOrderStats(context.Background())
+			legacyStats, legacyErr := processor.GetOrderStats(ctx)
 			if legacyErr != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				fmt.Fprintf(w, "Failed to get stats: %v", err)
@@ -310,14 +383,18 @@ func main() {
 		json.NewEncoder(w).Encode(stats)
 	})
 
-	// Orders endpoint - to view all orders
+	// Orders endpoint - view all orders or submit a single JSON order
 	http.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			handleCreateOrder(w, r)
+			return
+		}
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
 
-		allOrders, err := orders.GetAllOrders()
+		allOrders, err := orders.GetAllOrders(tenant.WithID(r.Context(), tenant.FromRequest(r)))
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			fmt.Fprintf(w, "Failed to get orders: %v", err)
@@ -331,6 +408,53 @@ func main() {
 		})
 	})
 
+	// Bulk NDJSON order ingestion endpoint
+	http.HandleFunc("/orders/bulk", handleBulkOrders(cfg.MaxFileSize))
+	// Amend the lines of an order, hold and release it, and pick, pack and ship its shipments
+	fulfillmentService := fulfillment.NewService(imsClient)
+	if grpcClient != nil {
+		fulfillmentService.SetShipmentStock(grpcClient)
+	}
+	http.HandleFunc("/orders/", handleOrderActions(
+		handleOrderFulfilment(fulfillmentService),
+		handleOrderAmendment(amendment.NewService(imsClient)),
+		handleOrderHolds(orderFinalizer),
+	))
+	// Customer returns (RMA) that restock IMS
+	returnService := returns.NewService(imsClient)
+	http.HandleFunc("/returns", handleReturns(returnService))
+	http.HandleFunc("/returns/", handleReturns(returnService))
+	// Direct-to-S3 uploads through pre-signed URLs
+	uploadService, err := upload.NewService(upload.Config{
+		TempBucket:      cfg.UploadTempBucket,
+		PermanentBucket: uploadBucket,
+		Region:          cfg.S3Region,
+	})
+	if err != nil {
+		log.Printf("⚠️ Direct upload service unavailable: %v", err)
+		uploadService = nil
+	}
+	http.HandleFunc("/dry-runs/", handleDryRunReports(cfg))
+	http.HandleFunc("/uploads/presign", handlePresignUpload(cfg, uploadService))
+	http.HandleFunc("/uploads/", handleUploadActions(cfg, uploadService, sqsClient))
+	// Upload jobs: invalid rows, correction templates and resubmission
+	http.HandleFunc("/jobs/", handleJobs(cfg, s3Client))
+	// Per-tenant order validation rules
+	http.HandleFunc("/validation-rules", handleValidationRules)
+	// Per-tenant partial allocation and backorder policy
+	http.HandleFunc("/allocation-policy", handleAllocationPolicy)
+
+	http.HandleFunc("/upload-quota", handleUploadQuota)
+	// Kafka dead-letter admin
+	http.HandleFunc("/admin/dlq", handleDeadLetters(dlqStore, eventPublisher))
+	http.HandleFunc("/admin/dlq/", handleDeadLetters(dlqStore, eventPublisher))
+	// Outbox relay lag
+	http.HandleFunc("/admin/outbox/lag", handleOutboxLag)
+	http.HandleFunc("/admin/outbox/requeue", handleOutboxRequeue)
+
+	http.HandleFunc("/admin/on-hold", handleOnHoldBacklog(backlogRetrier))
+	http.HandleFunc("/admin/on-hold/", handleOnHoldBacklog(backlogRetrier))
+
 	// Create sample data endpoint
 	http.HandleFunc("/create-sample-data", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -414,10 +538,53 @@ func main() {
 	log.Println("🚀 OMS Service starting on :8088")
 	log.Println("📋 Endpoints available:")
 	log.Println("  POST /upload - Upload CSV files")
+	log.Println("  POST /upload?dry_run=true - Validate a CSV without creating orders")
+	log.Println("  GET  /dry-runs/{id}/errors - Download the rejected rows of a dry run")
+	log.Println("  POST /uploads/presign - Get a pre-signed URL to upload a CSV directly to S3")
+	log.Println("  POST /uploads/{upload_id}/complete - Process a CSV uploaded through a pre-signed URL")
+	log.Println("  POST /uploads/{id}/reprocess[?dry_run=true] - Re-ingest a stored upload, skipping existing orders")
 	log.Println("  GET  /stats - Order statistics")
+	log.Println("  POST /orders - Submit a single JSON order")
+	log.Println("  POST /orders/bulk - Submit NDJSON orders")
+	log.Println("  PATCH /orders/{id}/lines - Change quantities, remove or add order lines")
+	log.Println("  GET/POST /orders/{id}/holds - List or place holds (fraud_review, address_verification, payment_pending, manual)")
+	log.Println("  POST /orders/{id}/holds/{hold_id}/release - Release a hold and re-evaluate the order")
+	log.Println("  GET  /orders/{id}/shipments - Shipments of an order with their history")
+	log.Println("  POST /orders/{id}/{pick|pack|ship} - Advance all shipments of an order")
+	log.Println("  POST /orders/{id}/shipments/{shipment_id}/{pick|pack|ship} - Advance one shipment")
+	log.Println("  GET  /jobs/{id} - Upload job status and lineage")
+	log.Println("  GET  /jobs/{id}/invalid-rows - Invalid rows with field errors")
+	log.Println("  GET  /jobs/{id}/corrections - Download invalid rows as a correction template")
+	log.Println("  POST /jobs/{id}/resubmit - Re-upload corrected rows for a job")
+	log.Println("  POST /returns - Open a return against shipped units of an order")
+	log.Println("  GET  /returns, /returns/{id} - List and inspect returns")
+	log.Println("  POST /returns/{id}/receive, /returns/{id}/grade - Receive, grade and restock a return")
+	log.Println("  GET/PUT /validation-rules - Order validation rules for the caller's tenant")
+	log.Println("  GET/PUT /allocation-policy - Partial allocation policy for the caller's tenant")
+	log.Println("  GET  /upload-quota - Daily upload quotas of the caller's tenant and today's usage")
+	log.Println("  GET  /admin/dlq - List dead-lettered Kafka messages")
+	log.Println("  GET  /admin/dlq/{id} - Inspect a dead-lettered message")
+	log.Println("  POST /admin/dlq/{id}/replay - Replay a dead-lettered message")
+	log.Println("  GET  /admin/outbox/lag - Pending order events not yet published to Kafka")
+	log.Println("  POST /admin/outbox/requeue - Retry order events the relay gave up on")
 	log.Println("  GET  /invalid-files - List invalid CSV files")
 	log.Println("  GET  /invalid-files/{filename} - Download invalid CSV file")
 	log.Println("  GET  /health - Health check")
+	log.Println("  GET  /debug/vars - Metrics, including oms_degraded_decisions by component and mode")
 	log.Println("🔗 All services using go_commons (S3, SQS, Kafka, CSV)")
-	log.Fatal(http.ListenAndServe(":8088", nil))
+
+	// Authenticate every request but the health check, the tenant comes from the credential
+	handler := http.Handler(http.DefaultServeMux)
+	if cfg.AuthMode == "off" {
+		log.Println("⚠️ AUTH_MODE=off: requests are not authenticated, the tenant comes from X-Tenant-ID")
+	} else {
+		verifier, err := newJWTVerifier(cfg)
+		if err != nil {
+			log.Fatalf("Failed to configure JWT verification: %v", err)
+		}
+		tokens := auth.NewServiceTokens(cfg.IMSJWTSecret, cfg.IMSAPIKey)
+		handler = withAuth(auth.NewAuthenticator(verifier, imsClient, tokens, cfg.AuthKeyCacheTTL), handler)
+		log.Println("🔐 Authentication enforced: send an API key or JWT as a bearer token")
+	}
+	log.Fatal(http.ListenAndServe(":8088", handler))
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"oms-service/internal/processor"
+	"oms-service/internal/tenant"
+	"oms-service/internal/validation"
+)
+
+const (
+	// maxOrderBodySize bounds the body of a single JSON order
+	maxOrderBodySize = 1 << 20 // 1MB
+	// bulkOrderBatchSize matches the batch size used for CSV processing
+	bulkOrderBatchSize = 100
+)
+
+// handleCreateOrder ingests a single JSON order through the CSV pipeline
+func handleCreateOrder(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "Failed to read order: %v", err)
+		return
+	}
+
+	record, err := decodeOrderRecord(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Invalid order JSON: %v", err)
+		return
+	}
+
+	ctx := tenant.WithID(r.Context(), tenant.FromRequest(r))
+	result := processor.ProcessOrderRecords(ctx, []map[string]interface{}{record})[0]
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Accepted {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleOrderActions routes the sub-resources of /orders/{order_id}
+func handleOrderActions(fulfilment, amendment, holds http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/orders/"), "/"), "/")
+		switch {
+		case len(parts) == 2 && parts[1] == "lines":
+			amendment(w, r)
+		case len(parts) >= 2 && parts[1] == "holds":
+			holds(w, r)
+		default:
+			fulfilment(w, r)
+		}
+	}
+}
+
+// handleBulkOrders ingests an NDJSON stream of orders in batches and reports each line's outcome
+func handleBulkOrders(maxBodySize int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := tenant.WithID(r.Context(), tenant.FromRequest(r))
+		scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxBodySize))
+		scanner.Buffer(make([]byte, 64*1024), maxOrderBodySize)
+
+		var results []processor.OrderResult
+		var batch []map[string]interface{}
+		var batchLines []int
+		accepted, rejected := 0, 0
+
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			for n, result := range processor.ProcessOrderRecords(ctx, batch) {
+				result.Index = batchLines[n]
+				results = append(results, result)
+			}
+			batch, batchLines = nil, nil
+		}
+
+		line := 0
+		for scanner.Scan() {
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+			line++
+
+			record, err := decodeOrderRecord(raw)
+			if err != nil {
+				results = append(results, processor.OrderResult{
+					Index: line,
+					Errors: []validation.FieldError{
+						validation.NewFieldError("", validation.CodeInvalidFormat, fmt.Sprintf("Invalid order JSON: %v", err)),
+					},
+				})
+				continue
+			}
+
+			batch = append(batch, record)
+			batchLines = append(batchLines, line)
+			if len(batch) >= bulkOrderBatchSize {
+				flush()
+			}
+		}
+		flush()
+
+		if err := scanner.Err(); err != nil {
+			log.Printf("❌ Bulk order stream aborted after line %d: %v", line, err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   fmt.Sprintf("failed to read NDJSON stream: %v", err),
+				"results": results,
+			})
+			return
+		}
+
+		for _, result := range results {
+			if result.Accepted {
+				accepted++
+			} else {
+				rejected++
+			}
+		}
+		log.Printf("📦 Bulk order ingestion completed: %d accepted, %d rejected", accepted, rejected)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accepted": accepted,
+			"rejected": rejected,
+			"results":  results,
+		})
+	}
+}
+
+// decodeOrderRecord converts a JSON order object into the record format used for CSV rows
+func decodeOrderRecord(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // keep numeric fields as written so they parse like CSV values
+
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if value == nil {
+			continue
+		}
+		record[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return record, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"oms-service/internal/outbox"
+)
+
+// handleOutboxLag reports how many order events are waiting to be published and how old the oldest is
+func handleOutboxLag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	lag, err := outbox.GetLag(r.Context())
+	if err != nil {
+		if errors.Is(err, outbox.ErrNotInitialized) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(w, "Failed to get outbox lag: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lag)
+}
+
+// handleOutboxRequeue makes order events the relay gave up on pending again
+func handleOutboxRequeue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	requeued, err := outbox.Requeue(r.Context())
+	if err != nil {
+		if errors.Is(err, outbox.ErrNotInitialized) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(w, "Failed to requeue outbox entries: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"requeued": requeued})
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"oms-service/config"
+	"oms-service/internal/jobs"
+	"oms-service/internal/processor"
+	"oms-service/internal/quota"
+	"oms-service/internal/sqs"
+	"oms-service/internal/tenant"
+	"oms-service/internal/upload"
+)
+
+// directUploadUseCase groups pre-signed order uploads in the temporary bucket
+const directUploadUseCase = "order-csv"
+
+// handlePresignUpload issues a pre-signed URL the client uploads a CSV to directly and
+// registers the upload job that tracks it:
+//
+//	POST /uploads/presign {"filename": "orders.csv", "content_type": "text/csv"}
+func handlePresignUpload(cfg *config.Config, service *upload.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if service == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "Direct uploads are not available")
+			return
+		}
+
+		var req struct {
+			Filename    string `json:"filename"`
+			ContentType string `json:"content_type"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize)).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid presign JSON: %v", err)
+			return
+		}
+		req.Filename = filepath.Base(req.Filename)
+		if req.Filename == "." || req.Filename == "/" || !allowedExtension(cfg, req.Filename) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "filename with one of the extensions %s is required", strings.Join(cfg.AllowedExtensions, ", "))
+			return
+		}
+		if req.ContentType == "" {
+			req.ContentType = "text/csv"
+		}
+
+		tenantID := tenant.FromRequest(r)
+		presigned, err := service.GenerateUploadURL(tenantID, directUploadUseCase, req.ContentType, req.Filename)
+		if err != nil {
+			log.Printf("❌ Failed to generate upload URL for %s: %v", req.Filename, err)
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprintf(w, "Failed to generate upload URL: %v", err)
+			return
+		}
+
+		job := &jobs.Job{
+			Filename: req.Filename,
+			UploadID: presigned.EphemeralUploadID,
+			TenantID: tenantID,
+			Status:   jobs.StatusAwaitingUpload,
+		}
+		if err := jobs.CreateJob(r.Context(), job); err != nil {
+			writeJobError(w, err)
+			return
+		}
+		log.Printf("🔏 Issued upload URL %s for %s (job %s)", job.UploadID, job.Filename, job.JobID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"upload_id":  job.UploadID,
+			"upload_url": presigned.URL,
+			"job_id":     job.JobID,
+			"status":     job.Status,
+		})
+	}
+}
+
+// handleUploadActions completes direct uploads and re-runs stored ones:
+//
+//	POST /uploads/{upload_id}/complete            process a file put through a pre-signed URL
+//	POST /uploads/{id}/reprocess[?dry_run=true]   re-ingest a stored file, by job or upload ID
+func handleUploadActions(cfg *config.Config, service *upload.Service, sqsClient *sqs.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/uploads/"), "/"), "/")
+		if len(parts) != 2 || (parts[1] != "complete" && parts[1] != "reprocess") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if parts[1] == "reprocess" {
+			reprocessUpload(w, r, parts[0])
+			return
+		}
+		completeUpload(w, r, cfg, service, sqsClient, parts[0])
+	}
+}
+
+// completeUpload moves a direct upload to permanent storage and processes it like a
+// multipart upload
+func completeUpload(w http.ResponseWriter, r *http.Request, cfg *config.Config, service *upload.Service, sqsClient *sqs.Client, uploadID string) {
+	if service == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "Direct uploads are not available")
+		return
+	}
+
+	tenantID := tenant.FromRequest(r)
+	job, err := jobs.GetJobByUploadID(r.Context(), uploadID)
+	if err == nil && job.TenantID != tenantID {
+		err = jobs.ErrJobNotFound
+	}
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	if job.Status != jobs.StatusAwaitingUpload {
+		writeCompleteConflict(w, job)
+		return
+	}
+	if err := quota.ConsumeFile(r.Context(), tenantID); err != nil {
+		writeQuotaExceeded(w, err)
+		return
+	}
+
+	spooled, err := spoolDirectUpload(r.Context(), service, cfg, job)
+	if err != nil {
+		if errors.Is(err, errFileTooLarge) {
+			writeUploadError(w, err, cfg.MaxFileSize)
+			return
+		}
+		log.Printf("❌ Failed to fetch direct upload %s: %v", uploadID, err)
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprintf(w, "Failed to fetch uploaded file: %v", err)
+		return
+	}
+
+	bucket, key := service.PermanentBucket(), uploadID
+	stored, err := service.TransferToPermanentStorage(r.Context(), uploadID, tenantID)
+	if err != nil {
+		log.Printf("⚠️ Transfer of upload %s to permanent storage skipped: %v", uploadID, err)
+	} else if stored != nil && stored.Key != "" {
+		key = stored.Key
+	}
+
+	if err := jobs.StartUpload(r.Context(), job, bucket, key); err != nil {
+		os.Remove(spooled.Path)
+		if errors.Is(err, jobs.ErrUploadCompleted) {
+			writeCompleteConflict(w, job)
+			return
+		}
+		writeJobError(w, err)
+		return
+	}
+
+	publishUpload(context.Background(), sqsClient, bucket, key, job.Filename, tenantID)
+	processUpload(spooled, key, job, tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"upload_id": uploadID,
+		"job_id":    job.JobID,
+		"status":    job.Status,
+		"bucket":    bucket,
+		"key":       key,
+	})
+}
+
+// reprocessUpload re-ingests the stored file of the upload job or direct upload id. Orders
+// that already exist are skipped. A dry run reports what would be created, skipped or
+// rejected; otherwise a new job tracks the reprocessing.
+func reprocessUpload(w http.ResponseWriter, r *http.Request, id string) {
+	tenantID := tenant.FromRequest(r)
+	job, err := jobs.GetJob(r.Context(), id)
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		job, err = jobs.GetJobByUploadID(r.Context(), id)
+	}
+	if err == nil && job.TenantID != tenantID {
+		err = jobs.ErrJobNotFound
+	}
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	if job.Key == "" || job.Status == jobs.StatusAwaitingUpload || job.Status == jobs.StatusProcessing {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprintf(w, "Job %s has no stored file to reprocess yet (status %s)", job.JobID, job.Status)
+		return
+	}
+	bucket := job.Bucket
+	if bucket == "" {
+		bucket = uploadBucket
+	}
+
+	ctx := tenant.WithID(r.Context(), tenantID)
+	if r.URL.Query().Get("dry_run") == "true" {
+		report, err := processor.ReprocessCSVFromS3(ctx, bucket, job.Key, true)
+		if err != nil {
+			log.Printf("❌ Dry run of %s/%s failed: %v", bucket, job.Key, err)
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprintf(w, "Failed to reprocess %s/%s: %v", bucket, job.Key, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	reprocess := &jobs.Job{
+		Filename:    job.Filename,
+		Bucket:      bucket,
+		Key:         job.Key,
+		ReprocessOf: job.JobID,
+		TenantID:    tenantID,
+	}
+	if err := jobs.CreateJob(r.Context(), reprocess); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	go func() {
+		if err := processor.ReprocessJobFromS3(context.Background(), reprocess); err != nil {
+			log.Printf("❌ Reprocessing %s/%s failed: %v", bucket, job.Key, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":       reprocess.JobID,
+		"reprocess_of": job.JobID,
+		"bucket":       bucket,
+		"key":          job.Key,
+		"status":       reprocess.Status,
+	})
+}
+
+// spoolDirectUpload downloads a direct upload from the temporary bucket into the temp
+// directory, rejecting files larger than the configured maximum
+func spoolDirectUpload(ctx context.Context, service *upload.Service, cfg *config.Config, job *jobs.Job) (*spooledUpload, error) {
+	if err := os.MkdirAll(cfg.TempDirectory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(cfg.TempDirectory, "upload-*.csv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	err = service.DownloadToWriter(ctx, job.UploadID, tmp)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	var size int64
+	if err == nil {
+		info, statErr := os.Stat(tmp.Name())
+		if statErr != nil {
+			err = statErr
+		} else if size = info.Size(); size > cfg.MaxFileSize {
+			err = errFileTooLarge
+		}
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return &spooledUpload{Path: tmp.Name(), Filename: job.Filename, Size: size}, nil
+}
+
+// allowedExtension reports whether filename has one of the configured upload extensions
+func allowedExtension(cfg *config.Config, filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, allowed := range cfg.AllowedExtensions {
+		if ext == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCompleteConflict reports a direct upload that was already completed
+func writeCompleteConflict(w http.ResponseWriter, job *jobs.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  jobs.ErrUploadCompleted.Error(),
+		"job_id": job.JobID,
+		"status": job.Status,
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"oms-service/internal/quota"
+	"oms-service/internal/tenant"
+)
+
+// handleUploadQuota reports the daily upload quotas of the caller's tenant and what it
+// uploaded today
+func handleUploadQuota(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID := tenant.FromRequest(r)
+	usage, err := quota.UsageFor(r.Context(), tenantID)
+	if err != nil {
+		if errors.Is(err, quota.ErrNotInitialized) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(w, "Failed to read upload usage: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"limits": quota.LimitsFor(r.Context(), tenantID),
+		"usage":  usage,
+	})
+}
+
+// writeQuotaExceeded rejects an upload over the tenant's daily quota with 429 and a
+// Retry-After of the time left until the quota resets
+func writeQuotaExceeded(w http.ResponseWriter, err error) {
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(exceeded.RetryAfter.Seconds()))))
+	}
+	log.Printf("⚠️ Upload rejected: %v", err)
+	w.WriteHeader(http.StatusTooManyRequests)
+	fmt.Fprintf(w, "Upload rejected: %v", err)
+}
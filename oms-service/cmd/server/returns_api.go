@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"oms-service/internal/orders"
+	"oms-service/internal/returns"
+	"oms-service/internal/tenant"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
+)
+
+// handleReturns serves the returns (RMA) API:
+//
+//	POST /returns                     open a return against shipped units of an order
+//	GET  /returns?order_id=&limit=    list returns, newest first
+//	GET  /returns/{return_id}         a return with its grading, refund and history
+//	POST /returns/{return_id}/receive record arrival at a hub ({"hub_id": "..."})
+//	POST /returns/{return_id}/grade   grade units, restock them in IMS and complete the return
+//
+// Lists are scoped to the X-Tenant-ID tenant when the header is present.
+func handleReturns(service *returns.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/returns"), "/")
+		parts := strings.Split(path, "/")
+		ctx := tenant.WithID(r.Context(), tenant.FromRequest(r))
+
+		switch {
+		case path == "" && r.Method == http.MethodPost:
+			var req returns.CreateRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize)).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "Invalid return JSON: %v", err)
+				return
+			}
+			ret, err := service.Create(ctx, req)
+			if err != nil {
+				writeReturnError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(ret)
+		case path == "" && r.Method == http.MethodGet:
+			tenantID := ""
+			if r.Header.Get(tenant.Header) != "" {
+				tenantID = tenant.FromRequest(r)
+			}
+			limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+			list, err := returns.List(ctx, tenantID, r.URL.Query().Get("order_id"), limit)
+			if err != nil {
+				writeReturnError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"returns": list, "count": len(list)})
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			ret, err := returns.Get(ctx, parts[0])
+			if err != nil {
+				writeReturnError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ret)
+		case len(parts) == 2 && parts[1] == "receive" && r.Method == http.MethodPost:
+			var req struct {
+				HubID string `json:"hub_id"`
+			}
+			if r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, "Invalid request JSON: %v", err)
+					return
+				}
+			}
+			ret, err := service.Receive(ctx, parts[0], req.HubID)
+			if err != nil {
+				writeReturnError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ret)
+		case len(parts) == 2 && parts[1] == "grade" && r.Method == http.MethodPost:
+			var req returns.GradeRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodySize)).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, "Invalid grade JSON: %v", err)
+				return
+			}
+			ret, err := service.Grade(ctx, parts[0], req)
+			if err != nil {
+				writeReturnError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ret)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// writeReturnError maps returns errors to HTTP responses
+func writeReturnError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, returns.ErrNotFound), errors.Is(err, orders.ErrOrderNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, returns.ErrInvalidReturn):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	case errors.Is(err, returns.ErrInvalidStatus):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, returns.ErrNotInitialized), imsclient.IsUnavailable(err):
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	fmt.Fprintf(w, "%v", err)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"oms-service/internal/degraded"
+	"oms-service/internal/jobs"
+	"oms-service/internal/processor"
+	"oms-service/internal/s3"
+	"oms-service/internal/sqs"
+	"oms-service/internal/tenant"
+
+	commonsqs "github.com/omniful/go_commons/sqs"
+)
+
+const (
+	// multipartOverhead leaves room for boundaries and part headers on top of the file itself
+	multipartOverhead = 1 << 20 // 1MB
+	// uploadBucket receives every order CSV upload
+	uploadBucket = "oms-orders"
+	// maxUploadAttempts bounds how often an upload message is queued again while S3 is down
+	maxUploadAttempts = 5
+	// uploadRetryDelay is the delay before the first retry, doubled on each attempt
+	uploadRetryDelay = 30 * time.Second
+)
+
+// errFileTooLarge is returned when an upload exceeds Config.MaxFileSize
+var errFileTooLarge = errors.New("file exceeds maximum upload size")
+
+// spooledUpload is a multipart file part copied to local disk
+type spooledUpload struct {
+	Path     string
+	Filename string
+	Size     int64
+}
+
+// spoolUpload streams the "file" part of a multipart request into tempDir
+// without holding it in memory, rejecting files larger than maxSize
+func spoolUpload(w http.ResponseWriter, r *http.Request, tempDir string, maxSize int64) (*spooledUpload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart form: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, fmt.Errorf("missing form field \"file\"")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart form: %w", err)
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		if err := os.MkdirAll(tempDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		tmp, err := os.CreateTemp(tempDir, "upload-*.csv")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+
+		size, err := io.Copy(tmp, io.LimitReader(part, maxSize+1))
+		closeErr := tmp.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err == nil && size > maxSize {
+			err = errFileTooLarge
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, errFileTooLarge
+			}
+			return nil, err
+		}
+
+		return &spooledUpload{
+			Path:     tmp.Name(),
+			Filename: filepath.Base(part.FileName()),
+			Size:     size,
+		}, nil
+	}
+}
+
+// storeUpload copies a spooled upload to S3 under a timestamped key and returns the key
+func storeUpload(ctx context.Context, s3Client *s3.SimpleS3Client, upload *spooledUpload) (string, error) {
+	// Generate unique filename with timestamp
+	timestamp := time.Now().Format("20060102-150405")
+	key := fmt.Sprintf("%s-%s", timestamp, upload.Filename)
+	log.Printf("File received successfully: %s (%d bytes)", key, upload.Size)
+
+	// Upload to S3 straight from the spooled file
+	file, err := os.Open(upload.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	if err := s3Client.Upload(ctx, uploadBucket, key, file, "text/csv"); err != nil {
+		log.Printf("⚠️ S3 upload skipped (LocalStack not available), processing directly")
+	} else {
+		log.Printf("File uploaded to S3 successfully: bucket=%s, key=%s", uploadBucket, key)
+	}
+	return key, nil
+}
+
+// createUploadJob registers an upload job, linking it to parentJobID for resubmissions
+func createUploadJob(ctx context.Context, upload *spooledUpload, key, parentJobID, tenantID string) (*jobs.Job, error) {
+	job := &jobs.Job{
+		Filename:    upload.Filename,
+		Bucket:      uploadBucket,
+		Key:         key,
+		ParentJobID: parentJobID,
+		TenantID:    tenantID,
+	}
+	if err := jobs.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// publishUpload sends the SQS message announcing an upload stored at bucket and key
+func publishUpload(ctx context.Context, sqsClient *sqs.Client, bucket, key, filename, tenantID string) {
+	message := map[string]string{
+		"bucket":            bucket,
+		"key":               key,
+		"original_filename": filename,
+		"tenant_id":         tenantID,
+	}
+	messageBytes, _ := json.Marshal(message)
+	sqsMessage := &commonsqs.Message{
+		Value: messageBytes,
+	}
+	if err := sqsClient.Publish(ctx, sqsMessage); err != nil {
+		log.Printf("Failed to send SQS message: %v", err)
+	} else {
+		log.Printf("SQS message sent successfully: %s", string(messageBytes))
+	}
+}
+
+// processUpload processes a spooled upload for tenantID in the background and removes it
+// when done. Rows are tracked against job when one was created.
+func processUpload(upload *spooledUpload, key string, job *jobs.Job, tenantID string) {
+	log.Printf("🔄 Processing CSV directly to ensure completion: %s", key)
+	go func() {
+		defer os.Remove(upload.Path)
+
+		file, err := os.Open(upload.Path)
+		if err != nil {
+			log.Printf("❌ CSV processing error: %v", err)
+			return
+		}
+		defer file.Close()
+
+		ctx := tenant.WithID(context.Background(), tenantID)
+		if job != nil {
+			err = processor.ProcessJobCSV(ctx, job, file)
+		} else {
+			err = processor.ProcessCSVStream(ctx, file, key)
+		}
+		if err != nil {
+			log.Printf("❌ CSV processing error: %v", err)
+		} else {
+			log.Printf("✅ CSV processing completed successfully - stats should update now")
+		}
+	}()
+}
+
+// processUploadMessage processes the CSV an upload message points at, applying the
+// degraded mode when S3 cannot serve it
+func processUploadMessage(ctx context.Context, sqsClient *sqs.Client, message map[string]string) {
+	bucket, key := message["bucket"], message["key"]
+	err := processor.ProcessCSVFromS3Real(ctx, bucket, key)
+	switch {
+	case errors.Is(err, processor.ErrStorageUnavailable):
+		degradeUpload(ctx, sqsClient, message, err)
+	case err != nil:
+		log.Printf("❌ [CSV Processor] S3 processing failed for %s: %v", key, err)
+	default:
+		log.Printf("✅ [CSV Processor] S3 processing completed successfully for %s", key)
+	}
+}
+
+// degradeUpload handles an upload S3 could not serve: dropped in fail mode, queued again
+// with a growing delay in queue mode, or replaced by mock orders in simulate mode
+func degradeUpload(ctx context.Context, sqsClient *sqs.Client, message map[string]string, cause error) {
+	bucket, key := message["bucket"], message["key"]
+	decision := degraded.Record(degraded.ComponentS3, cause)
+
+	switch decision.Mode {
+	case degraded.ModeFail:
+		log.Printf("❌ [CSV Processor] S3 unavailable, upload %s not processed: %v", key, cause)
+	case degraded.ModeSimulate:
+		if err := processor.ProcessSimulatedUpload(ctx, bucket, key, decision); err != nil {
+			log.Printf("❌ [CSV Processor] Simulated processing failed for %s: %v", key, err)
+		}
+	default:
+		attempt, _ := strconv.Atoi(message["attempt"])
+		if attempt+1 >= maxUploadAttempts {
+			log.Printf("❌ [CSV Processor] S3 unavailable after %d attempts, giving up on upload %s: %v", attempt+1, key, cause)
+			return
+		}
+
+		retry := make(map[string]string, len(message)+1)
+		for k, v := range message {
+			retry[k] = v
+		}
+		retry["attempt"] = strconv.Itoa(attempt + 1)
+		messageBytes, _ := json.Marshal(retry)
+		delay := uploadRetryDelay << attempt
+		if err := sqsClient.PublishDelayed(ctx, &commonsqs.Message{Value: messageBytes}, delay); err != nil {
+			log.Printf("❌ [CSV Processor] Failed to queue upload %s again: %v", key, err)
+			return
+		}
+		log.Printf("🔁 [CSV Processor] S3 unavailable, upload %s queued again in %s (attempt %d/%d)",
+			key, delay, attempt+2, maxUploadAttempts)
+	}
+}
+
+// writeUploadError reports a failure to receive an upload
+func writeUploadError(w http.ResponseWriter, err error, maxSize int64) {
+	if errors.Is(err, errFileTooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "File exceeds maximum size of %d bytes", maxSize)
+		return
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, "Failed to get file: %v", err)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"oms-service/internal/tenant"
+	"oms-service/internal/validation"
+)
+
+// handleValidationRules reads (GET) or replaces (PUT) the order validation rules
+// of the tenant named in the X-Tenant-ID header
+func handleValidationRules(w http.ResponseWriter, r *http.Request) {
+	tenantID := tenant.FromRequest(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(validation.RulesFor(r.Context(), tenantID))
+	case http.MethodPut:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodySize))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			fmt.Fprintf(w, "Failed to read rules: %v", err)
+			return
+		}
+
+		// Start from the defaults so omitted settings keep their default values
+		rules := validation.DefaultRules()
+		if err := json.Unmarshal(body, rules); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid rules JSON: %v", err)
+			return
+		}
+		rules.TenantID = tenantID
+		if rules.TotalTolerance < 0 || rules.MaxQuantityPerLine < 0 ||
+			(rules.MaxUnitPrice > 0 && rules.MaxUnitPrice < rules.MinUnitPrice) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "Invalid rules: limits must be non-negative and max_unit_price must not be below min_unit_price")
+			return
+		}
+
+		if err := validation.SaveRules(r.Context(), rules); err != nil {
+			if errors.Is(err, validation.ErrNotInitialized) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			fmt.Fprintf(w, "Failed to save rules: %v", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rules)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
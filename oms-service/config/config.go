@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config holds all configuration for the OMS service
@@ -25,6 +27,9 @@ type Config struct {
 	S3SecretKey string
 	S3UseSSL    bool
 
+	// UploadTempBucket receives files uploaded through pre-signed URLs
+	UploadTempBucket string
+
 	// SQS Configuration
 	SQSEndpoint  string
 	SQSRegion    string
@@ -45,12 +50,40 @@ type Config struct {
 	RedisDB       int
 
 	// IMS Service Configuration
-	IMSServiceURL string
+	IMSServiceURL       string
+	IMSTimeout          time.Duration
+	IMSMaxRetries       int
+	IMSBreakerThreshold int
+	IMSBreakerCooldown  time.Duration
+	// IMSJWTSecret signs the short-lived per-tenant JWTs OMS sends to IMS, it must match
+	// the IMS AUTH_JWT_HS256_SECRET. IMSAPIKey is sent instead when no secret is set.
+	IMSJWTSecret string
+	IMSAPIKey    string
+	// IMSGRPCAddress is the IMS gRPC server batch validation is sent to, over HTTP when empty
+	IMSGRPCAddress string
+
+	// Auth Configuration
+	AuthMode              string // enforce or off
+	AuthJWTHS256Secret    string
+	AuthJWTRS256PublicKey string // PEM, read from AUTH_JWT_RS256_PUBLIC_KEY_FILE when set
+	AuthJWTIssuer         string
+	AuthJWTAudience       string
+	// AuthKeyCacheTTL is how long an API key checked with IMS is trusted before it is
+	// checked again
+	AuthKeyCacheTTL time.Duration
+
+	// On-hold Backlog Configuration
+	OnHoldRetryInterval time.Duration
 
 	// File Processing Configuration
 	MaxFileSize       int64
 	AllowedExtensions []string
 	TempDirectory     string
+
+	// Upload Quota Configuration, defaults for tenants without quotas of their own.
+	// Zero means unlimited.
+	UploadQuotaFilesPerDay int
+	UploadQuotaRowsPerDay  int
 }
 
 // LoadConfig loads configuration from environment variables with defaults
@@ -59,7 +92,7 @@ func LoadConfig() *Config {
 		// Server defaults
 		ServerPort:      getEnv("SERVER_PORT", "8085"),
 		ServerHost:      getEnv("SERVER_HOST", "0.0.0.0"), // MongoDB defaults
-		MongoDBURI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+		MongoDBURI:      getEnv("MONGODB_URI", "mongodb://localhost:27017/?directConnection=true"),
 		MongoDBDatabase: getEnv("MONGODB_DATABASE", "oms_database"),
 		MongoDBTimeout:  getEnvAsInt("MONGODB_TIMEOUT", 30),
 
@@ -71,6 +104,9 @@ func LoadConfig() *Config {
 		S3SecretKey: getEnv("S3_SECRET_KEY", "test"),
 		S3UseSSL:    getEnvAsBool("S3_USE_SSL", false),
 
+		// Direct upload defaults
+		UploadTempBucket: getEnv("UPLOAD_TEMP_BUCKET", "oms-uploads-temp"),
+
 		// SQS defaults (LocalStack)
 		SQSEndpoint:  getEnv("SQS_ENDPOINT", "http://localhost:4566"),
 		SQSRegion:    getEnv("SQS_REGION", "us-east-1"),
@@ -91,12 +127,34 @@ func LoadConfig() *Config {
 		RedisDB:       getEnvAsInt("REDIS_DB", 0),
 
 		// IMS Service defaults
-		IMSServiceURL: getEnv("IMS_SERVICE_URL", "http://localhost:8081"),
+		IMSServiceURL:       getEnv("IMS_SERVICE_URL", "http://localhost:8081"),
+		IMSTimeout:          getEnvAsDuration("IMS_TIMEOUT", 10*time.Second),
+		IMSMaxRetries:       getEnvAsInt("IMS_MAX_RETRIES", 2),
+		IMSBreakerThreshold: getEnvAsInt("IMS_BREAKER_THRESHOLD", 5),
+		IMSBreakerCooldown:  getEnvAsDuration("IMS_BREAKER_COOLDOWN", 30*time.Second),
+		IMSJWTSecret:        getEnv("IMS_JWT_SECRET", ""),
+		IMSAPIKey:           getEnv("IMS_API_KEY", ""),
+		IMSGRPCAddress:      getEnv("IMS_GRPC_ADDRESS", ""),
+
+		// Auth defaults, requests must carry an API key or JWT
+		AuthMode:              getEnv("AUTH_MODE", "enforce"),
+		AuthJWTHS256Secret:    getEnv("AUTH_JWT_HS256_SECRET", ""),
+		AuthJWTRS256PublicKey: getEnvOrFile("AUTH_JWT_RS256_PUBLIC_KEY", ""),
+		AuthJWTIssuer:         getEnv("AUTH_JWT_ISSUER", ""),
+		AuthJWTAudience:       getEnv("AUTH_JWT_AUDIENCE", "oms-service"),
+		AuthKeyCacheTTL:       getEnvAsDuration("AUTH_KEY_CACHE_TTL", time.Minute),
+
+		// On-hold backlog defaults
+		OnHoldRetryInterval: getEnvAsDuration("ON_HOLD_RETRY_INTERVAL", 5*time.Minute),
 
 		// File processing defaults
 		MaxFileSize:       getEnvAsInt64("MAX_FILE_SIZE", 10*1024*1024), // 10MB
 		AllowedExtensions: getEnvAsSlice("ALLOWED_EXTENSIONS", []string{".csv", ".txt"}),
 		TempDirectory:     getEnv("TEMP_DIRECTORY", "/tmp/oms"),
+
+		// Upload quota defaults
+		UploadQuotaFilesPerDay: getEnvAsInt("UPLOAD_QUOTA_FILES_PER_DAY", 500),
+		UploadQuotaRowsPerDay:  getEnvAsInt("UPLOAD_QUOTA_ROWS_PER_DAY", 1000000),
 	}
 
 	// Log loaded configuration (without sensitive data)
@@ -107,6 +165,8 @@ func LoadConfig() *Config {
 	log.Printf("   SQS Queue: %s", config.SQSQueueName)
 	log.Printf("   Kafka Topic: %s (enabled: %v)", config.KafkaTopic, config.KafkaEnabled)
 	log.Printf("   IMS Service: %s", config.IMSServiceURL)
+	log.Printf("   Auth: %s", config.AuthMode)
+	log.Printf("   Upload quotas: %d files, %d rows per tenant per day", config.UploadQuotaFilesPerDay, config.UploadQuotaRowsPerDay)
 
 	return config
 }
@@ -119,6 +179,19 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvOrFile reads key, or the file named by key_FILE when that is set
+func getEnvOrFile(key, defaultValue string) string {
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("⚠️ Failed to read %s_FILE %s: %v", key, path, err)
+			return defaultValue
+		}
+		return string(data)
+	}
+	return getEnv(key, defaultValue)
+}
+
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -146,6 +219,15 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		// Simple comma-separated parsing
@@ -195,7 +277,12 @@ func (c *Config) GetKafkaConfig() map[string]interface{} {
 
 // ValidateConfig validates that all required configuration is present
 func (c *Config) ValidateConfig() error {
-	// Add validation logic here if needed
+	if c.AuthMode != "enforce" && c.AuthMode != "off" {
+		return fmt.Errorf("AUTH_MODE must be enforce or off, got %q", c.AuthMode)
+	}
+	if c.UploadQuotaFilesPerDay < 0 || c.UploadQuotaRowsPerDay < 0 {
+		return fmt.Errorf("UPLOAD_QUOTA_FILES_PER_DAY and UPLOAD_QUOTA_ROWS_PER_DAY must not be negative")
+	}
 	log.Println("✅ Configuration validation passed")
 	return nil
 }
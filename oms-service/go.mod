@@ -6,8 +6,14 @@ require (
 	github.com/IBM/sarama v1.45.1
 	github.com/aws/aws-sdk-go v1.44.140
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.66.2
+	github.com/google/uuid v1.6.0
 	github.com/omniful/go_commons v0.6.23
+	github.com/omniful/ims-service/pkg/imsclient v0.0.0
+	github.com/omniful/ims-service/pkg/imspb v0.0.0
+	github.com/omniful/ims-service/pkg/jwt v0.0.0
 	go.mongodb.org/mongo-driver v1.17.4
+	google.golang.org/grpc v1.65.0
+	google.golang.org/protobuf v1.34.2
 )
 
 require (
@@ -46,7 +52,6 @@ require (
 	github.com/go-playground/validator/v10 v10.26.0 // indirect
 	github.com/goccy/go-json v0.10.2 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/hashicorp/go-uuid v1.0.3 // indirect
@@ -89,9 +94,13 @@ require (
 	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 	google.golang.org/genproto v0.0.0-20230110181048-76db0878b65f // indirect
-	google.golang.org/grpc v1.65.0 // indirect
-	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace go_commons => ../go_commons
+
+replace github.com/omniful/ims-service/pkg/imsclient => ../ims-service/pkg/imsclient
+
+replace github.com/omniful/ims-service/pkg/imspb => ../ims-service/pkg/imspb
+
+replace github.com/omniful/ims-service/pkg/jwt => ../ims-service/pkg/jwt
@@ -0,0 +1,212 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// policyCacheTTL bounds how long a tenant's policy is served from memory
+const policyCacheTTL = time.Minute
+
+// Strategies for splitting an order line across hubs
+const (
+	// SplitNone reserves a line only at the hub it was ordered from
+	SplitNone = "none"
+	// SplitPreferredHub starts at the line's hub, then tries the hubs in HubPriority and
+	// finally the remaining hubs with the most stock first
+	SplitPreferredHub = "preferred_hub"
+	// SplitFewestShipments takes stock from the hubs holding the most of it first, so an
+	// order ships in as few parts as possible
+	SplitFewestShipments = "fewest_shipments"
+)
+
+// ErrNotInitialized is returned when MongoDB was not available at startup
+var ErrNotInitialized = errors.New("allocation policy store not initialized")
+
+// Policy controls how the order finalizer allocates inventory for a tenant
+type Policy struct {
+	TenantID string `bson:"tenant_id" json:"tenant_id"`
+	// AllowPartial reserves whatever stock is available and backorders the rest,
+	// instead of keeping the whole order on_hold until every line can be filled
+	AllowPartial bool `bson:"allow_partial" json:"allow_partial"`
+	// SplitStrategy decides whether and how a line is split across hubs
+	SplitStrategy string `bson:"split_strategy" json:"split_strategy"`
+	// HubPriority lists hubs to try, in order, after the line's hub with SplitPreferredHub
+	HubPriority []string  `bson:"hub_priority,omitempty" json:"hub_priority,omitempty"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// DefaultPolicy is all-or-nothing allocation from the line's hub
+func DefaultPolicy() *Policy {
+	return &Policy{TenantID: "default", SplitStrategy: SplitNone}
+}
+
+// Validate checks that the policy names a known split strategy
+func (p *Policy) Validate() error {
+	switch p.SplitStrategy {
+	case SplitNone, SplitPreferredHub, SplitFewestShipments:
+		return nil
+	case "":
+		p.SplitStrategy = SplitNone
+		return nil
+	default:
+		return fmt.Errorf("unknown split_strategy %q", p.SplitStrategy)
+	}
+}
+
+// Splits reports whether lines may be reserved at hubs other than their own
+func (p *Policy) Splits() bool {
+	return p.SplitStrategy == SplitPreferredHub || p.SplitStrategy == SplitFewestShipments
+}
+
+// HubOrder returns the hubs to reserve a line ordered from preferredHub at, in the order
+// they should be tried, given the stock available at each hub
+func (p *Policy) HubOrder(preferredHub string, available map[string]int) []string {
+	if !p.Splits() {
+		return []string{preferredHub}
+	}
+
+	// Hubs with the most stock first, by code when tied so the order is stable
+	byStock := make([]string, 0, len(available))
+	for hub := range available {
+		byStock = append(byStock, hub)
+	}
+	sort.Slice(byStock, func(i, j int) bool {
+		if available[byStock[i]] != available[byStock[j]] {
+			return available[byStock[i]] > available[byStock[j]]
+		}
+		return byStock[i] < byStock[j]
+	})
+	if p.SplitStrategy == SplitFewestShipments {
+		return byStock
+	}
+
+	seen := make(map[string]bool, len(available)+1)
+	hubs := make([]string, 0, len(available)+1)
+	for _, hub := range append(append([]string{preferredHub}, p.HubPriority...), byStock...) {
+		if hub != "" && !seen[hub] {
+			seen[hub] = true
+			hubs = append(hubs, hub)
+		}
+	}
+	return hubs
+}
+
+type cachedPolicy struct {
+	policy   *Policy
+	loadedAt time.Time
+}
+
+var (
+	policiesCollection *mongo.Collection
+	policyCache        = make(map[string]cachedPolicy)
+	policyMu           sync.RWMutex
+)
+
+// Initialize sets up the per-tenant policy collection
+func Initialize(client *mongo.Client) error {
+	if client == nil {
+		return ErrNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := client.Database("oms_database").Collection("allocation_policies")
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create allocation_policies index: %w", err)
+	}
+
+	policiesCollection = collection
+	log.Println("📊 Database: oms_database, Collection: allocation_policies")
+	return nil
+}
+
+// PolicyFor returns the policy of a tenant, falling back to the "default" tenant's
+// stored policy and then to DefaultPolicy
+func PolicyFor(ctx context.Context, tenantID string) *Policy {
+	if policy := cachedPolicyFor(tenantID); policy != nil {
+		return policy
+	}
+
+	policy, err := loadPolicy(ctx, tenantID)
+	if err != nil && tenantID != "default" {
+		policy, err = loadPolicy(ctx, "default")
+	}
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) && !errors.Is(err, ErrNotInitialized) {
+			log.Printf("⚠️ Failed to load allocation policy for tenant %s, using defaults: %v", tenantID, err)
+		}
+		policy = DefaultPolicy()
+	}
+
+	policyMu.Lock()
+	policyCache[tenantID] = cachedPolicy{policy: policy, loadedAt: time.Now()}
+	policyMu.Unlock()
+	return policy
+}
+
+// SavePolicy stores the policy of a tenant, replacing any previous configuration
+func SavePolicy(ctx context.Context, policy *Policy) error {
+	if policiesCollection == nil {
+		return ErrNotInitialized
+	}
+	if policy.TenantID == "" {
+		return fmt.Errorf("tenant_id is required")
+	}
+
+	policy.UpdatedAt = time.Now()
+	_, err := policiesCollection.ReplaceOne(ctx, bson.M{"tenant_id": policy.TenantID}, policy,
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save allocation policy: %w", err)
+	}
+
+	// Tenants falling back to the default policy must pick up the change as well
+	policyMu.Lock()
+	if policy.TenantID == "default" {
+		policyCache = make(map[string]cachedPolicy)
+	} else {
+		delete(policyCache, policy.TenantID)
+	}
+	policyMu.Unlock()
+
+	log.Printf("✅ Allocation policy saved for tenant %s", policy.TenantID)
+	return nil
+}
+
+func cachedPolicyFor(tenantID string) *Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+
+	cached, ok := policyCache[tenantID]
+	if !ok || time.Since(cached.loadedAt) > policyCacheTTL {
+		return nil
+	}
+	return cached.policy
+}
+
+func loadPolicy(ctx context.Context, tenantID string) (*Policy, error) {
+	if policiesCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var policy Policy
+	if err := policiesCollection.FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
@@ -0,0 +1,341 @@
+package amendment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"oms-service/internal/backlog"
+	"oms-service/internal/kafka"
+	"oms-service/internal/orders"
+	"oms-service/internal/outbox"
+	"oms-service/internal/tenant"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
+)
+
+var (
+	// ErrNotAmendable is returned for orders whose status or shipments no longer allow changes
+	ErrNotAmendable = errors.New("order cannot be amended")
+	// ErrInvalidAmendment is returned for amendments that do not describe valid lines
+	ErrInvalidAmendment = errors.New("invalid amendment")
+	// ErrReservationFailed is returned when IMS could not reserve an increased quantity
+	ErrReservationFailed = errors.New("inventory reservation failed")
+)
+
+// amendableStatuses are the order statuses in which lines may change. Once a shipment is
+// picked the order is with the warehouse and can no longer be amended.
+var amendableStatuses = map[string]bool{
+	"on_hold":                       true,
+	orders.StatusPartiallyAllocated: true,
+	"new_order":                     true,
+}
+
+// LinePatch sets the quantity of a line, identified by SKU and, when an order has the SKU
+// at several hubs, hub. A zero quantity removes the line; an unknown SKU adds one.
+type LinePatch struct {
+	SKU       string  `json:"sku"`
+	HubID     string  `json:"hub_id,omitempty"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price,omitempty"`
+}
+
+// Request amends the lines of an order
+type Request struct {
+	Lines  []LinePatch `json:"lines"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// Result is an amended order with the changes made to its lines
+type Result struct {
+	Order   *orders.Order      `json:"order"`
+	Changes []kafka.LineChange `json:"changes"`
+}
+
+// reservation is a quantity of a SKU to reserve (or release, when negative) at a hub
+type reservation struct {
+	hubID    string
+	sku      string
+	quantity int
+}
+
+// stock returns the IMS request moving quantity of the reservation's SKU at its hub
+func (r reservation) stock(quantity int) imsclient.StockRequest {
+	return imsclient.StockRequest{HubCode: r.hubID, SkuCode: r.sku, Quantity: quantity}
+}
+
+// Service amends order lines and keeps their IMS reservations in step
+type Service struct {
+	imsClient *imsclient.Client
+}
+
+// NewService creates an amendment service that adjusts reservations through imsClient
+func NewService(imsClient *imsclient.Client) *Service {
+	return &Service{imsClient: imsClient}
+}
+
+// Amend applies req to the lines of an order. Reserved quantities change by exactly the
+// amended delta: reductions release stock, increases of fully reserved lines reserve it
+// and lines still waiting for stock have their backorder adjusted instead. If IMS or the
+// order update fails, the reservations already changed are reverted.
+func (s *Service) Amend(ctx context.Context, orderID string, req Request) (*Result, error) {
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidAmendment)
+	}
+
+	order, err := orders.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = order.WithTenant(ctx)
+	if !amendableStatuses[order.Status] {
+		return nil, fmt.Errorf("%w: order %s is %s", ErrNotAmendable, orderID, order.Status)
+	}
+	for _, shipment := range order.Shipments {
+		if shipment.Status != orders.ShipmentAllocated && shipment.Status != orders.ShipmentCancelled {
+			return nil, fmt.Errorf("%w: shipment %s is %s", ErrNotAmendable, shipment.ShipmentID, shipment.Status)
+		}
+	}
+
+	lines := order.CurrentLines()
+	shipments := orders.CloneShipments(order.Shipments)
+	if len(shipments) == 0 && len(order.Lines) == 0 {
+		// Orders reserved before shipments were tracked hold their stock at the line's hub
+		for _, line := range lines {
+			if line.Reserved > 0 {
+				shipments = orders.AddReservation(shipments, orderID, line.HubID, line.SKU, line.Reserved)
+			}
+		}
+	}
+
+	var (
+		changes      []kafka.LineChange
+		reservations []reservation
+		patched      = make(map[int]bool)
+	)
+	for _, patch := range req.Lines {
+		if patch.SKU == "" || patch.Quantity < 0 {
+			return nil, fmt.Errorf("%w: every line needs a sku and a non-negative quantity", ErrInvalidAmendment)
+		}
+
+		i, err := findLine(lines, patch)
+		if err != nil {
+			return nil, err
+		}
+		if i >= 0 && patched[i] {
+			return nil, fmt.Errorf("%w: %s is amended twice", ErrInvalidAmendment, patch.SKU)
+		}
+
+		switch {
+		case i < 0:
+			// New line
+			if patch.Quantity == 0 || patch.HubID == "" {
+				return nil, fmt.Errorf("%w: new line %s needs a hub_id and a positive quantity", ErrInvalidAmendment, patch.SKU)
+			}
+			line := orders.OrderLine{SKU: patch.SKU, HubID: patch.HubID, Quantity: patch.Quantity, UnitPrice: patch.UnitPrice}
+			if order.Status == "new_order" {
+				// Lines added to a fully reserved order are reserved right away
+				line.Reserved = patch.Quantity
+				reservations = append(reservations, reservation{hubID: patch.HubID, sku: patch.SKU, quantity: patch.Quantity})
+			}
+			lines = append(lines, line)
+			patched[len(lines)-1] = true
+			changes = append(changes, kafka.LineChange{
+				Change: kafka.LineAdded, SKU: patch.SKU, HubID: patch.HubID, NewQuantity: patch.Quantity,
+			})
+			continue
+		case patch.Quantity == lines[i].Quantity:
+			patched[i] = true
+			continue
+		}
+
+		line := &lines[i]
+		patched[i] = true
+		change := kafka.LineChange{
+			Change: kafka.LineQuantityChanged, SKU: line.SKU, HubID: line.HubID,
+			OldQuantity: line.Quantity, NewQuantity: patch.Quantity,
+		}
+		if patch.Quantity == 0 {
+			change.Change = kafka.LineRemoved
+		}
+
+		switch {
+		case patch.Quantity < line.Reserved:
+			// Release the reserved units the line no longer needs
+			var taken map[string]int
+			shipments, taken = orders.TakeReservation(shipments, line.SKU, line.Reserved-patch.Quantity)
+			for hubID, quantity := range taken {
+				reservations = append(reservations, reservation{hubID: hubID, sku: line.SKU, quantity: -quantity})
+			}
+			line.Reserved = patch.Quantity
+		case patch.Quantity > line.Quantity && line.Outstanding() == 0:
+			// A fully reserved line stays fully reserved
+			delta := patch.Quantity - line.Quantity
+			reservations = append(reservations, reservation{hubID: line.HubID, sku: line.SKU, quantity: delta})
+			line.Reserved += delta
+		}
+		line.Quantity = patch.Quantity
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		return &Result{Order: order, Changes: []kafka.LineChange{}}, nil
+	}
+
+	// Removed lines are dropped, reserved increases are recorded on the line's hub shipment
+	var amended []orders.OrderLine
+	for _, line := range lines {
+		if line.Quantity > 0 {
+			line.Backordered = line.Outstanding()
+			amended = append(amended, line)
+		}
+	}
+	if len(amended) == 0 {
+		return nil, fmt.Errorf("%w: an order needs at least one line, cancel it instead", ErrInvalidAmendment)
+	}
+	for _, r := range reservations {
+		if r.quantity > 0 {
+			shipments = orders.AddReservation(shipments, orderID, r.hubID, r.sku, r.quantity)
+		}
+	}
+
+	applied, err := s.adjustReservations(ctx, reservations)
+	if err != nil {
+		s.revert(ctx, applied)
+		return nil, err
+	}
+
+	readAt := order.UpdatedAt
+	previousStatus := order.Status
+	order.Lines = amended
+	order.Shipments = shipments
+	order.Status = orders.AllocationStatus(amended)
+	order.Quantity, order.TotalAmount = totals(amended, order.UnitPrice)
+
+	event := &kafka.OrderUpdatedEvent{
+		OrderID:        orderID,
+		CustomerID:     order.CustomerID,
+		Status:         order.Status,
+		PreviousStatus: previousStatus,
+		Reason:         req.Reason,
+		Changes:        changes,
+		UpdatedAt:      time.Now(),
+	}
+	err = orders.AmendInTransaction(ctx, order, readAt, func(txCtx context.Context) error {
+		entry, err := newOrderUpdatedEntry(event, tenant.FromContext(ctx))
+		if err != nil {
+			return err
+		}
+		return outbox.Insert(txCtx, entry)
+	})
+	if err != nil {
+		s.revert(ctx, applied)
+		return nil, err
+	}
+
+	syncBacklog(ctx, order)
+	log.Printf("✏️ Order %s amended: %d line changes, %s → %s", orderID, len(changes), previousStatus, order.Status)
+	return &Result{Order: order, Changes: changes}, nil
+}
+
+// adjustReservations reserves or releases each quantity in IMS, stopping at the first
+// failure. It returns the adjustments that were made.
+func (s *Service) adjustReservations(ctx context.Context, reservations []reservation) ([]reservation, error) {
+	for i, r := range reservations {
+		var err error
+		if r.quantity > 0 {
+			_, err = s.imsClient.ReserveInventory(ctx, r.stock(r.quantity))
+		} else {
+			_, err = s.imsClient.ReleaseInventory(ctx, r.stock(-r.quantity))
+		}
+		if err != nil {
+			return reservations[:i], fmt.Errorf("%w: %s at %s: %w", ErrReservationFailed, r.sku, r.hubID, err)
+		}
+	}
+	return reservations, nil
+}
+
+// revert undoes reservation adjustments, latest first
+func (s *Service) revert(ctx context.Context, applied []reservation) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		r := applied[i]
+		var err error
+		if r.quantity > 0 {
+			_, err = s.imsClient.ReleaseInventory(ctx, r.stock(r.quantity))
+		} else {
+			_, err = s.imsClient.ReserveInventory(ctx, r.stock(-r.quantity))
+		}
+		if err != nil {
+			log.Printf("❌ Failed to revert reservation of %d %s at %s: %v", r.quantity, r.sku, r.hubID, err)
+		}
+	}
+}
+
+// findLine returns the index of the line a patch amends, or -1 for a new line
+func findLine(lines []orders.OrderLine, patch LinePatch) (int, error) {
+	found := -1
+	for i, line := range lines {
+		if line.SKU != patch.SKU || (patch.HubID != "" && line.HubID != patch.HubID) {
+			continue
+		}
+		if found >= 0 {
+			return -1, fmt.Errorf("%w: %s is ordered from several hubs, hub_id is required", ErrInvalidAmendment, patch.SKU)
+		}
+		found = i
+	}
+	return found, nil
+}
+
+// totals returns the total quantity and amount of lines, priced at unitPrice when a line
+// has no price of its own
+func totals(lines []orders.OrderLine, unitPrice float64) (int, float64) {
+	quantity, amount := 0, 0.0
+	for _, line := range lines {
+		price := line.UnitPrice
+		if price == 0 {
+			price = unitPrice
+		}
+		quantity += line.Quantity
+		amount += float64(line.Quantity) * price
+	}
+	return quantity, amount
+}
+
+// syncBacklog keeps the on-hold backlog entry of an amended order in step with its lines
+func syncBacklog(ctx context.Context, order *orders.Order) {
+	var err error
+	if order.Status == "new_order" {
+		err = backlog.Release(ctx, order.OrderID)
+	} else {
+		items := make([]backlog.Item, len(order.Lines))
+		for i, line := range order.Lines {
+			items[i] = backlog.Item{
+				SKU:       line.SKU,
+				HubID:     line.HubID,
+				Quantity:  line.Quantity,
+				UnitPrice: line.UnitPrice,
+			}
+		}
+		err = backlog.UpdateItems(ctx, order.OrderID, items)
+	}
+	if err != nil && !errors.Is(err, backlog.ErrNotInitialized) {
+		log.Printf("⚠️ Failed to update on-hold backlog for amended order %s: %v", order.OrderID, err)
+	}
+}
+
+// newOrderUpdatedEntry builds the outbox entry for an order.updated event of tenantID
+func newOrderUpdatedEntry(event *kafka.OrderUpdatedEvent, tenantID string) (*outbox.Entry, error) {
+	msg, err := kafka.NewEventMessage(kafka.EventOrderUpdated, event.OrderID, event.OrderID, tenantID, event)
+	if err != nil {
+		return nil, err
+	}
+	return &outbox.Entry{
+		AggregateID: event.OrderID,
+		EventType:   kafka.EventOrderUpdated,
+		Topic:       msg.Topic,
+		Key:         msg.Key,
+		Payload:     string(msg.Value),
+		Headers:     msg.Headers,
+	}, nil
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"oms-service/internal/tenant"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
+	"github.com/omniful/ims-service/pkg/jwt"
+)
+
+// ErrInvalidCredential is returned for a credential that is malformed, unknown, expired
+// or revoked. The middleware answers it with 401. It is the error pkg/jwt rejects tokens with.
+var ErrInvalidCredential = jwt.ErrInvalidCredential
+
+// maxCachedKeys bounds the API key cache, expired entries are dropped when it is full
+const maxCachedKeys = 10000
+
+// Authentication methods
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the caller a credential identifies. Tenant is the tenant code, TenantID its
+// UUID, however the credential named the tenant.
+type Principal struct {
+	Subject  string   `json:"subject"`
+	Tenant   string   `json:"tenant"`
+	TenantID string   `json:"tenant_id,omitempty"`
+	Scopes   []string `json:"scopes"`
+	Method   string   `json:"method"`
+}
+
+// Allows reports whether the principal was granted scope
+func (p *Principal) Allows(scope string) bool {
+	return Allows(p.Scopes, scope)
+}
+
+// BoundTo reports whether ref, a tenant code or UUID, names the principal's tenant
+func (p *Principal) BoundTo(ref string) bool {
+	return ref == p.Tenant || (p.TenantID != "" && ref == p.TenantID)
+}
+
+type cachedPrincipal struct {
+	principal *Principal
+	expires   time.Time
+}
+
+// Authenticator identifies the caller of a request. JWTs are verified locally when a
+// key is configured, and their tenant resolved with IMS; API keys, and JWTs otherwise,
+// are checked with IMS, which stores the keys, and trusted for keyTTL.
+type Authenticator struct {
+	verifier *jwt.Verifier
+	ims      *imsclient.Client
+	tokens   *ServiceTokens
+	keyTTL   time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedPrincipal
+}
+
+// NewAuthenticator creates an Authenticator. verifier may be nil; tokens are the
+// credentials OMS sends to IMS, used to resolve the tenant of verified JWTs.
+func NewAuthenticator(verifier *jwt.Verifier, ims *imsclient.Client, tokens *ServiceTokens, keyTTL time.Duration) *Authenticator {
+	return &Authenticator{
+		verifier: verifier,
+		ims:      ims,
+		tokens:   tokens,
+		keyTTL:   keyTTL,
+		cache:    map[string]cachedPrincipal{},
+	}
+}
+
+// Authenticate returns the principal of credential. Rejected credentials fail with an
+// error wrapping ErrInvalidCredential, any other error means IMS could not check it.
+func (a *Authenticator) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	credential = strings.TrimSpace(credential)
+	if credential == "" {
+		return nil, fmt.Errorf("%w: no credential", ErrInvalidCredential)
+	}
+	if jwt.IsJWT(credential) && a.verifier != nil {
+		claims, err := a.verifier.Verify(credential)
+		if err != nil {
+			return nil, err
+		}
+		owner, err := a.resolveTenant(ctx, claims.Tenant)
+		if err != nil {
+			return nil, err
+		}
+		return &Principal{
+			Subject:  claims.Subject,
+			Tenant:   owner.Tenant,
+			TenantID: owner.TenantID,
+			Scopes:   strings.Fields(claims.Scope),
+			Method:   MethodJWT,
+		}, nil
+	}
+	return a.checkWithIMS(ctx, credential)
+}
+
+// resolveTenant returns the principal OMS acts as in IMS for the tenant ref, a code or
+// UUID, whose Tenant and TenantID are the code and UUID of that tenant. A tenant is then
+// stored under its code whether a JWT or an API key named it. Tenants IMS does not know,
+// and with a fixed API key any tenant but the key's, fail with ErrInvalidCredential.
+func (a *Authenticator) resolveTenant(ctx context.Context, ref string) (*Principal, error) {
+	credential := a.tokens.Credential(tenant.WithID(ctx, ref))
+	if credential == "" {
+		return nil, fmt.Errorf("no IMS credential configured to resolve tenant %s", ref)
+	}
+	owner, err := a.checkWithIMS(ctx, credential)
+	if err != nil {
+		return nil, err
+	}
+	if ref != owner.Tenant && ref != owner.TenantID {
+		return nil, fmt.Errorf("%w: token is for tenant %s, OMS serves %s", ErrInvalidCredential, ref, owner.Tenant)
+	}
+	return owner, nil
+}
+
+// checkWithIMS asks IMS who credential belongs to, caching the answer by its hash
+func (a *Authenticator) checkWithIMS(ctx context.Context, credential string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(credential))
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	a.mu.Lock()
+	if cached, ok := a.cache[hash]; ok && now.Before(cached.expires) {
+		a.mu.Unlock()
+		return cached.principal, nil
+	}
+	a.mu.Unlock()
+
+	resp, err := a.ims.WhoAmI(ctx, credential)
+	if err != nil {
+		if imsclient.IsUnauthorized(err) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+		}
+		return nil, fmt.Errorf("failed to check credential with IMS: %w", err)
+	}
+	principal := &Principal{
+		Subject:  resp.Subject,
+		Tenant:   resp.TenantCode,
+		TenantID: resp.TenantID,
+		Scopes:   resp.Scopes,
+		Method:   resp.Method,
+	}
+	if principal.Tenant == "" {
+		principal.Tenant = resp.TenantID
+	}
+
+	a.mu.Lock()
+	if len(a.cache) >= maxCachedKeys {
+		for key, cached := range a.cache {
+			if now.After(cached.expires) {
+				delete(a.cache, key)
+			}
+		}
+	}
+	if len(a.cache) < maxCachedKeys {
+		a.cache[hash] = cachedPrincipal{principal: principal, expires: now.Add(a.keyTTL)}
+	}
+	a.mu.Unlock()
+
+	return principal, nil
+}
+
+// serviceTokenTTL is the lifetime of the JWTs OMS mints for IMS
+const serviceTokenTTL = 5 * time.Minute
+
+// serviceScopes are granted to the JWTs OMS mints: it validates orders against the
+// catalog and reserves, releases and fulfils stock
+var serviceScopes = []string{ScopeCatalogRead, ScopeInventoryWrite}
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// ServiceTokens provides the credential OMS sends to IMS. With a secret shared with IMS
+// it mints a short-lived JWT for the tenant of each request, so one OMS serves every
+// tenant; otherwise it sends a fixed API key, bound to a single tenant.
+type ServiceTokens struct {
+	secret []byte
+	apiKey string
+
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+// NewServiceTokens creates the credential source for IMS requests
+func NewServiceTokens(secret, apiKey string) *ServiceTokens {
+	s := &ServiceTokens{apiKey: apiKey, tokens: map[string]cachedToken{}}
+	if secret != "" {
+		s.secret = []byte(secret)
+	}
+	return s
+}
+
+// Credential returns the credential for the tenant of ctx, or "" when none is configured
+func (s *ServiceTokens) Credential(ctx context.Context) string {
+	if s.secret == nil {
+		return s.apiKey
+	}
+	tenantID := tenant.FromContext(ctx)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Reuse a token until it is close to expiring
+	if cached, ok := s.tokens[tenantID]; ok && now.Add(time.Minute).Before(cached.expires) {
+		return cached.token
+	}
+	expires := now.Add(serviceTokenTTL)
+	token, err := jwt.SignHS256(jwt.Claims{
+		Subject:   "oms-service",
+		Tenant:    tenantID,
+		Scope:     strings.Join(serviceScopes, " "),
+		Issuer:    "oms-service",
+		Audience:  jwt.Audience{"ims-service"},
+		ExpiresAt: expires.Unix(),
+		IssuedAt:  now.Unix(),
+	}, s.secret)
+	if err != nil {
+		return ""
+	}
+	s.tokens[tenantID] = cachedToken{token: token, expires: expires}
+	return token
+}
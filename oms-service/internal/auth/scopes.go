@@ -0,0 +1,36 @@
+// Package auth authenticates OMS requests with the credentials IMS issues, tenant-bound
+// API keys and HS256 or RS256 JWTs, and mints the JWTs OMS sends to IMS.
+package auth
+
+import "strings"
+
+// Scopes are named resource:level, matching IMS. A level grants every level below it,
+// so orders:admin allows orders:write and orders:read.
+const (
+	ScopeInventoryRead  = "inventory:read"
+	ScopeInventoryWrite = "inventory:write"
+	ScopeInventoryAdmin = "inventory:admin"
+	ScopeCatalogRead    = "catalog:read"
+	ScopeCatalogWrite   = "catalog:write"
+	ScopeCatalogAdmin   = "catalog:admin"
+	ScopeOrdersRead     = "orders:read"
+	ScopeOrdersWrite    = "orders:write"
+	ScopeOrdersAdmin    = "orders:admin"
+)
+
+var levels = map[string]int{"read": 1, "write": 2, "admin": 3}
+
+// Allows reports whether the granted scopes cover required
+func Allows(granted []string, required string) bool {
+	resource, level, ok := strings.Cut(required, ":")
+	if !ok || levels[level] == 0 {
+		return false
+	}
+	for _, scope := range granted {
+		r, l, ok := strings.Cut(scope, ":")
+		if ok && r == resource && levels[l] >= levels[level] {
+			return true
+		}
+	}
+	return false
+}
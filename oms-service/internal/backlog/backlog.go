@@ -0,0 +1,305 @@
+package backlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Backlog entry statuses
+const (
+	StatusWaiting  = "waiting"
+	StatusReleased = "released"
+)
+
+// Orderings in which waiting orders are retried
+const (
+	OrderFIFO     = "fifo"
+	OrderPriority = "priority"
+)
+
+// ErrNotInitialized is returned when MongoDB was not available at startup
+var ErrNotInitialized = errors.New("on-hold backlog not initialized")
+
+// ErrNotFound is returned when an order is not in the backlog
+var ErrNotFound = errors.New("order not in on-hold backlog")
+
+// Item is an order line waiting for stock
+type Item struct {
+	SKU         string  `bson:"sku" json:"sku"`
+	ProductName string  `bson:"product_name" json:"product_name"`
+	HubID       string  `bson:"hub_id" json:"hub_id"`
+	Quantity    int     `bson:"quantity" json:"quantity"`
+	UnitPrice   float64 `bson:"unit_price" json:"unit_price"`
+}
+
+// Entry is an on_hold order waiting for stock, indexed by the hubs and SKUs of its items
+type Entry struct {
+	OrderID       string     `bson:"order_id" json:"order_id"`
+	TenantID      string     `bson:"tenant_id" json:"tenant_id"`
+	CustomerID    string     `bson:"customer_id" json:"customer_id"`
+	TotalAmount   float64    `bson:"total_amount" json:"total_amount"`
+	OrderedAt     time.Time  `bson:"ordered_at" json:"ordered_at"`
+	Items         []Item     `bson:"items" json:"items"`
+	Priority      int        `bson:"priority" json:"priority"`
+	Status        string     `bson:"status" json:"status"`
+	Attempts      int        `bson:"attempts" json:"attempts"`
+	Overdue       bool       `bson:"overdue" json:"overdue"`
+	OverdueAt     *time.Time `bson:"overdue_at,omitempty" json:"overdue_at,omitempty"`
+	HeldAt        time.Time  `bson:"held_at" json:"held_at"`
+	LastCheckedAt *time.Time `bson:"last_checked_at,omitempty" json:"last_checked_at,omitempty"`
+	ReleasedAt    *time.Time `bson:"released_at,omitempty" json:"released_at,omitempty"`
+}
+
+// Filter narrows the entries returned by List
+type Filter struct {
+	HubID       string
+	SKU         string
+	TenantID    string
+	OverdueOnly bool
+	Limit       int64
+}
+
+var backlogCollection *mongo.Collection
+
+// Initialize sets up the backlog collection and its indexes
+func Initialize(client *mongo.Client) error {
+	if client == nil {
+		return ErrNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := client.Database("oms_database").Collection("on_hold_backlog")
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "order_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{
+			{Key: "status", Value: 1},
+			{Key: "items.hub_id", Value: 1},
+			{Key: "items.sku", Value: 1},
+			{Key: "priority", Value: -1},
+			{Key: "held_at", Value: 1},
+		}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "held_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create on_hold_backlog indexes: %w", err)
+	}
+
+	backlogCollection = collection
+	log.Println("📊 Database: oms_database, Collection: on_hold_backlog")
+	return nil
+}
+
+// RetryOrder reads the retry ordering from ON_HOLD_RETRY_ORDER, fifo (default) or priority
+func RetryOrder() string {
+	if os.Getenv("ON_HOLD_RETRY_ORDER") == OrderPriority {
+		return OrderPriority
+	}
+	return OrderFIFO
+}
+
+// MaxWait reads how long an order may wait before it is flagged from ON_HOLD_MAX_WAIT, default 24h
+func MaxWait() time.Duration {
+	if wait, err := time.ParseDuration(os.Getenv("ON_HOLD_MAX_WAIT")); err == nil && wait > 0 {
+		return wait
+	}
+	return 24 * time.Hour
+}
+
+// Hold adds an order to the backlog, or records another failed attempt if it is already
+// waiting. The original hold time is kept so the order does not lose its place.
+func Hold(ctx context.Context, entry *Entry) error {
+	if backlogCollection == nil {
+		return ErrNotInitialized
+	}
+
+	now := time.Now()
+	_, err := backlogCollection.UpdateOne(ctx, bson.M{"order_id": entry.OrderID}, bson.M{
+		"$set": bson.M{
+			"tenant_id":       entry.TenantID,
+			"customer_id":     entry.CustomerID,
+			"total_amount":    entry.TotalAmount,
+			"ordered_at":      entry.OrderedAt,
+			"items":           entry.Items,
+			"status":          StatusWaiting,
+			"last_checked_at": now,
+		},
+		"$setOnInsert": bson.M{"held_at": now, "priority": entry.Priority, "overdue": false},
+		"$inc":         bson.M{"attempts": 1},
+		"$unset":       bson.M{"released_at": ""},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to add order %s to on-hold backlog: %w", entry.OrderID, err)
+	}
+	return nil
+}
+
+// Release marks an order as no longer waiting, once it was finalized or cancelled
+func Release(ctx context.Context, orderID string) error {
+	if backlogCollection == nil {
+		return ErrNotInitialized
+	}
+
+	_, err := backlogCollection.UpdateOne(ctx,
+		bson.M{"order_id": orderID, "status": StatusWaiting},
+		bson.M{"$set": bson.M{"status": StatusReleased, "released_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to release order %s from on-hold backlog: %w", orderID, err)
+	}
+	return nil
+}
+
+// UpdateItems replaces the items of a waiting order after its lines were amended
+func UpdateItems(ctx context.Context, orderID string, items []Item) error {
+	if backlogCollection == nil {
+		return ErrNotInitialized
+	}
+
+	_, err := backlogCollection.UpdateOne(ctx,
+		bson.M{"order_id": orderID, "status": StatusWaiting},
+		bson.M{"$set": bson.M{"items": items}})
+	if err != nil {
+		return fmt.Errorf("failed to update items of order %s in on-hold backlog: %w", orderID, err)
+	}
+	return nil
+}
+
+// SetPriority changes the priority of a waiting order, higher priorities are retried first
+func SetPriority(ctx context.Context, orderID string, priority int) (*Entry, error) {
+	if backlogCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var entry Entry
+	err := backlogCollection.FindOneAndUpdate(ctx,
+		bson.M{"order_id": orderID, "status": StatusWaiting},
+		bson.M{"$set": bson.M{"priority": priority}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set priority of order %s: %w", orderID, err)
+	}
+	return &entry, nil
+}
+
+// Waiting returns up to limit waiting orders in retry order, only those with an item at
+// hubID for sku when both are given, or with an item for sku at any hub when only sku is
+func Waiting(ctx context.Context, tenantID, hubID, sku string, limit int64) ([]Entry, error) {
+	if backlogCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	query := bson.M{"status": StatusWaiting}
+	if tenantID != "" {
+		query["tenant_id"] = tenantID
+	}
+	switch {
+	case hubID != "" && sku != "":
+		query["items"] = bson.M{"$elemMatch": bson.M{"hub_id": hubID, "sku": sku}}
+	case sku != "":
+		query["items.sku"] = sku
+	}
+
+	cursor, err := backlogCollection.Find(ctx, query, options.Find().SetSort(retrySort()).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query on-hold backlog: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []Entry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode backlog entries: %w", err)
+	}
+	return entries, nil
+}
+
+// List returns waiting orders matching filter in retry order
+func List(ctx context.Context, filter Filter) ([]Entry, error) {
+	if backlogCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	query := bson.M{"status": StatusWaiting}
+	if filter.TenantID != "" {
+		query["tenant_id"] = filter.TenantID
+	}
+	if filter.HubID != "" {
+		query["items.hub_id"] = filter.HubID
+	}
+	if filter.SKU != "" {
+		query["items.sku"] = filter.SKU
+	}
+	if filter.OverdueOnly {
+		query["overdue"] = true
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+
+	cursor, err := backlogCollection.Find(ctx, query, options.Find().SetSort(retrySort()).SetLimit(filter.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list on-hold backlog: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []Entry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode backlog entries: %w", err)
+	}
+	return entries, nil
+}
+
+// FlagOverdue flags waiting orders held for longer than maxWait and returns their entries
+func FlagOverdue(ctx context.Context, maxWait time.Duration) ([]Entry, error) {
+	if backlogCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	query := bson.M{
+		"status":  StatusWaiting,
+		"overdue": false,
+		"held_at": bson.M{"$lt": time.Now().Add(-maxWait)},
+	}
+	cursor, err := backlogCollection.Find(ctx, query, options.Find().SetProjection(bson.M{"order_id": 1, "tenant_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find overdue orders: %w", err)
+	}
+	var overdue []Entry
+	if err := cursor.All(ctx, &overdue); err != nil {
+		return nil, fmt.Errorf("failed to decode overdue orders: %w", err)
+	}
+	if len(overdue) == 0 {
+		return nil, nil
+	}
+
+	orderIDs := make([]string, len(overdue))
+	for i, entry := range overdue {
+		orderIDs[i] = entry.OrderID
+	}
+	_, err = backlogCollection.UpdateMany(ctx,
+		bson.M{"order_id": bson.M{"$in": orderIDs}, "status": StatusWaiting},
+		bson.M{"$set": bson.M{"overdue": true, "overdue_at": time.Now()}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to flag overdue orders: %w", err)
+	}
+	return overdue, nil
+}
+
+// retrySort orders waiting entries oldest first, by priority first if configured
+func retrySort() bson.D {
+	if RetryOrder() == OrderPriority {
+		return bson.D{{Key: "priority", Value: -1}, {Key: "held_at", Value: 1}}
+	}
+	return bson.D{{Key: "held_at", Value: 1}}
+}
@@ -0,0 +1,154 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"oms-service/internal/kafka"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Dead letter statuses
+const (
+	StatusDead     = "dead"
+	StatusReplayed = "replayed"
+)
+
+// ErrNotFound is returned when no dead letter exists for the given ID
+var ErrNotFound = errors.New("dead letter not found")
+
+// Record is a dead-lettered Kafka message as stored in MongoDB
+type Record struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	kafka.DeadLetter `bson:",inline"`
+	Status           string     `bson:"status" json:"status"`
+	ReplayCount      int        `bson:"replay_count" json:"replay_count"`
+	ReplayedAt       *time.Time `bson:"replayed_at,omitempty" json:"replayed_at,omitempty"`
+}
+
+// Filter narrows the dead letters returned by List
+type Filter struct {
+	Topic  string
+	Status string
+	Limit  int64
+}
+
+// Store keeps dead-lettered messages in MongoDB
+type Store struct {
+	collection *mongo.Collection
+}
+
+// Ensure Store can be used by the Kafka consumer
+var _ kafka.DeadLetterStore = (*Store)(nil)
+
+// NewStore creates a dead letter store backed by the dead_letters collection
+func NewStore(client *mongo.Client) (*Store, error) {
+	if client == nil {
+		return nil, fmt.Errorf("mongodb client not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := client.Database("oms_database").Collection("dead_letters")
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "topic", Value: 1}, {Key: "status", Value: 1}, {Key: "failed_at", Value: -1}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead_letters index: %w", err)
+	}
+
+	log.Println("📊 Database: oms_database, Collection: dead_letters")
+	return &Store{collection: collection}, nil
+}
+
+// SaveDeadLetter records a message that exhausted its retries
+func (s *Store) SaveDeadLetter(ctx context.Context, letter *kafka.DeadLetter) error {
+	record := &Record{DeadLetter: *letter, Status: StatusDead}
+	if _, err := s.collection.InsertOne(ctx, record); err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	return nil
+}
+
+// List returns dead letters matching filter, newest first
+func (s *Store) List(ctx context.Context, filter Filter) ([]Record, error) {
+	query := bson.M{}
+	if filter.Topic != "" {
+		query["topic"] = filter.Topic
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+
+	cursor, err := s.collection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "failed_at", Value: -1}}).
+		SetLimit(filter.Limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	records := []Record{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letters: %w", err)
+	}
+	return records, nil
+}
+
+// Get returns a dead letter by its ID
+func (s *Store) Get(ctx context.Context, id string) (*Record, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	var record Record
+	err = s.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	return &record, nil
+}
+
+// Replay publishes a dead letter back to its original topic as a fresh message
+func (s *Store) Replay(ctx context.Context, id string, publisher kafka.MessagePublisher) (*Record, error) {
+	record, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := kafka.RepublishHeaders(record.Headers)
+	headers["replayed_from"] = record.ID.Hex()
+	if err := publisher.Publish(ctx, record.Topic, record.Key, []byte(record.Value), headers); err != nil {
+		return nil, fmt.Errorf("failed to replay dead letter: %w", err)
+	}
+
+	now := time.Now()
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": record.ID}, bson.M{
+		"$set": bson.M{"status": StatusReplayed, "replayed_at": now},
+		"$inc": bson.M{"replay_count": 1},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dead letter replayed but not updated: %w", err)
+	}
+
+	record.Status = StatusReplayed
+	record.ReplayedAt = &now
+	record.ReplayCount++
+	log.Printf("♻️ Replayed dead letter %s to %s", record.ID.Hex(), record.Topic)
+	return record, nil
+}
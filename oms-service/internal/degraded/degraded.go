@@ -0,0 +1,145 @@
+package degraded
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Degradation modes, applied when a dependency cannot be reached
+const (
+	// ModeFail rejects the work, as if the dependency had refused it
+	ModeFail = "fail"
+	// ModeQueue puts the work aside to be retried once the dependency is back
+	ModeQueue = "queue"
+	// ModeSimulate carries on with made-up answers. Development only, it needs
+	// DEGRADED_ALLOW_SIMULATION=true.
+	ModeSimulate = "simulate"
+)
+
+// Dependencies whose outages are handled by the policy
+const (
+	ComponentIMSValidation = "ims_validation"
+	ComponentIMSInventory  = "ims_inventory"
+	ComponentS3            = "s3"
+)
+
+// Decision records that work was handled in degraded mode. It is kept on the orders it
+// affected.
+type Decision struct {
+	Component string    `bson:"component" json:"component"`
+	Mode      string    `bson:"mode" json:"mode"`
+	Reason    string    `bson:"reason" json:"reason"`
+	At        time.Time `bson:"at" json:"at"`
+}
+
+var (
+	// decisionCounts counts decisions by component and mode, published at /debug/vars
+	decisionCounts = expvar.NewMap("oms_degraded_decisions")
+	// lastDecision is the most recent decision, published at /debug/vars
+	lastDecision = expvar.NewString("oms_degraded_last_decision")
+
+	warnOnce   sync.Once
+	alertMu    sync.Mutex
+	lastAlerts = map[string]time.Time{}
+	suppressed = map[string]int{}
+	httpClient = &http.Client{Timeout: 5 * time.Second}
+)
+
+// Mode reads the degradation mode from DEGRADED_MODE: fail, queue (default) or simulate.
+// Simulate falls back to queue unless DEGRADED_ALLOW_SIMULATION=true.
+func Mode() string {
+	switch mode := strings.ToLower(os.Getenv("DEGRADED_MODE")); mode {
+	case ModeFail:
+		return ModeFail
+	case ModeSimulate:
+		if os.Getenv("DEGRADED_ALLOW_SIMULATION") == "true" {
+			return ModeSimulate
+		}
+		warnOnce.Do(func() {
+			log.Printf("⚠️ DEGRADED_MODE=simulate needs DEGRADED_ALLOW_SIMULATION=true, using %s", ModeQueue)
+		})
+		return ModeQueue
+	default:
+		return ModeQueue
+	}
+}
+
+// Record decides how to handle component being unavailable, counts the decision and
+// raises an alert. Callers apply the returned mode and keep the decision on the orders
+// it affects.
+func Record(component string, cause error) Decision {
+	decision := Decision{
+		Component: component,
+		Mode:      Mode(),
+		Reason:    cause.Error(),
+		At:        time.Now().UTC(),
+	}
+
+	decisionCounts.Add(component+"."+decision.Mode, 1)
+	if data, err := json.Marshal(decision); err == nil {
+		lastDecision.Set(string(data))
+	}
+	alert(decision)
+	return decision
+}
+
+// alert logs a decision and posts it to DEGRADED_ALERT_WEBHOOK, at most once per
+// component and mode every DEGRADED_ALERT_INTERVAL (default 5m)
+func alert(decision Decision) {
+	key := decision.Component + "." + decision.Mode
+	interval := alertInterval()
+
+	alertMu.Lock()
+	if last, ok := lastAlerts[key]; ok && time.Since(last) < interval {
+		suppressed[key]++
+		alertMu.Unlock()
+		return
+	}
+	lastAlerts[key] = time.Now()
+	repeats := suppressed[key]
+	suppressed[key] = 0
+	alertMu.Unlock()
+
+	log.Printf("🚨 [DEGRADED] %s unavailable, mode %s (%d more since last alert): %s",
+		decision.Component, decision.Mode, repeats, decision.Reason)
+
+	webhook := os.Getenv("DEGRADED_ALERT_WEBHOOK")
+	if webhook == "" {
+		return
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"text":       fmt.Sprintf("OMS is running degraded: %s unavailable, mode %s", decision.Component, decision.Mode),
+		"decision":   decision,
+		"suppressed": repeats,
+	})
+	if err != nil {
+		return
+	}
+	go func() {
+		resp, err := httpClient.Post(webhook, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("⚠️ [DEGRADED] Failed to send alert: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("⚠️ [DEGRADED] Alert webhook returned status %d", resp.StatusCode)
+		}
+	}()
+}
+
+// alertInterval reads how often alerts repeat from DEGRADED_ALERT_INTERVAL, default 5m
+func alertInterval() time.Duration {
+	if interval, err := time.ParseDuration(os.Getenv("DEGRADED_ALERT_INTERVAL")); err == nil && interval >= 0 {
+		return interval
+	}
+	return 5 * time.Minute
+}
@@ -0,0 +1,312 @@
+package fulfillment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"oms-service/internal/orders"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
+)
+
+// Fulfilment actions and the shipment status each one leads to
+const (
+	ActionPick = "pick"
+	ActionPack = "pack"
+	ActionShip = "ship"
+)
+
+var actionStatus = map[string]string{
+	ActionPick: orders.ShipmentPicked,
+	ActionPack: orders.ShipmentPacked,
+	ActionShip: orders.ShipmentShipped,
+}
+
+// statusRank orders shipment statuses so repeated or out-of-date requests are no-ops
+var statusRank = map[string]int{
+	orders.ShipmentAllocated: 0,
+	orders.ShipmentPicked:    1,
+	orders.ShipmentPacked:    2,
+	orders.ShipmentShipped:   3,
+}
+
+// Order statuses derived from the shipments of an order
+const (
+	StatusPicked           = "picked"
+	StatusPacked           = "packed"
+	StatusPartiallyShipped = "partially_shipped"
+	StatusShipped          = "shipped"
+)
+
+var (
+	// ErrUnknownAction is returned for actions other than pick, pack and ship
+	ErrUnknownAction = errors.New("unknown fulfilment action")
+	// ErrTrackingRequired is returned when a shipment is shipped without a tracking number
+	ErrTrackingRequired = errors.New("tracking_number is required to ship")
+	// ErrInvalidTransition is returned when a shipment cannot move to the requested status
+	ErrInvalidTransition = errors.New("invalid shipment transition")
+	// ErrNoShipments is returned for orders with nothing allocated to fulfil
+	ErrNoShipments = errors.New("order has no allocated shipments")
+	// ErrOrderHeld is returned for orders with an active hold
+	ErrOrderHeld = errors.New("order has active holds")
+)
+
+// Request asks for the shipments of an order, or one of them, to move on
+type Request struct {
+	Action         string `json:"-"`
+	ShipmentID     string `json:"-"`
+	Carrier        string `json:"carrier,omitempty"`
+	TrackingNumber string `json:"tracking_number,omitempty"`
+}
+
+// Validate checks the action and the fields it needs
+func (r Request) Validate() error {
+	if _, ok := actionStatus[r.Action]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAction, r.Action)
+	}
+	if r.Action == ActionShip && r.TrackingNumber == "" {
+		return ErrTrackingRequired
+	}
+	return nil
+}
+
+// ShipmentStock fulfils the lines of a shipment in one call, such as the IMS gRPC client.
+// It returns the outcome of each line, a line failing does not stop the others.
+type ShipmentStock interface {
+	Fulfill(ctx context.Context, orderID string, lines []imsclient.StockRequest) ([]error, error)
+}
+
+// Service moves shipments through pick, pack and ship and deducts shipped stock in IMS
+type Service struct {
+	imsClient *imsclient.Client
+	stock     ShipmentStock
+	// mutex serialises transitions so a shipment is never fulfilled in IMS twice
+	mutex sync.Mutex
+}
+
+// NewService creates a fulfilment service that fulfils shipped stock through imsClient
+func NewService(imsClient *imsclient.Client) *Service {
+	return &Service{imsClient: imsClient}
+}
+
+// SetShipmentStock makes the service fulfil shipped stock through stock rather than line
+// by line over the JSON API
+func (s *Service) SetShipmentStock(stock ShipmentStock) {
+	s.stock = stock
+}
+
+// Shipments returns the shipments of an order with their history
+func (s *Service) Shipments(ctx context.Context, orderID string) ([]orders.Shipment, error) {
+	order, err := s.loadOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return order.Shipments, nil
+}
+
+// Advance applies req to the shipment it names, or to every open shipment of the order.
+// Shipments already at or past the requested status are left as they are, so repeating a
+// request is safe. It returns the updated order.
+func (s *Service) Advance(ctx context.Context, orderID string, req Request) (*orders.Order, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	order, err := s.loadOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = order.WithTenant(ctx)
+	if holds := order.ActiveHolds(); len(holds) > 0 {
+		return nil, fmt.Errorf("%w: %s placed by %s", ErrOrderHeld, holds[0].Type, holds[0].PlacedBy)
+	}
+
+	var targets []orders.Shipment
+	for _, shipment := range order.Shipments {
+		switch {
+		case req.ShipmentID != "" && shipment.ShipmentID == req.ShipmentID:
+			if shipment.Status == orders.ShipmentCancelled {
+				return nil, fmt.Errorf("%w: shipment %s is cancelled", ErrInvalidTransition, shipment.ShipmentID)
+			}
+			targets = append(targets, shipment)
+		case req.ShipmentID == "" && shipment.Status != orders.ShipmentCancelled:
+			targets = append(targets, shipment)
+		}
+	}
+	if req.ShipmentID != "" && len(targets) == 0 {
+		return nil, orders.ErrShipmentNotFound
+	}
+	if len(targets) == 0 {
+		return nil, ErrNoShipments
+	}
+
+	for _, shipment := range targets {
+		if err := s.advanceShipment(ctx, orderID, shipment, req); err != nil {
+			return nil, err
+		}
+	}
+
+	order, err = orders.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if status := orderStatus(order); status != "" && status != order.Status {
+		if err := orders.UpdateOrderStatus(ctx, orderID, status); err != nil {
+			return nil, err
+		}
+		order.Status = status
+	}
+	return order, nil
+}
+
+// advanceShipment moves one shipment to the status of req, fulfilling its stock in IMS
+// when it ships
+func (s *Service) advanceShipment(ctx context.Context, orderID string, shipment orders.Shipment, req Request) error {
+	target := actionStatus[req.Action]
+	if statusRank[shipment.Status] >= statusRank[target] {
+		if shipment.Status == orders.ShipmentShipped && req.Action == ActionShip &&
+			shipment.TrackingNumber != req.TrackingNumber {
+			return fmt.Errorf("%w: shipment %s already shipped with tracking number %s",
+				ErrInvalidTransition, shipment.ShipmentID, shipment.TrackingNumber)
+		}
+		return nil
+	}
+
+	if target == orders.ShipmentShipped {
+		if err := s.fulfil(ctx, orderID, shipment); err != nil {
+			return err
+		}
+	}
+
+	change := orders.ShipmentEvent{To: target, At: time.Now()}
+	if target == orders.ShipmentShipped {
+		change.Carrier = req.Carrier
+		change.TrackingNumber = req.TrackingNumber
+	}
+	if err := orders.TransitionShipment(ctx, orderID, shipment.ShipmentID, shipment.Status, change); err != nil {
+		return err
+	}
+
+	log.Printf("📦 Shipment %s of order %s: %s → %s", shipment.ShipmentID, orderID, shipment.Status, target)
+	return nil
+}
+
+// fulfil deducts what is left to fulfil of a shipment in IMS. Fulfilled units are recorded
+// line by line so a retry after a failure only fulfils what is left.
+func (s *Service) fulfil(ctx context.Context, orderID string, shipment orders.Shipment) error {
+	var lines []imsclient.StockRequest
+	for _, item := range shipment.Items {
+		if remaining := item.Quantity - item.Fulfilled; remaining > 0 {
+			lines = append(lines, imsclient.StockRequest{HubCode: shipment.HubID, SkuCode: item.SKU, Quantity: remaining})
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if s.stock == nil {
+		for _, line := range lines {
+			if _, err := s.imsClient.FulfillInventory(ctx, line); err != nil {
+				return fmt.Errorf("failed to fulfil %s for shipment %s: %w", line.SkuCode, shipment.ShipmentID, err)
+			}
+			if err := orders.RecordFulfilled(ctx, orderID, shipment.ShipmentID, line.SkuCode, line.Quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs, err := s.stock.Fulfill(ctx, orderID, lines)
+	if err != nil {
+		return fmt.Errorf("failed to fulfil shipment %s: %w", shipment.ShipmentID, err)
+	}
+	var failed error
+	for i, line := range lines {
+		if errs[i] != nil {
+			if failed == nil {
+				failed = fmt.Errorf("failed to fulfil %s for shipment %s: %w", line.SkuCode, shipment.ShipmentID, errs[i])
+			}
+			continue
+		}
+		if err := orders.RecordFulfilled(ctx, orderID, shipment.ShipmentID, line.SkuCode, line.Quantity); err != nil {
+			return err
+		}
+	}
+	return failed
+}
+
+// loadOrder returns an order, first recording shipments for orders allocated before they
+// were tracked
+func (s *Service) loadOrder(ctx context.Context, orderID string) (*orders.Order, error) {
+	order, err := orders.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(order.Shipments) > 0 || order.Status != "new_order" {
+		return order, nil
+	}
+
+	shipments := legacyShipments(order)
+	if len(shipments) == 0 {
+		return order, nil
+	}
+	if err := orders.EnsureShipments(ctx, orderID, shipments); err != nil {
+		return nil, err
+	}
+	return orders.GetOrder(ctx, orderID)
+}
+
+// legacyShipments builds the shipments of an order that was reserved at the hub of each
+// of its lines
+func legacyShipments(order *orders.Order) []orders.Shipment {
+	var shipments []orders.Shipment
+	for _, line := range order.CurrentLines() {
+		if line.Reserved > 0 {
+			shipments = orders.AddReservation(shipments, order.OrderID, line.HubID, line.SKU, line.Reserved)
+		}
+	}
+	return shipments
+}
+
+// orderStatus derives the status of an order from its open shipments, or returns "" when
+// the order status should be left alone
+func orderStatus(order *orders.Order) string {
+	if order.Status == "on_hold" || order.Status == orders.StatusPartiallyAllocated || order.Status == "cancelled" {
+		// Orders still waiting for stock keep their status until the backorders are filled
+		return ""
+	}
+
+	lowest, shipped, open := statusRank[orders.ShipmentShipped], 0, 0
+	for _, shipment := range order.Shipments {
+		if shipment.Status == orders.ShipmentCancelled {
+			continue
+		}
+		open++
+		if shipment.Status == orders.ShipmentShipped {
+			shipped++
+		}
+		lowest = min(lowest, statusRank[shipment.Status])
+	}
+
+	switch {
+	case open == 0:
+		return ""
+	case shipped == open:
+		return StatusShipped
+	case shipped > 0:
+		return StatusPartiallyShipped
+	case lowest == statusRank[orders.ShipmentPacked]:
+		return StatusPacked
+	case lowest == statusRank[orders.ShipmentPicked]:
+		return StatusPicked
+	default:
+		return ""
+	}
+}
@@ -1,226 +1,29 @@
 package ims
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"strings"
-	"time"
-)
-
-// Client for IMS API calls
-type Client struct {
-	baseURL    string
-	httpClient *http.Client
-}
-
-// ValidationResult holds validation results from IMS
-type ValidationResult struct {
-	SKUValid bool   `json:"sku_valid"`
-	HubValid bool   `json:"hub_valid"`
-	Error    string `json:"error,omitempty"`
-}
-
-// SKUResponse from IMS SKU validation
-type SKUResponse struct {
-	Valid       bool   `json:"valid"`
-	SKU         string `json:"sku"`
-	ProductName string `json:"product_name,omitempty"`
-	Error       string `json:"error,omitempty"`
-}
-
-// HubResponse from IMS Hub validation
-type HubResponse struct {
-	Valid bool   `json:"valid"`
-	HubID string `json:"hub_id"`
-	Name  string `json:"name,omitempty"`
-	Error string `json:"error,omitempty"`
-}
-
-// NewClient creates a new IMS client
-func NewClient(baseURL string) *Client {
-	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-	}
-}
-
-// ValidateSKU validates a SKU via IMS API
-func (c *Client) ValidateSKU(ctx context.Context, sku string) (*SKUResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/skus/code/%s", c.baseURL, sku)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create SKU validation request: %w", err)
-	}
-
-	// Add tenant ID header (required by IMS)
-	req.Header.Set("X-Tenant-ID", "default")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		// IMS service not available, simulate validation for demo
-		log.Printf("⚠️  IMS service not available for SKU validation: %v", err)
-		return c.simulateSKUValidation(sku), nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNotFound {
-		return &SKUResponse{
-			Valid: false,
-			SKU:   sku,
-			Error: "SKU not found",
-		}, nil
-	}
-
-	if resp.StatusCode == http.StatusBadRequest {
-		// IMS expects UUID format, but we're using human-readable SKUs for demo
-		// Simulate validation for demo purposes
-		log.Printf("⚠️  IMS SKU validation format mismatch (expects UUID): %s", sku)
-		return c.simulateSKUValidation(sku), nil
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		// For other errors, simulate validation
-		log.Printf("⚠️  IMS SKU validation error %d, simulating: %s", resp.StatusCode, sku)
-		return c.simulateSKUValidation(sku), nil
-	}
-
-	// Parse the actual SKU response from IMS
-	var imsResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&imsResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode SKU response: %w", err)
-	}
-
-	// Check if SKU is active (assuming IMS returns this field)
-	active := true
-	if activeField, ok := imsResponse["active"]; ok {
-		if activeBool, ok := activeField.(bool); ok {
-			active = activeBool
-		}
-	}
-
-	return &SKUResponse{
-		Valid:       active,
-		SKU:         sku,
-		ProductName: fmt.Sprintf("%v", imsResponse["name"]),
-		Error:       "",
-	}, nil
-}
-
-// simulateSKUValidation simulates SKU validation for demo purposes
-func (c *Client) simulateSKUValidation(sku string) *SKUResponse {
-	// Consider SKUs starting with "INVALID" as invalid for demo
-	valid := !strings.HasPrefix(strings.ToUpper(sku), "INVALID")
+	"oms-service/config"
+	"oms-service/internal/auth"
+	"oms-service/internal/tenant"
 
-	return &SKUResponse{
-		Valid:       valid,
-		SKU:         sku,
-		ProductName: fmt.Sprintf("Product for %s", sku),
-		Error:       "",
-	}
-}
-
-// ValidateHub validates a Hub via IMS API
-func (c *Client) ValidateHub(ctx context.Context, hubID string) (*HubResponse, error) {
-	// For hub validation, we'll check if the hub exists by trying to get hub list
-	// Since IMS doesn't have a hub-by-code endpoint, we'll validate by checking service health
-	url := fmt.Sprintf("%s/api/v1/hubs/", c.baseURL)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Hub validation request: %w", err)
-	}
-
-	// Add tenant ID header (required by IMS)
-	req.Header.Set("X-Tenant-ID", "default")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		// IMS service not available, simulate validation for demo
-		log.Printf("⚠️  IMS service not available for Hub validation: %v", err)
-		return c.simulateHubValidation(hubID), nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusBadRequest {
-		// IMS has tenant requirements, simulate validation for demo
-		log.Printf("⚠️  IMS Hub validation tenant issue, simulating: %s", hubID)
-		return c.simulateHubValidation(hubID), nil
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		// For other errors, simulate validation
-		log.Printf("⚠️  IMS Hub validation error %d, simulating: %s", resp.StatusCode, hubID)
-		return c.simulateHubValidation(hubID), nil
-	}
-
-	// For now, assume hub is valid if IMS service is reachable
-	// In a real implementation, we'd parse the hub list and check if hubID exists
-	return &HubResponse{
-		Valid: true,
-		HubID: hubID,
-		Name:  fmt.Sprintf("Hub %s", hubID),
-		Error: "",
-	}, nil
-}
-
-// simulateHubValidation simulates Hub validation for demo purposes
-func (c *Client) simulateHubValidation(hubID string) *HubResponse {
-	// Consider Hubs starting with "INVALID" as invalid for demo
-	valid := !strings.HasPrefix(strings.ToUpper(hubID), "INVALID")
-
-	return &HubResponse{
-		Valid: valid,
-		HubID: hubID,
-		Name:  fmt.Sprintf("Hub %s", hubID),
-		Error: "",
-	}
-}
-
-// ValidateOrder validates both SKU and Hub for an order
-func (c *Client) ValidateOrder(ctx context.Context, sku, hubID string) (*ValidationResult, error) {
-	// Validate SKU
-	skuResp, err := c.ValidateSKU(ctx, sku)
-	if err != nil {
-		return &ValidationResult{
-			SKUValid: false,
-			HubValid: false,
-			Error:    fmt.Sprintf("SKU validation failed: %v", err),
-		}, nil
-	}
-
-	// Validate Hub
-	hubResp, err := c.ValidateHub(ctx, hubID)
-	if err != nil {
-		return &ValidationResult{
-			SKUValid: skuResp.Valid,
-			HubValid: false,
-			Error:    fmt.Sprintf("Hub validation failed: %v", err),
-		}, nil
-	}
-
-	result := &ValidationResult{
-		SKUValid: skuResp.Valid,
-		HubValid: hubResp.Valid,
-	}
-
-	// Collect any validation errors
-	var errors []string
-	if !skuResp.Valid && skuResp.Error != "" {
-		errors = append(errors, fmt.Sprintf("SKU: %s", skuResp.Error))
-	}
-	if !hubResp.Valid && hubResp.Error != "" {
-		errors = append(errors, fmt.Sprintf("Hub: %s", hubResp.Error))
-	}
-
-	if len(errors) > 0 {
-		result.Error = fmt.Sprintf("%v", errors)
-	}
+	"github.com/omniful/ims-service/pkg/imsclient"
+)
 
-	return result, nil
+// NewClient creates the IMS client shared across OMS. Requests carry the tenant of their
+// context and a credential for it, and IMS failures surface as imsclient.ErrUnavailable
+// rather than simulated success.
+func NewClient(cfg *config.Config) *imsclient.Client {
+	maxRetries := cfg.IMSMaxRetries
+	if maxRetries == 0 {
+		// imsclient reads zero as its default
+		maxRetries = -1
+	}
+	return imsclient.New(imsclient.Config{
+		BaseURL:           cfg.IMSServiceURL,
+		Timeout:           cfg.IMSTimeout,
+		MaxRetries:        maxRetries,
+		BreakerThreshold:  cfg.IMSBreakerThreshold,
+		BreakerCooldown:   cfg.IMSBreakerCooldown,
+		TenantFromContext: tenant.FromContext,
+		Credential:        auth.NewServiceTokens(cfg.IMSJWTSecret, cfg.IMSAPIKey).Credential,
+	})
 }
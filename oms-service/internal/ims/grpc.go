@@ -0,0 +1,261 @@
+package ims
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"oms-service/config"
+	"oms-service/internal/auth"
+	"oms-service/internal/tenant"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
+	"github.com/omniful/ims-service/pkg/imspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCClient calls the IMS gRPC API, used for the hot paths where the JSON API costs most:
+// batch validation and the reservations, releases and fulfilments of orders. Like the
+// HTTP client, calls carry the tenant of their context and a credential for it, and
+// failures IMS may recover from surface as imsclient.ErrUnavailable.
+type GRPCClient struct {
+	conn      *grpc.ClientConn
+	inventory imspb.InventoryClient
+	// http is the HTTP client whose circuit breaker and retries calls go through, so IMS
+	// is judged up or down the same way over both transports
+	http    *imsclient.Client
+	timeout time.Duration
+}
+
+// NewGRPCClient creates a client for the IMS gRPC server at cfg.IMSGRPCAddress that shares
+// the circuit breaker and retry policy of httpClient. It connects lazily, on the first call.
+func NewGRPCClient(cfg *config.Config, httpClient *imsclient.Client) (*GRPCClient, error) {
+	conn, err := grpc.NewClient(cfg.IMSGRPCAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(&callCredentials{tokens: auth.NewServiceTokens(cfg.IMSJWTSecret, cfg.IMSAPIKey)}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create IMS gRPC client: %w", err)
+	}
+	return &GRPCClient{
+		conn:      conn,
+		inventory: imspb.NewInventoryClient(conn),
+		http:      httpClient,
+		timeout:   cfg.IMSTimeout,
+	}, nil
+}
+
+// Inventory returns the generated client, for calls not wrapped here
+func (c *GRPCClient) Inventory() imspb.InventoryClient {
+	return c.inventory
+}
+
+// Close closes the connection to IMS
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// ValidateBatch validates order lines like imsclient.Client.ValidateBatch does over HTTP
+func (c *GRPCClient) ValidateBatch(ctx context.Context, lines []imsclient.ValidationLine) ([]imsclient.ValidationResult, error) {
+	req := &imspb.ValidateBatchRequest{Lines: make([]*imspb.ValidationLine, len(lines))}
+	for i, line := range lines {
+		req.Lines[i] = &imspb.ValidationLine{Sku: line.SKU, HubId: line.HubID, SellerId: line.SellerID}
+	}
+
+	var resp *imspb.ValidateBatchResponse
+	err := c.call(ctx, "ValidateBatch", true, func(ctx context.Context, opts ...grpc.CallOption) (err error) {
+		resp, err = c.inventory.ValidateBatch(ctx, req, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(lines) {
+		return nil, fmt.Errorf("IMS returned %d validation results for %d lines", len(resp.Results), len(lines))
+	}
+
+	results := make([]imsclient.ValidationResult, len(resp.Results))
+	for i, result := range resp.Results {
+		results[i] = imsclient.ValidationResult{
+			SKU:           result.Sku,
+			HubID:         result.HubId,
+			SellerID:      result.SellerId,
+			Valid:         result.Valid,
+			SKUValid:      result.SkuValid,
+			HubValid:      result.HubValid,
+			SKUStatus:     result.SkuStatus,
+			HubStatus:     result.HubStatus,
+			SellerMatch:   result.SellerMatch,
+			HubCarriesSKU: result.HubCarriesSku,
+			Errors:        result.Errors,
+		}
+	}
+	return results, nil
+}
+
+// ReserveOrder reserves every line of an order in one call, or none of them: IMS releases
+// the lines it reserved when one fails. The failure of that line is returned like the
+// HTTP client returns it, an imsclient.APIError with 409 for missing stock.
+func (c *GRPCClient) ReserveOrder(ctx context.Context, orderID string, lines []imsclient.StockRequest) error {
+	var resp *imspb.ReserveOrderResponse
+	req := &imspb.ReserveOrderRequest{OrderId: orderID, Lines: stockLines(lines)}
+	err := c.call(ctx, "ReserveOrder", false, func(ctx context.Context, opts ...grpc.CallOption) (err error) {
+		resp, err = c.inventory.ReserveOrder(ctx, req, opts...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Reserved {
+		return nil
+	}
+
+	var failed error
+	var stranded []imsclient.StockRequest
+	for i, line := range resp.Lines {
+		switch line.Status {
+		case imspb.LineStatus_LINE_STATUS_OK:
+			// IMS could not release it after another line failed
+			stranded = append(stranded, lines[i])
+		case imspb.LineStatus_LINE_STATUS_ROLLED_BACK, imspb.LineStatus_LINE_STATUS_SKIPPED:
+		default:
+			if failed == nil {
+				failed = fmt.Errorf("failed to reserve inventory for SKU %s: %w", line.SkuCode, lineError(line))
+			}
+		}
+	}
+	if len(stranded) > 0 {
+		if _, err := c.Release(ctx, orderID, stranded); err != nil {
+			return fmt.Errorf("order %s was reserved in part and could not be released: %w", orderID, err)
+		}
+	}
+	if failed == nil {
+		failed = fmt.Errorf("IMS did not reserve order %s", orderID)
+	}
+	return failed
+}
+
+// Release moves the reserved quantity of each line back to available. It returns the
+// outcome of each line, nil when it was released; a line failing does not stop the others.
+func (c *GRPCClient) Release(ctx context.Context, orderID string, lines []imsclient.StockRequest) ([]error, error) {
+	return c.moveStock(ctx, "Release", orderID, lines, c.inventory.Release)
+}
+
+// Fulfill deducts the reserved quantity of each line once it has left its hub. It returns
+// the outcome of each line like Release.
+func (c *GRPCClient) Fulfill(ctx context.Context, orderID string, lines []imsclient.StockRequest) ([]error, error) {
+	return c.moveStock(ctx, "Fulfill", orderID, lines, c.inventory.Fulfill)
+}
+
+func (c *GRPCClient) moveStock(ctx context.Context, method, orderID string, lines []imsclient.StockRequest,
+	move func(ctx context.Context, req *imspb.StockRequest, opts ...grpc.CallOption) (*imspb.StockResponse, error)) ([]error, error) {
+	var resp *imspb.StockResponse
+	req := &imspb.StockRequest{OrderId: orderID, Lines: stockLines(lines)}
+	err := c.call(ctx, method, false, func(ctx context.Context, opts ...grpc.CallOption) (err error) {
+		resp, err = move(ctx, req, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Lines) != len(lines) {
+		return nil, fmt.Errorf("IMS returned %d results for %d lines", len(resp.Lines), len(lines))
+	}
+
+	errs := make([]error, len(resp.Lines))
+	for i, line := range resp.Lines {
+		errs[i] = lineError(line)
+	}
+	return errs, nil
+}
+
+// call makes one gRPC call through the circuit breaker and retries of the HTTP client.
+// Reads are retried on any failure IMS may recover from; writes only when IMS rejected
+// them unprocessed for rate limiting, so stock is never moved twice. grpc-go already
+// retries calls that never left the client.
+func (c *GRPCClient) call(ctx context.Context, method string, idempotent bool, fn func(ctx context.Context, opts ...grpc.CallOption) error) error {
+	return c.http.Call(ctx, func(ctx context.Context) (bool, time.Duration, error) {
+		if c.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+		}
+		var header metadata.MD
+		err := fn(ctx, grpc.Header(&header))
+		if err == nil {
+			return false, 0, nil
+		}
+
+		switch status.Code(err) {
+		case codes.ResourceExhausted:
+			return true, retryAfter(header), fmt.Errorf("%w: IMS gRPC %s: %v", imsclient.ErrRateLimited, method, err)
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Aborted:
+			return idempotent, 0, fmt.Errorf("%w: IMS gRPC %s: %v", imsclient.ErrUnavailable, method, err)
+		default:
+			return false, 0, fmt.Errorf("IMS gRPC %s failed: %w", method, err)
+		}
+	})
+}
+
+// retryAfter reads the retry-after header IMS sends with ResourceExhausted, in seconds
+func retryAfter(header metadata.MD) time.Duration {
+	values := header.Get("retry-after")
+	if len(values) == 0 {
+		return 0
+	}
+	seconds, err := strconv.Atoi(values[0])
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func stockLines(lines []imsclient.StockRequest) []*imspb.StockLine {
+	stock := make([]*imspb.StockLine, len(lines))
+	for i, line := range lines {
+		stock[i] = &imspb.StockLine{HubCode: line.HubCode, SkuCode: line.SkuCode, Quantity: int32(line.Quantity)}
+	}
+	return stock
+}
+
+// lineError returns the failure of a stock line as the HTTP client reports the same
+// failure of a single line, nil when the line succeeded
+func lineError(line *imspb.LineResult) error {
+	switch line.Status {
+	case imspb.LineStatus_LINE_STATUS_OK:
+		return nil
+	case imspb.LineStatus_LINE_STATUS_INSUFFICIENT:
+		return &imsclient.APIError{StatusCode: http.StatusConflict, Message: line.Error}
+	case imspb.LineStatus_LINE_STATUS_NOT_FOUND:
+		return &imsclient.APIError{StatusCode: http.StatusNotFound, Message: line.Error}
+	case imspb.LineStatus_LINE_STATUS_INVALID:
+		return &imsclient.APIError{StatusCode: http.StatusBadRequest, Message: line.Error}
+	default:
+		return fmt.Errorf("%w: IMS could not move %s at %s (%s): %s",
+			imsclient.ErrUnavailable, line.SkuCode, line.HubCode, line.Status, line.Error)
+	}
+}
+
+// callCredentials sends the credential and tenant of each call's context as metadata
+type callCredentials struct {
+	tokens *auth.ServiceTokens
+}
+
+func (c *callCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	md := map[string]string{"x-tenant-id": tenant.FromContext(ctx)}
+	if credential := c.tokens.Credential(ctx); credential != "" {
+		md["authorization"] = "Bearer " + credential
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity is false, IMS is reached over the internal network
+func (c *callCredentials) RequireTransportSecurity() bool {
+	return false
+}
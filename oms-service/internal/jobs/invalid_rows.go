@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"oms-service/internal/validation"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Invalid row statuses
+const (
+	RowOpen       = "open"       // waiting to be corrected
+	RowResolved   = "resolved"   // corrected row was accepted by a child job
+	RowSuperseded = "superseded" // corrected row failed again and is tracked by the child job
+)
+
+// Columns added to the corrected-template CSV around the original order columns
+const (
+	SourceRowColumn = "source_row"
+	ErrorsColumn    = "errors"
+)
+
+// defaultTemplateHeaders are used when a job did not record its CSV headers
+var defaultTemplateHeaders = []string{
+	"order_id", "customer_name", "customer_email", "product_name", "sku", "hub_id",
+	"quantity", "unit_price", "total_amount", "order_date", "shipping_address",
+}
+
+// InvalidRow is a rejected CSV row kept for correction and resubmission
+type InvalidRow struct {
+	JobID      string                  `bson:"job_id" json:"job_id"`
+	RowNumber  int                     `bson:"row_number" json:"row_number"`
+	ParentRow  int                     `bson:"parent_row,omitempty" json:"parent_row,omitempty"`
+	Data       map[string]string       `bson:"data" json:"data"`
+	Errors     []validation.FieldError `bson:"errors" json:"errors"`
+	Status     string                  `bson:"status" json:"status"`
+	ResolvedBy string                  `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	CreatedAt  time.Time               `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time               `bson:"updated_at" json:"updated_at"`
+}
+
+// SaveInvalidRows stores the rejected rows of a batch
+func SaveInvalidRows(ctx context.Context, rows []InvalidRow) error {
+	if invalidRowsCollection == nil {
+		return ErrNotInitialized
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(rows))
+	for i := range rows {
+		rows[i].Status = RowOpen
+		rows[i].CreatedAt = now
+		rows[i].UpdatedAt = now
+		docs[i] = rows[i]
+	}
+
+	if _, err := invalidRowsCollection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to save invalid rows: %w", err)
+	}
+	return nil
+}
+
+// GetInvalidRows returns the rejected rows of a job ordered by row number
+func GetInvalidRows(ctx context.Context, jobID string) ([]InvalidRow, error) {
+	rows := []InvalidRow{}
+	err := StreamInvalidRows(ctx, jobID, false, func(row *InvalidRow) error {
+		rows = append(rows, *row)
+		return nil
+	})
+	return rows, err
+}
+
+// StreamInvalidRows calls fn for each rejected row of a job without loading them all at once
+func StreamInvalidRows(ctx context.Context, jobID string, openOnly bool, fn func(*InvalidRow) error) error {
+	if invalidRowsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	filter := bson.M{"job_id": jobID}
+	if openOnly {
+		filter["status"] = RowOpen
+	}
+	cursor, err := invalidRowsCollection.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "row_number", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to query invalid rows: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row InvalidRow
+		if err := cursor.Decode(&row); err != nil {
+			return fmt.Errorf("failed to decode invalid row: %w", err)
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// OpenRowNumbers returns the row numbers of a job that are still waiting for correction
+func OpenRowNumbers(ctx context.Context, jobID string) (map[int]bool, error) {
+	open := make(map[int]bool)
+	err := StreamInvalidRows(ctx, jobID, true, func(row *InvalidRow) error {
+		open[row.RowNumber] = true
+		return nil
+	})
+	return open, err
+}
+
+// UpdateRowStatus marks rows of a job as resolved or superseded by a child job
+func UpdateRowStatus(ctx context.Context, jobID string, rowNumbers []int, status, childJobID string) error {
+	if invalidRowsCollection == nil {
+		return ErrNotInitialized
+	}
+	if len(rowNumbers) == 0 {
+		return nil
+	}
+
+	_, err := invalidRowsCollection.UpdateMany(ctx,
+		bson.M{"job_id": jobID, "row_number": bson.M{"$in": rowNumbers}, "status": RowOpen},
+		bson.M{"$set": bson.M{"status": status, "resolved_by": childJobID, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update invalid rows: %w", err)
+	}
+	return nil
+}
+
+// WriteCorrectionTemplate writes the open rejected rows of a job as a CSV that can be
+// edited and resubmitted. Each row carries its source_row and the reasons it was rejected.
+func WriteCorrectionTemplate(ctx context.Context, w io.Writer, job *Job) (int, error) {
+	headers := TemplateHeaders(job.Headers)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{SourceRowColumn}, headers...), ErrorsColumn)); err != nil {
+		return 0, fmt.Errorf("failed to write template header: %w", err)
+	}
+
+	count := 0
+	err := StreamInvalidRows(ctx, job.JobID, true, func(row *InvalidRow) error {
+		record := make([]string, 0, len(headers)+2)
+		record = append(record, strconv.Itoa(row.RowNumber))
+		for _, header := range headers {
+			record = append(record, row.Data[header])
+		}
+		record = append(record, strings.Join(validation.Messages(row.Errors), "; "))
+
+		count++
+		return writer.Write(record)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	writer.Flush()
+	return count, writer.Error()
+}
+
+// TemplateHeaders returns the order columns of a correction template, without the
+// bookkeeping columns added by a previous template
+func TemplateHeaders(headers []string) []string {
+	if len(headers) == 0 {
+		return defaultTemplateHeaders
+	}
+
+	columns := make([]string, 0, len(headers))
+	for _, header := range headers {
+		if header == SourceRowColumn || header == ErrorsColumn {
+			continue
+		}
+		columns = append(columns, header)
+	}
+	return columns
+}
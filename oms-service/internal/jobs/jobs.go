@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Job statuses
+const (
+	StatusAwaitingUpload = "awaiting_upload" // pre-signed URL issued, file not yet uploaded
+	StatusProcessing     = "processing"
+	StatusCompleted      = "completed"
+	StatusFailed         = "failed"
+)
+
+// ErrNotInitialized is returned when MongoDB was not available at startup
+var ErrNotInitialized = errors.New("job store not initialized")
+
+// ErrJobNotFound is returned when no job exists for the given ID
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrUploadCompleted is returned when a direct upload was already completed
+var ErrUploadCompleted = errors.New("upload already completed")
+
+// Job tracks a single CSV upload and the outcome of processing it
+type Job struct {
+	JobID       string    `bson:"job_id" json:"job_id"`
+	Filename    string    `bson:"filename" json:"filename"`
+	Bucket      string    `bson:"bucket,omitempty" json:"bucket,omitempty"`
+	Key         string    `bson:"key,omitempty" json:"key,omitempty"`
+	UploadID    string    `bson:"upload_id,omitempty" json:"upload_id,omitempty"`
+	ParentJobID string    `bson:"parent_job_id,omitempty" json:"parent_job_id,omitempty"`
+	ReprocessOf string    `bson:"reprocess_of,omitempty" json:"reprocess_of,omitempty"`
+	TenantID    string    `bson:"tenant_id" json:"tenant_id"`
+	Status      string    `bson:"status" json:"status"`
+	Headers     []string  `bson:"headers,omitempty" json:"headers,omitempty"`
+	TotalRows   int       `bson:"total_rows" json:"total_rows"`
+	ValidRows   int       `bson:"valid_rows" json:"valid_rows"`
+	InvalidRows int       `bson:"invalid_rows" json:"invalid_rows"`
+	SkippedRows int       `bson:"skipped_rows" json:"skipped_rows"`
+	InvalidKey  string    `bson:"invalid_key,omitempty" json:"invalid_key,omitempty"`
+	Error       string    `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+var (
+	jobsCollection        *mongo.Collection
+	invalidRowsCollection *mongo.Collection
+)
+
+// Initialize sets up the job collections and their indexes
+func Initialize(client *mongo.Client) error {
+	if client == nil {
+		return ErrNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := client.Database("oms_database")
+	jobs := db.Collection("upload_jobs")
+	rows := db.Collection("invalid_rows")
+
+	_, err := jobs.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "job_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "parent_job_id", Value: 1}}},
+		{Keys: bson.D{{Key: "upload_id", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create upload_jobs indexes: %w", err)
+	}
+
+	_, err = rows.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "job_id", Value: 1}, {Key: "row_number", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create invalid_rows indexes: %w", err)
+	}
+
+	jobsCollection = jobs
+	invalidRowsCollection = rows
+
+	log.Println("📊 Database: oms_database, Collections: upload_jobs, invalid_rows")
+	return nil
+}
+
+// CreateJob stores a new job and assigns its ID. Jobs start in the processing state unless
+// another status is set.
+func CreateJob(ctx context.Context, job *Job) error {
+	if jobsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	now := time.Now()
+	job.JobID = primitive.NewObjectID().Hex()
+	if job.Status == "" {
+		job.Status = StatusProcessing
+	}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if _, err := jobsCollection.InsertOne(ctx, job); err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	log.Printf("🗂️ Created upload job %s for %s", job.JobID, job.Filename)
+	return nil
+}
+
+// GetJob retrieves a job by its ID
+func GetJob(ctx context.Context, jobID string) (*Job, error) {
+	if jobsCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var job Job
+	err := jobsCollection.FindOne(ctx, bson.M{"job_id": jobID}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetJobByUploadID retrieves the job of a direct upload by its upload ID
+func GetJobByUploadID(ctx context.Context, uploadID string) (*Job, error) {
+	if jobsCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var job Job
+	err := jobsCollection.FindOne(ctx, bson.M{"upload_id": uploadID}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// StartUpload moves a job awaiting its direct upload to processing and records where the
+// file was stored. Only one caller can start a job, later calls get ErrUploadCompleted.
+func StartUpload(ctx context.Context, job *Job, bucket, key string) error {
+	if jobsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	now := time.Now()
+	result, err := jobsCollection.UpdateOne(ctx,
+		bson.M{"job_id": job.JobID, "status": StatusAwaitingUpload},
+		bson.M{"$set": bson.M{"status": StatusProcessing, "bucket": bucket, "key": key, "updated_at": now}})
+	if err != nil {
+		return fmt.Errorf("failed to start job: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrUploadCompleted
+	}
+
+	job.Status = StatusProcessing
+	job.Bucket = bucket
+	job.Key = key
+	job.UpdatedAt = now
+	return nil
+}
+
+// GetChildJobs returns the jobs that were resubmitted from jobID
+func GetChildJobs(ctx context.Context, jobID string) ([]Job, error) {
+	if jobsCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	cursor, err := jobsCollection.Find(ctx, bson.M{"parent_job_id": jobID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child jobs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	children := []Job{}
+	if err := cursor.All(ctx, &children); err != nil {
+		return nil, fmt.Errorf("failed to decode child jobs: %w", err)
+	}
+	return children, nil
+}
+
+// GetLineage returns the chain of jobs from the original upload down to jobID
+func GetLineage(ctx context.Context, jobID string) ([]Job, error) {
+	var lineage []Job
+	seen := make(map[string]bool)
+
+	for id := jobID; id != "" && !seen[id]; {
+		seen[id] = true
+		job, err := GetJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		lineage = append([]Job{*job}, lineage...)
+		id = job.ParentJobID
+	}
+	return lineage, nil
+}
+
+// CompleteJob records the final counts and status of a job
+func CompleteJob(ctx context.Context, job *Job, processingErr error) error {
+	if jobsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	job.Status = StatusCompleted
+	job.Error = ""
+	if processingErr != nil {
+		job.Status = StatusFailed
+		job.Error = processingErr.Error()
+	}
+	job.UpdatedAt = time.Now()
+
+	_, err := jobsCollection.UpdateOne(ctx, bson.M{"job_id": job.JobID}, bson.M{
+		"$set": bson.M{
+			"status":       job.Status,
+			"error":        job.Error,
+			"headers":      job.Headers,
+			"total_rows":   job.TotalRows,
+			"valid_rows":   job.ValidRows,
+			"invalid_rows": job.InvalidRows,
+			"skipped_rows": job.SkippedRows,
+			"invalid_key":  job.InvalidKey,
+			"updated_at":   job.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+
+	log.Printf("🗂️ Upload job %s %s: %d rows, %d valid, %d invalid, %d skipped",
+		job.JobID, job.Status, job.TotalRows, job.ValidRows, job.InvalidRows, job.SkippedRows)
+	return nil
+}
@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"oms-service/internal/allocation"
+	"oms-service/internal/backlog"
+	"oms-service/internal/orders"
+	"oms-service/internal/tenant"
+
+	"github.com/omniful/go_commons/pubsub"
+)
+
+// retryBatchSize caps how many waiting orders one retry pass re-evaluates
+const retryBatchSize = 200
+
+// BacklogRetrier re-runs finalization for on_hold orders when stock may have arrived. It is
+// triggered by IMS inventory.changed events and by a periodic sweep, which also flags
+// orders that have waited too long.
+type BacklogRetrier struct {
+	finalizer *OrderFinalizerHandler
+	interval  time.Duration
+	maxWait   time.Duration
+	// mutex runs one pass at a time. Finalizations from other paths or instances may still
+	// overlap a pass, orders.UpdateAllocation keeps them from both recording reservations.
+	mutex sync.Mutex
+}
+
+// NewBacklogRetrier creates a retrier that sweeps the whole backlog every interval
+func NewBacklogRetrier(finalizer *OrderFinalizerHandler, interval time.Duration) *BacklogRetrier {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &BacklogRetrier{
+		finalizer: finalizer,
+		interval:  interval,
+		maxWait:   backlog.MaxWait(),
+	}
+}
+
+// RetryWaiting re-runs finalization for waiting orders of tenantID with an item at hubID for
+// sku (at any hub when hubID is empty), or for all waiting orders when both are empty. It
+// returns how many were finalized.
+func (r *BacklogRetrier) RetryWaiting(ctx context.Context, tenantID, hubID, sku string) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries, err := backlog.Waiting(ctx, tenantID, hubID, sku, retryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	finalized := 0
+	for i := range entries {
+		entry := &entries[i]
+		orderCtx := tenant.WithID(ctx, entry.TenantID)
+		ok, err := r.finalizer.finalizeOrder(orderCtx, backlogEvent(entry))
+		if errors.Is(err, orders.ErrOrderModified) {
+			// Allocated by another pass meanwhile, such as an inventory event racing the
+			// sweep for a backordered order, which this one gave its reservations back to
+			log.Printf("⏭️ [BACKLOG] Order %s changed while it was retried, left to the next pass", entry.OrderID)
+			continue
+		}
+		if err != nil {
+			log.Printf("❌ [BACKLOG] Retry of on_hold order %s failed: %v", entry.OrderID, err)
+			continue
+		}
+		if ok {
+			finalized++
+		}
+	}
+
+	if len(entries) > 0 {
+		log.Printf("🔁 [BACKLOG] Retried %d on_hold orders, %d finalized", len(entries), finalized)
+	}
+	return finalized, nil
+}
+
+// Start sweeps the backlog every interval until ctx is cancelled
+func (r *BacklogRetrier) Start(ctx context.Context) {
+	go func() {
+		log.Printf("🔁 [BACKLOG] On-hold retrier started (every %s, overdue after %s, %s order)",
+			r.interval, r.maxWait, backlog.RetryOrder())
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.flagOverdue(ctx)
+				if _, err := r.RetryWaiting(ctx, "", "", ""); err != nil {
+					log.Printf("❌ [BACKLOG] Periodic retry failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// flagOverdue flags orders that have waited on_hold for longer than maxWait
+func (r *BacklogRetrier) flagOverdue(ctx context.Context) {
+	overdue, err := backlog.FlagOverdue(ctx, r.maxWait)
+	if err != nil {
+		log.Printf("❌ [BACKLOG] Failed to flag overdue orders: %v", err)
+		return
+	}
+
+	for _, entry := range overdue {
+		log.Printf("⏰ [BACKLOG] Order %s has been on_hold for more than %s", entry.OrderID, r.maxWait)
+		orderCtx := tenant.WithID(ctx, entry.TenantID)
+		if err := orders.AddFlag(orderCtx, entry.OrderID, orders.FlagOnHoldOverdue); err != nil {
+			log.Printf("⚠️ [BACKLOG] %v", err)
+		}
+	}
+}
+
+// Process implements pubsub.IPubSubMessageHandler for IMS inventory events. Waiting orders
+// for the item are retried whenever its available quantity goes up.
+func (r *BacklogRetrier) Process(ctx context.Context, message *pubsub.Message) error {
+	envelope := EnvelopeFromHeaders(message.Headers)
+	if envelope.Type != EventInventoryChanged {
+		return nil
+	}
+
+	var event InventoryChangedEvent
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		return Permanent(fmt.Errorf("failed to unmarshal %s event: %w", envelope.Type, err))
+	}
+	if event.After.Available <= event.Before.Available {
+		return nil
+	}
+
+	log.Printf("📦 [BACKLOG] Stock available for %s at %s went %d → %d (%s), retrying waiting orders",
+		event.SkuCode, event.HubCode, event.Before.Available, event.After.Available, event.Cause)
+	// Orders are stored under the tenant their client named, by code or by UUID. Events
+	// without a tenant retry the orders of every tenant.
+	tenants := envelope.Tenants()
+	if len(tenants) == 0 {
+		tenants = []string{""}
+	}
+	for _, tenantID := range tenants {
+		// Tenants splitting lines across hubs can use the new stock for orders from any hub
+		hubID := event.HubCode
+		if allocation.PolicyFor(ctx, tenantID).Splits() {
+			hubID = ""
+		}
+		if _, err := r.RetryWaiting(ctx, tenantID, hubID, event.SkuCode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backlogEvent rebuilds the order.created event of a waiting order
+func backlogEvent(entry *backlog.Entry) *OrderCreatedEvent {
+	items := make([]OrderItem, len(entry.Items))
+	for i, item := range entry.Items {
+		items[i] = OrderItem{
+			SKU:         item.SKU,
+			ProductName: item.ProductName,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			HubID:       item.HubID,
+		}
+	}
+
+	return &OrderCreatedEvent{
+		OrderID:     entry.OrderID,
+		CustomerID:  entry.CustomerID,
+		TotalAmount: entry.TotalAmount,
+		Status:      "on_hold",
+		CreatedAt:   entry.OrderedAt,
+		Items:       items,
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"oms-service/internal/tenant"
+
 	"github.com/IBM/sarama"
 	"github.com/omniful/go_commons/pubsub"
 	"github.com/omniful/go_commons/pubsub/interceptor"
@@ -21,6 +23,9 @@ type ConsumerClient struct {
 	HandlerMap               map[string]pubsub.IPubSubMessageHandler
 	Interceptor              interceptor.Interceptor
 	DeadLetterQueuePublisher *sqs.Publisher
+	retryPolicy              *RetryPolicy
+	retryPublisher           MessagePublisher
+	deadLetterStore          DeadLetterStore
 	transactionName          string
 	mutex                    sync.Mutex
 	isRunning                bool
@@ -51,11 +56,12 @@ type DeadLetterConfig struct {
 	Prefix   string
 }
 
-// OrderEventHandler interface for handling order events
+// OrderEventHandler handles order events, one method per event type
 type OrderEventHandler interface {
 	HandleOrderCreated(ctx context.Context, event *OrderCreatedEvent) error
-	HandleOrderUpdated(ctx context.Context, event *OrderCreatedEvent) error
-	HandleOrderCancelled(ctx context.Context, event *OrderCreatedEvent) error
+	HandleOrderUpdated(ctx context.Context, event *OrderUpdatedEvent) error
+	HandleOrderCancelled(ctx context.Context, event *OrderCancelledEvent) error
+	HandleOrderShipped(ctx context.Context, event *OrderShippedEvent) error
 }
 
 // DefaultOrderEventHandler provides a default implementation
@@ -69,18 +75,25 @@ func (h *DefaultOrderEventHandler) HandleOrderCreated(ctx context.Context, event
 }
 
 // HandleOrderUpdated handles order updated events
-func (h *DefaultOrderEventHandler) HandleOrderUpdated(ctx context.Context, event *OrderCreatedEvent) error {
+func (h *DefaultOrderEventHandler) HandleOrderUpdated(ctx context.Context, event *OrderUpdatedEvent) error {
 	log.Printf("📥 [KAFKA CONSUMER] Order updated event received: OrderID=%s, Status=%s",
 		event.OrderID, event.Status)
 	return nil
 }
 
 // HandleOrderCancelled handles order cancelled events
-func (h *DefaultOrderEventHandler) HandleOrderCancelled(ctx context.Context, event *OrderCreatedEvent) error {
+func (h *DefaultOrderEventHandler) HandleOrderCancelled(ctx context.Context, event *OrderCancelledEvent) error {
 	log.Printf("📥 [KAFKA CONSUMER] Order cancelled event received: OrderID=%s", event.OrderID)
 	return nil
 }
 
+// HandleOrderShipped handles order shipped events
+func (h *DefaultOrderEventHandler) HandleOrderShipped(ctx context.Context, event *OrderShippedEvent) error {
+	log.Printf("📥 [KAFKA CONSUMER] Order shipped event received: OrderID=%s, Tracking=%s",
+		event.OrderID, event.TrackingNumber)
+	return nil
+}
+
 // NewConsumer creates a new Kafka consumer client
 func NewConsumer(config *ConsumerConfig) *ConsumerClient {
 	if config.RetryInterval == 0 {
@@ -166,12 +179,30 @@ func (c *ConsumerClient) RegisterHandler(topic string, handler pubsub.IPubSubMes
 	return c
 }
 
-// RegisterOrderEventHandler registers a handler for order events
+// RegisterOrderEventHandler registers a handler for every event type on the order events topic
 func (c *ConsumerClient) RegisterOrderEventHandler(handler OrderEventHandler) *ConsumerClient {
 	orderHandler := &OrderMessageHandler{
 		EventHandler: handler,
 	}
-	return c.RegisterHandler("order.created", orderHandler)
+	return c.RegisterHandler(OrderEventsTopic(), orderHandler)
+}
+
+// SetRetryPolicy enables retry topics for failed messages on every registered topic
+func (c *ConsumerClient) SetRetryPolicy(policy *RetryPolicy) *ConsumerClient {
+	c.retryPolicy = policy
+	return c
+}
+
+// SetRetryPublisher sets the publisher used to move failed messages to retry and dead-letter topics
+func (c *ConsumerClient) SetRetryPublisher(publisher MessagePublisher) *ConsumerClient {
+	c.retryPublisher = publisher
+	return c
+}
+
+// SetDeadLetterStore sets where messages are recorded once their retries are exhausted
+func (c *ConsumerClient) SetDeadLetterStore(store DeadLetterStore) *ConsumerClient {
+	c.deadLetterStore = store
+	return c
 }
 
 // UnRegisterHandler removes a handler for a topic in a concurrent-safe way
@@ -217,11 +248,23 @@ func (c *ConsumerClient) Subscribe(ctx context.Context) error {
 				Context:         ctx,
 				TransactionName: c.transactionName,
 				RetryInterval:   c.config.RetryInterval,
+				Retry:           c.retryPolicy,
+				Publisher:       c.retryPublisher,
+				DeadLetters:     c.deadLetterStore,
+				TopicRoutes:     make(map[string]string),
 			}
 
 			topics := make([]string, 0, len(c.HandlerMap))
 			for topic := range c.HandlerMap {
 				topics = append(topics, topic)
+				if c.retryPolicy == nil {
+					continue
+				}
+				// Retry topics are handled by the handler of their original topic
+				for _, retryTopic := range c.retryPolicy.RetryTopics(topic) {
+					consumerHandler.TopicRoutes[retryTopic] = topic
+					topics = append(topics, retryTopic)
+				}
 			}
 
 			log.Printf("🔄 Starting to consume from topics: %v", topics)
@@ -287,6 +330,10 @@ type ConsumerGroupHandler struct {
 	Context         context.Context
 	TransactionName string
 	RetryInterval   time.Duration
+	Retry           *RetryPolicy
+	Publisher       MessagePublisher
+	DeadLetters     DeadLetterStore
+	TopicRoutes     map[string]string // retry topic -> original topic
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim
@@ -303,6 +350,10 @@ func (h *ConsumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
 
 // ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages()
 func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	if _, isRetry := h.TopicRoutes[claim.Topic()]; isRetry {
+		return h.consumeRetryClaim(session, claim)
+	}
+
 	for {
 		select {
 		case message := <-claim.Messages():
@@ -310,7 +361,9 @@ func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 				return nil
 			}
 
-			h.processMessage(session, message)
+			if h.processMessage(session, message) {
+				session.MarkMessage(message, "")
+			}
 
 		case <-h.Context.Done():
 			return nil
@@ -318,38 +371,44 @@ func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 	}
 }
 
-// processMessage processes a single Kafka message
-func (h *ConsumerGroupHandler) processMessage(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) {
+// processMessage processes a single Kafka message. It returns false if the message was
+// neither processed nor handed to a retry or dead-letter topic and must not be marked.
+func (h *ConsumerGroupHandler) processMessage(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) bool {
 	topic := message.Topic
+	if originalTopic, isRetry := h.TopicRoutes[message.Topic]; isRetry {
+		topic = originalTopic
+	}
 
 	handler, exists := h.HandlerMap[topic]
 	if !exists {
 		log.Printf("⚠️ No handler found for topic: %s", topic)
-		session.MarkMessage(message, "")
-		return
+		return true
 	}
 	// Convert Sarama message to pubsub message
 	pubsubMessage := &pubsub.Message{
-		Topic: message.Topic,
-		Key:   string(message.Key), // Convert []byte to string
-		Value: message.Value,
-		Headers: map[string]string{
-			"partition": fmt.Sprintf("%d", message.Partition),
-			"offset":    fmt.Sprintf("%d", message.Offset),
-			"timestamp": message.Timestamp.Format(time.RFC3339),
-		},
+		Topic:   message.Topic,
+		Key:     string(message.Key), // Convert []byte to string
+		Value:   message.Value,
+		Headers: messageHeaders(message),
 	}
+
 	// Process message with handler
 	err := handler.Process(h.Context, pubsubMessage)
 	if err != nil {
-		log.Printf("❌ Failed to process message from topic %s: %v", topic, err)
-		// Could implement retry logic here
-		return
+		log.Printf("❌ Failed to process message from topic %s: %v", message.Topic, err)
+		// Keep the message on the partition until it has been handed to a retry or dead-letter topic
+		for !h.handleFailure(h.Context, topic, pubsubMessage, err) {
+			select {
+			case <-time.After(h.RetryInterval):
+			case <-session.Context().Done():
+				return false
+			}
+		}
+		return true
 	}
 
-	// Mark message as processed
-	session.MarkMessage(message, "")
 	log.Printf("✅ Message processed successfully from topic: %s", topic)
+	return true
 }
 
 // OrderMessageHandler handles order-specific messages
@@ -357,31 +416,56 @@ type OrderMessageHandler struct {
 	EventHandler OrderEventHandler
 }
 
-// Process implements pubsub.IPubSubMessageHandler
+// Process implements pubsub.IPubSubMessageHandler, dispatching on the event type header.
+// Unknown types and undecodable payloads are permanent failures and go straight to the DLQ.
 func (h *OrderMessageHandler) Process(ctx context.Context, message *pubsub.Message) error {
-	var event OrderCreatedEvent
-	err := json.Unmarshal(message.Value, &event)
-	if err != nil {
-		log.Printf("❌ Failed to unmarshal order event: %v", err)
-		return err
+	envelope := EnvelopeFromHeaders(message.Headers)
+	ctx = WithEnvelope(ctx, envelope)
+	if envelope.TenantID != "" {
+		ctx = tenant.WithID(ctx, envelope.TenantID)
 	}
 
-	// Route based on event type (could be extended with event type field)
-	switch event.Status {
-	case "created", "pending", "on_hold":
-		err = h.EventHandler.HandleOrderCreated(ctx, &event)
-	case "updated", "processing", "shipped", "new_order":
-		err = h.EventHandler.HandleOrderUpdated(ctx, &event)
-	case "cancelled":
-		err = h.EventHandler.HandleOrderCancelled(ctx, &event)
+	var err error
+	switch envelope.Type {
+	case EventOrderCreated:
+		var event OrderCreatedEvent
+		if err = decodeEvent(message, envelope, &event); err == nil {
+			err = h.EventHandler.HandleOrderCreated(ctx, &event)
+		}
+	case EventOrderUpdated:
+		var event OrderUpdatedEvent
+		if err = decodeEvent(message, envelope, &event); err == nil {
+			err = h.EventHandler.HandleOrderUpdated(ctx, &event)
+		}
+	case EventOrderCancelled:
+		var event OrderCancelledEvent
+		if err = decodeEvent(message, envelope, &event); err == nil {
+			err = h.EventHandler.HandleOrderCancelled(ctx, &event)
+		}
+	case EventOrderShipped:
+		var event OrderShippedEvent
+		if err = decodeEvent(message, envelope, &event); err == nil {
+			err = h.EventHandler.HandleOrderShipped(ctx, &event)
+		}
 	default:
-		err = h.EventHandler.HandleOrderCreated(ctx, &event) // Default to created
+		err = Permanent(fmt.Errorf("%w %q", ErrUnknownEventType, envelope.Type))
 	}
 
 	if err != nil {
-		log.Printf("❌ Failed to handle order event %s: %v", event.OrderID, err)
+		log.Printf("❌ Failed to handle %s event %s: %v", envelope.Type, envelope.ID, err)
 		return err
 	}
 
 	return nil
 }
+
+// decodeEvent unmarshals the payload of message, rejecting schema versions this service does not know
+func decodeEvent(message *pubsub.Message, envelope Envelope, event interface{}) error {
+	if envelope.SchemaVersion != schemaVersion {
+		return Permanent(fmt.Errorf("unsupported schema version %q for %s", envelope.SchemaVersion, envelope.Type))
+	}
+	if err := json.Unmarshal(message.Value, event); err != nil {
+		return Permanent(fmt.Errorf("failed to unmarshal %s event: %w", envelope.Type, err))
+	}
+	return nil
+}
@@ -34,17 +34,14 @@ func (h *ExampleOrderEventHandler) HandleOrderCreated(ctx context.Context, event
 }
 
 // HandleOrderUpdated processes order updates
-func (h *ExampleOrderEventHandler) HandleOrderUpdated(ctx context.Context, event *OrderCreatedEvent) error {
-	log.Printf("🔄 Processing order update: %s -> %s", event.OrderID, event.Status)
+func (h *ExampleOrderEventHandler) HandleOrderUpdated(ctx context.Context, event *OrderUpdatedEvent) error {
+	log.Printf("🔄 Processing order update: %s %s -> %s", event.OrderID, event.PreviousStatus, event.Status)
 
 	// Example processing logic based on status:
 	switch event.Status {
 	case "processing":
 		log.Printf("🏭 Order %s is now being processed", event.OrderID)
 		// Trigger warehouse operations
-	case "shipped":
-		log.Printf("🚚 Order %s has been shipped", event.OrderID)
-		// Send tracking information to customer
 	case "delivered":
 		log.Printf("📦 Order %s has been delivered", event.OrderID)
 		// Send delivery confirmation, request review
@@ -54,8 +51,8 @@ func (h *ExampleOrderEventHandler) HandleOrderUpdated(ctx context.Context, event
 }
 
 // HandleOrderCancelled processes order cancellations
-func (h *ExampleOrderEventHandler) HandleOrderCancelled(ctx context.Context, event *OrderCreatedEvent) error {
-	log.Printf("❌ Processing order cancellation: %s", event.OrderID)
+func (h *ExampleOrderEventHandler) HandleOrderCancelled(ctx context.Context, event *OrderCancelledEvent) error {
+	log.Printf("❌ Processing order cancellation: %s (%s)", event.OrderID, event.Reason)
 
 	// Example processing logic:
 	// 1. Restore inventory
@@ -63,11 +60,18 @@ func (h *ExampleOrderEventHandler) HandleOrderCancelled(ctx context.Context, eve
 	// 3. Send cancellation confirmation
 	// 4. Update analytics
 
-	log.Printf("💰 Refunding $%.2f for cancelled order %s", event.TotalAmount, event.OrderID)
+	log.Printf("📦 Restoring %d items for cancelled order %s", len(event.Items), event.OrderID)
 
 	return nil
 }
 
+// HandleOrderShipped processes shipped orders
+func (h *ExampleOrderEventHandler) HandleOrderShipped(ctx context.Context, event *OrderShippedEvent) error {
+	log.Printf("🚚 Order %s has been shipped via %s", event.OrderID, event.Carrier)
+	// Send tracking information to customer
+	return nil
+}
+
 // StartKafkaConsumer demonstrates how to start the Kafka consumer in your application
 func StartKafkaConsumer(ctx context.Context) error {
 	// Create consumer with default configuration
@@ -0,0 +1,269 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omniful/go_commons/pubsub"
+)
+
+// Event envelope headers, following the CloudEvents Kafka binding in binary mode:
+// the payload is the message value and the context attributes travel as ce_ headers.
+const (
+	HeaderSpecVersion   = "ce_specversion"
+	HeaderEventID       = "ce_id"
+	HeaderEventSource   = "ce_source"
+	HeaderEventType     = "ce_type"
+	HeaderEventTime     = "ce_time"
+	HeaderEventSubject  = "ce_subject"
+	HeaderTenantID      = "ce_tenantid"
+	HeaderTenantCode    = "ce_tenantcode"
+	HeaderSchemaVersion = "ce_schemaversion"
+	HeaderContentType   = "content-type"
+
+	// HeaderLegacyEventType is the event type header used before the envelope was introduced
+	HeaderLegacyEventType = "event_type"
+)
+
+// Order event types
+const (
+	EventOrderCreated   = "order.created"
+	EventOrderUpdated   = "order.updated"
+	EventOrderCancelled = "order.cancelled"
+	EventOrderShipped   = "order.shipped"
+
+	// EventInventoryChanged is published by IMS whenever inventory quantities change
+	EventInventoryChanged = "inventory.changed"
+)
+
+const (
+	specVersion   = "1.0"
+	eventSource   = "oms-service"
+	schemaVersion = "1"
+)
+
+// ErrUnknownEventType is returned for messages whose type has no handler
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// Envelope holds the context attributes of an event
+type Envelope struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	Source        string    `json:"source"`
+	Subject       string    `json:"subject,omitempty"`
+	Time          time.Time `json:"time"`
+	TenantID      string    `json:"tenant_id"`
+	TenantCode    string    `json:"tenant_code,omitempty"` // set by IMS, whose ce_tenantid is the tenant UUID
+	SchemaVersion string    `json:"schema_version"`
+}
+
+// Tenants returns the identifiers the event's tenant may be known by in OMS: its code
+// when the publisher sent one, then its ID
+func (e Envelope) Tenants() []string {
+	switch {
+	case e.TenantCode == "" && e.TenantID == "":
+		return nil
+	case e.TenantCode == "":
+		return []string{e.TenantID}
+	case e.TenantID == "" || e.TenantID == e.TenantCode:
+		return []string{e.TenantCode}
+	default:
+		return []string{e.TenantCode, e.TenantID}
+	}
+}
+
+// OrderUpdatedEvent is emitted when an order changes status or its lines are amended
+type OrderUpdatedEvent struct {
+	OrderID        string       `json:"order_id"`
+	CustomerID     string       `json:"customer_id"`
+	Status         string       `json:"status"`
+	PreviousStatus string       `json:"previous_status,omitempty"`
+	Reason         string       `json:"reason,omitempty"`
+	Changes        []LineChange `json:"changes,omitempty"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// Line change types of an amended order
+const (
+	LineAdded           = "added"
+	LineRemoved         = "removed"
+	LineQuantityChanged = "quantity_changed"
+)
+
+// LineChange describes how an amendment changed one line of an order
+type LineChange struct {
+	Change      string `json:"change"`
+	SKU         string `json:"sku"`
+	HubID       string `json:"hub_id"`
+	OldQuantity int    `json:"old_quantity"`
+	NewQuantity int    `json:"new_quantity"`
+}
+
+// OrderCancelledEvent is emitted when an order is cancelled. Items lists what was
+// reserved so it can be released.
+type OrderCancelledEvent struct {
+	OrderID     string      `json:"order_id"`
+	CustomerID  string      `json:"customer_id"`
+	Reason      string      `json:"reason,omitempty"`
+	CancelledAt time.Time   `json:"cancelled_at"`
+	Items       []OrderItem `json:"items"`
+}
+
+// OrderShippedEvent is emitted when an order leaves the hub
+type OrderShippedEvent struct {
+	OrderID        string      `json:"order_id"`
+	CustomerID     string      `json:"customer_id"`
+	Carrier        string      `json:"carrier,omitempty"`
+	TrackingNumber string      `json:"tracking_number,omitempty"`
+	ShippedAt      time.Time   `json:"shipped_at"`
+	Items          []OrderItem `json:"items"`
+}
+
+// InventoryLevels are the quantities of an inventory item in IMS
+type InventoryLevels struct {
+	Quantity  int `json:"quantity"`
+	Available int `json:"available"`
+	Reserved  int `json:"reserved"`
+	InTransit int `json:"in_transit"`
+	Damaged   int `json:"damaged"`
+}
+
+// InventoryChangedEvent is the payload of IMS inventory.changed events
+type InventoryChangedEvent struct {
+	TenantID  string          `json:"tenant_id"`
+	HubCode   string          `json:"hub_code"`
+	SkuCode   string          `json:"sku_code"`
+	Cause     string          `json:"cause"`
+	Before    InventoryLevels `json:"before"`
+	After     InventoryLevels `json:"after"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the consumer dead-letters the message without retrying it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// NewEnvelope creates the envelope for a new event about subject
+func NewEnvelope(eventType, subject, tenantID string) Envelope {
+	return Envelope{
+		ID:            uuid.NewString(),
+		Type:          eventType,
+		Source:        eventSource,
+		Subject:       subject,
+		Time:          time.Now().UTC(),
+		TenantID:      tenantID,
+		SchemaVersion: schemaVersion,
+	}
+}
+
+// Headers returns the envelope as message headers
+func (e Envelope) Headers() map[string]string {
+	headers := map[string]string{
+		HeaderSpecVersion:   specVersion,
+		HeaderEventID:       e.ID,
+		HeaderEventSource:   e.Source,
+		HeaderEventType:     e.Type,
+		HeaderEventTime:     e.Time.Format(time.RFC3339Nano),
+		HeaderTenantID:      e.TenantID,
+		HeaderSchemaVersion: e.SchemaVersion,
+		HeaderContentType:   "application/json",
+		// Kept so consumers that predate the envelope still recognise the event
+		HeaderLegacyEventType: e.Type,
+	}
+	if e.Subject != "" {
+		headers[HeaderEventSubject] = e.Subject
+	}
+	return headers
+}
+
+// EnvelopeFromHeaders reads the envelope of a consumed message. Messages published before
+// the envelope was introduced only carry event_type and are read as schema version 1.
+func EnvelopeFromHeaders(headers map[string]string) Envelope {
+	envelope := Envelope{
+		ID:            headers[HeaderEventID],
+		Type:          headers[HeaderEventType],
+		Source:        headers[HeaderEventSource],
+		Subject:       headers[HeaderEventSubject],
+		TenantID:      headers[HeaderTenantID],
+		TenantCode:    headers[HeaderTenantCode],
+		SchemaVersion: headers[HeaderSchemaVersion],
+	}
+	if envelope.Type == "" {
+		envelope.Type = headers[HeaderLegacyEventType]
+	}
+	if envelope.SchemaVersion == "" {
+		envelope.SchemaVersion = schemaVersion
+	}
+	if eventTime, err := time.Parse(time.RFC3339Nano, headers[HeaderEventTime]); err == nil {
+		envelope.Time = eventTime
+	}
+	return envelope
+}
+
+type envelopeKey struct{}
+
+// WithEnvelope returns a copy of ctx carrying the envelope of the event being handled
+func WithEnvelope(ctx context.Context, envelope Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, envelope)
+}
+
+// EnvelopeFromContext returns the envelope of the event being handled
+func EnvelopeFromContext(ctx context.Context) (Envelope, bool) {
+	envelope, ok := ctx.Value(envelopeKey{}).(Envelope)
+	return envelope, ok
+}
+
+// OrderEventsTopic returns the topic order events are published to
+func OrderEventsTopic() string {
+	if topic := os.Getenv("KAFKA_TOPIC"); topic != "" {
+		return topic
+	}
+	return "order-events"
+}
+
+// InventoryEventsTopic returns the topic IMS publishes inventory events to
+func InventoryEventsTopic() string {
+	if topic := os.Getenv("KAFKA_INVENTORY_TOPIC"); topic != "" {
+		return topic
+	}
+	return "inventory-events"
+}
+
+// NewEventMessage builds a Kafka message carrying payload in an envelope of eventType.
+// Messages are keyed by key so events for the same key stay in order.
+func NewEventMessage(eventType, key, subject, tenantID string, payload interface{}) (*pubsub.Message, error) {
+	value, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	return &pubsub.Message{
+		Topic:   OrderEventsTopic(),
+		Key:     key,
+		Value:   value,
+		Headers: NewEnvelope(eventType, subject, tenantID).Headers(),
+	}, nil
+}
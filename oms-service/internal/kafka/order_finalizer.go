@@ -1,222 +1,476 @@
 package kafka
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
+	"oms-service/internal/allocation"
+	"oms-service/internal/backlog"
+	"oms-service/internal/degraded"
 	"oms-service/internal/orders"
-	"time"
+	"oms-service/internal/tenant"
+
+	"github.com/omniful/ims-service/pkg/imsclient"
 )
 
+// OrderStock reserves and releases the lines of an order in one call, such as the IMS gRPC
+// client. Without one, the finalizer calls the JSON API line by line.
+type OrderStock interface {
+	// ReserveOrder reserves every line or none of them
+	ReserveOrder(ctx context.Context, orderID string, lines []imsclient.StockRequest) error
+	// Release releases each line on its own, returning the outcome of each
+	Release(ctx context.Context, orderID string, lines []imsclient.StockRequest) ([]error, error)
+}
+
 // OrderFinalizerHandler handles order finalization logic
 type OrderFinalizerHandler struct {
-	imsBaseURL string
-	httpClient *http.Client
+	imsClient *imsclient.Client
+	stock     OrderStock
 }
 
-// NewOrderFinalizerHandler creates a new order finalizer
-func NewOrderFinalizerHandler(imsBaseURL string) *OrderFinalizerHandler {
-	return &OrderFinalizerHandler{
-		imsBaseURL: imsBaseURL,
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+// NewOrderFinalizerHandler creates a new order finalizer that reserves stock through imsClient
+func NewOrderFinalizerHandler(imsClient *imsclient.Client) *OrderFinalizerHandler {
+	return &OrderFinalizerHandler{imsClient: imsClient}
+}
+
+// SetOrderStock makes the finalizer reserve and release stock through stock rather than
+// line by line over the JSON API
+func (h *OrderFinalizerHandler) SetOrderStock(stock OrderStock) {
+	h.stock = stock
 }
 
 // HandleOrderCreated processes order.created events with full finalization logic
 func (h *OrderFinalizerHandler) HandleOrderCreated(ctx context.Context, event *OrderCreatedEvent) error {
+	_, err := h.finalizeOrder(ctx, event)
+	return err
+}
+
+// Reevaluate runs finalization again for an order from its stored lines, once something that
+// blocked it, such as a hold, is resolved. It reports whether the order is fully allocated.
+func (h *OrderFinalizerHandler) Reevaluate(ctx context.Context, orderID string) (bool, error) {
+	order, err := orders.GetOrder(ctx, orderID)
+	if err != nil {
+		return false, err
+	}
+	ctx = order.WithTenant(ctx)
+
+	event := &OrderCreatedEvent{
+		OrderID:     order.OrderID,
+		CustomerID:  order.CustomerID,
+		TotalAmount: order.TotalAmount,
+		Status:      order.Status,
+		CreatedAt:   order.CreatedAt,
+	}
+	for _, line := range order.CurrentLines() {
+		event.Items = append(event.Items, OrderItem{
+			SKU:         line.SKU,
+			ProductName: order.ProductName,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.UnitPrice,
+			HubID:       line.HubID,
+		})
+	}
+	return h.finalizeOrder(ctx, event)
+}
+
+// finalizeOrder reserves inventory for the outstanding lines of an order. Under the
+// tenant's allocation policy an order is either reserved in full (new_order), reserved in
+// part with the rest backordered (partially_allocated), or not at all (on_hold). Orders
+// still waiting for stock are added to the backlog; finalized reports full allocation.
+// A pass only records its reservations if the order is unchanged since it was read, so a
+// backordered order topped up by an inventory event and by the periodic sweep at the same
+// time has its outstanding quantity reserved once, and the losing pass releases its own.
+func (h *OrderFinalizerHandler) finalizeOrder(ctx context.Context, event *OrderCreatedEvent) (finalized bool, err error) {
 	log.Printf("🔄 [ORDER FINALIZER] Processing order finalization: OrderID=%s", event.OrderID)
 
-	// Step 1: Check inventory availability
-	available, err := h.checkInventoryAvailability(ctx, event)
+	order, err := orders.GetOrder(ctx, event.OrderID)
+	if errors.Is(err, orders.ErrOrderNotFound) {
+		return false, Permanent(err)
+	}
 	if err != nil {
-		log.Printf("❌ [ORDER FINALIZER] Inventory check failed for order %s: %v", event.OrderID, err)
-		return h.markOrderFailed(ctx, event.OrderID, fmt.Sprintf("Inventory check failed: %v", err))
+		return false, err
+	}
+	ctx = order.WithTenant(ctx)
+	if order.Status != "on_hold" && order.Status != orders.StatusPartiallyAllocated {
+		// Redelivered events and retries must not reserve twice
+		log.Printf("⏭️ [ORDER FINALIZER] Order %s is %s, nothing to allocate", event.OrderID, order.Status)
+		return order.Status == "new_order", nil
 	}
+	if holds := order.ActiveHolds(); len(holds) > 0 {
+		// Released holds re-run finalization, see Reevaluate
+		log.Printf("⏸️ [ORDER FINALIZER] Order %s has %d active holds (first: %s), not allocating",
+			event.OrderID, len(holds), holds[0].Type)
+		return false, nil
+	}
+
+	lines := allocationLines(order, event)
+	policy := allocation.PolicyFor(ctx, tenant.FromContext(ctx))
+
+	// Step 1: Decide how much of each outstanding line can be reserved now, and at which hubs
+	stock := make(map[string]map[string]int) // available stock by hub, shared by lines of a SKU
+	var reservations []lineReservation
+	short := false
+	simulated := false // IMS is down and DEGRADED_MODE=simulate, no more IMS calls for this order
+	for i, line := range lines {
+		outstanding := line.Outstanding()
+		if outstanding <= 0 {
+			continue
+		}
 
-	if !available {
-		log.Printf("⚠️ [ORDER FINALIZER] Insufficient inventory for order %s, keeping on_hold", event.OrderID)
-		return h.updateOrderStatus(ctx, event.OrderID, "on_hold", "Insufficient inventory")
+		key := line.SKU
+		if !policy.Splits() {
+			key = line.HubID + "/" + line.SKU
+		}
+		available, ok := stock[key]
+		switch {
+		case simulated:
+			available = simulatedStock(line)
+		case !ok:
+			available, err = h.hubStock(ctx, policy, line)
+			if imsclient.IsUnavailable(err) {
+				if simulated, err = h.degrade(ctx, event, err); !simulated {
+					return false, err
+				}
+				available = simulatedStock(line)
+			} else if err != nil {
+				log.Printf("❌ [ORDER FINALIZER] Inventory check failed for order %s: %v", event.OrderID, err)
+				return false, h.markOrderFailed(ctx, event.OrderID, fmt.Sprintf("Inventory check failed: %v", err))
+			}
+			stock[key] = available
+		}
+
+		remaining := outstanding
+		var planned []lineReservation
+		for _, hub := range policy.HubOrder(line.HubID, available) {
+			quantity := min(remaining, available[hub])
+			if quantity <= 0 {
+				continue
+			}
+			planned = append(planned, lineReservation{line: i, item: OrderItem{SKU: line.SKU, HubID: hub, Quantity: quantity}})
+			remaining -= quantity
+			if remaining == 0 {
+				break
+			}
+		}
+		if remaining > 0 {
+			short = true
+			log.Printf("⚠️ [INVENTORY] Insufficient stock: SKU=%s, Hub=%s, Required=%d, Available=%d",
+				line.SKU, line.HubID, outstanding, outstanding-remaining)
+			if !policy.AllowPartial {
+				continue
+			}
+		}
+
+		for _, reservation := range planned {
+			available[reservation.item.HubID] -= reservation.item.Quantity
+		}
+		reservations = append(reservations, planned...)
+	}
+	if short && !policy.AllowPartial {
+		// All-or-nothing: reserve nothing until every line can be filled
+		reservations = nil
 	}
 
-	// Step 2: Reserve inventory atomically
-	err = h.reserveInventory(ctx, event)
-	if err != nil {
+	// Step 2: Reserve inventory, releasing this pass's reservations if one fails
+	items := make([]OrderItem, len(reservations))
+	for i, reservation := range reservations {
+		items[i] = reservation.item
+	}
+	if simulated {
+		log.Printf("🧪 [ORDER FINALIZER] Simulating reservation of %d items for order %s", len(items), event.OrderID)
+	} else if err := h.reserveItems(ctx, event.OrderID, items); imsclient.IsUnavailable(err) {
+		if simulated, err = h.degrade(ctx, event, err); !simulated {
+			return false, err
+		}
+	} else if imsclient.IsConflict(err) {
+		// Other orders took the stock since it was checked. reserveItems released what it
+		// reserved before the failure, and like a short line the order waits for stock,
+		// reserving nothing this pass; backlog retries allocate it again under its policy.
+		log.Printf("⚠️ [ORDER FINALIZER] Stock for order %s was taken before it was reserved: %v", event.OrderID, err)
+		reservations, items = nil, nil
+	} else if err != nil {
 		log.Printf("❌ [ORDER FINALIZER] Inventory reservation failed for order %s: %v", event.OrderID, err)
-		return h.markOrderFailed(ctx, event.OrderID, fmt.Sprintf("Inventory reservation failed: %v", err))
+		return false, h.markOrderFailed(ctx, event.OrderID, fmt.Sprintf("Inventory reservation failed: %v", err))
 	}
 
-	// Step 3: Update order status to new_order
-	err = h.updateOrderStatus(ctx, event.OrderID, "new_order", "Inventory confirmed and reserved")
-	if err != nil {
-		// Try to rollback inventory reservation
-		rollbackErr := h.releaseInventory(ctx, event)
-		if rollbackErr != nil {
-			log.Printf("❌ [ORDER FINALIZER] Failed to rollback inventory for order %s: %v", event.OrderID, rollbackErr)
+	// Step 3: Record the allocation of each line, the shipment per hub and the resulting status
+	shipments := orders.CloneShipments(order.Shipments)
+	for _, reservation := range reservations {
+		lines[reservation.line].Reserved += reservation.item.Quantity
+		shipments = orders.AddReservation(shipments, event.OrderID,
+			reservation.item.HubID, reservation.item.SKU, reservation.item.Quantity)
+	}
+	for i := range lines {
+		lines[i].Backordered = lines[i].Outstanding()
+	}
+	status := orders.AllocationStatus(lines)
+
+	log.Printf("📝 [ORDER] Updating order %s status: %s (%d shipments)", event.OrderID, status, len(shipments))
+	if err := orders.UpdateAllocation(ctx, order, status, lines, shipments); err != nil {
+		// Give back what this pass reserved. When another finalization, an amendment or a
+		// hold changed the order meanwhile, what it recorded stands and this pass is retried
+		// from the order as it is now.
+		if errors.Is(err, orders.ErrOrderModified) {
+			log.Printf("⚠️ [ORDER FINALIZER] Order %s changed while it was allocated, releasing %d items", event.OrderID, len(items))
 		}
-		return fmt.Errorf("failed to update order status: %w", err)
+		if !simulated {
+			if rollbackErr := h.releaseInventory(ctx, event.OrderID, items); rollbackErr != nil {
+				log.Printf("❌ [ORDER FINALIZER] Failed to rollback inventory for order %s: %v", event.OrderID, rollbackErr)
+			}
+		}
+		return false, fmt.Errorf("failed to update order status: %w", err)
 	}
 
-	log.Printf("✅ [ORDER FINALIZER] Order %s successfully finalized: on_hold → new_order", event.OrderID)
-	return nil
-}
+	if status != "new_order" {
+		log.Printf("⚠️ [ORDER FINALIZER] Order %s is %s, waiting for stock", event.OrderID, status)
+		h.holdOrder(ctx, event)
+		return false, nil
+	}
 
-// HandleOrderUpdated handles order update events (placeholder for future expansion)
-func (h *OrderFinalizerHandler) HandleOrderUpdated(ctx context.Context, event *OrderCreatedEvent) error {
-	log.Printf("📥 [ORDER FINALIZER] Order updated event: OrderID=%s, Status=%s", event.OrderID, event.Status)
-	// Future: Handle order modifications, status changes, etc.
-	return nil
+	h.releaseFromBacklog(ctx, event.OrderID)
+	log.Printf("✅ [ORDER FINALIZER] Order %s successfully finalized: %s → new_order", event.OrderID, order.Status)
+	return true, nil
 }
 
-// HandleOrderCancelled handles order cancellation with inventory release
-func (h *OrderFinalizerHandler) HandleOrderCancelled(ctx context.Context, event *OrderCreatedEvent) error {
-	log.Printf("🚫 [ORDER FINALIZER] Processing order cancellation: OrderID=%s", event.OrderID)
+// allocationLines returns the lines of an order, built from the event items the first
+// time the order is allocated
+func allocationLines(order *orders.Order, event *OrderCreatedEvent) []orders.OrderLine {
+	if len(order.Lines) > 0 {
+		return append([]orders.OrderLine(nil), order.Lines...)
+	}
 
-	// Release any reserved inventory
-	err := h.releaseInventory(ctx, event)
-	if err != nil {
-		log.Printf("⚠️ [ORDER FINALIZER] Failed to release inventory for cancelled order %s: %v", event.OrderID, err)
+	lines := make([]orders.OrderLine, len(event.Items))
+	for i, item := range event.Items {
+		lines[i] = orders.OrderLine{
+			SKU:         item.SKU,
+			HubID:       item.HubID,
+			Quantity:    item.Quantity,
+			Backordered: item.Quantity,
+		}
 	}
+	return lines
+}
 
-	// Update order status to cancelled
-	return h.updateOrderStatus(ctx, event.OrderID, "cancelled", "Order cancelled by system")
+// lineReservation is a quantity of an order line to reserve at one hub
+type lineReservation struct {
+	line int
+	item OrderItem
 }
 
-// checkInventoryAvailability verifies if sufficient inventory is available
-func (h *OrderFinalizerHandler) checkInventoryAvailability(ctx context.Context, event *OrderCreatedEvent) (bool, error) {
-	for _, item := range event.Items {
-		available, err := h.getAvailableInventory(ctx, item.HubID, item.SKU)
-		if err != nil {
-			return false, fmt.Errorf("failed to check inventory for SKU %s at hub %s: %w", item.SKU, item.HubID, err)
+// reservedItems returns what is reserved in IMS for the shipments of an order that have
+// not left their hub. Orders allocated before shipments were recorded hold their
+// reservations at the hub of each line.
+func reservedItems(order *orders.Order) []OrderItem {
+	var items []OrderItem
+	if len(order.Shipments) == 0 {
+		for _, line := range order.Lines {
+			if line.Reserved > 0 {
+				items = append(items, OrderItem{SKU: line.SKU, HubID: line.HubID, Quantity: line.Reserved})
+			}
 		}
+		return items
+	}
 
-		if available < item.Quantity {
-			log.Printf("⚠️ [INVENTORY] Insufficient stock: SKU=%s, Hub=%s, Required=%d, Available=%d",
-				item.SKU, item.HubID, item.Quantity, available)
-			return false, nil
+	for _, shipment := range order.Shipments {
+		if shipment.Status == orders.ShipmentShipped || shipment.Status == orders.ShipmentCancelled {
+			continue
+		}
+		for _, item := range shipment.Items {
+			items = append(items, OrderItem{SKU: item.SKU, HubID: shipment.HubID, Quantity: item.Quantity})
 		}
 	}
+	return items
+}
 
-	log.Printf("✅ [INVENTORY] All items available for order %s", event.OrderID)
-	return true, nil
+// degrade applies the degraded mode policy when IMS cannot be reached while finalizing an
+// order, and flags the order with the decision. It reports whether to carry on with
+// simulated inventory; otherwise err is returned from finalization as is.
+func (h *OrderFinalizerHandler) degrade(ctx context.Context, event *OrderCreatedEvent, cause error) (simulate bool, err error) {
+	decision := degraded.Record(degraded.ComponentIMSInventory, cause)
+	if err := orders.RecordDegraded(ctx, event.OrderID, decision); err != nil {
+		log.Printf("⚠️ [ORDER FINALIZER] %v", err)
+	}
+
+	switch decision.Mode {
+	case degraded.ModeFail:
+		log.Printf("❌ [ORDER FINALIZER] IMS unavailable, failing order %s: %v", event.OrderID, cause)
+		return false, h.markOrderFailed(ctx, event.OrderID, fmt.Sprintf("Inventory service unavailable: %v", cause))
+	case degraded.ModeSimulate:
+		log.Printf("🧪 [ORDER FINALIZER] IMS unavailable, simulating inventory for order %s: %v", event.OrderID, cause)
+		return true, nil
+	default:
+		// Left on_hold in the backlog and retried through the retry topics once IMS is back
+		log.Printf("⚠️ [ORDER FINALIZER] IMS unavailable, order %s left on hold: %v", event.OrderID, cause)
+		h.holdOrder(ctx, event)
+		return false, cause
+	}
 }
 
-// getAvailableInventory queries IMS for available inventory
-func (h *OrderFinalizerHandler) getAvailableInventory(ctx context.Context, hubCode, skuCode string) (int, error) {
-	url := fmt.Sprintf("%s/api/v1/inventory/%s/%s", h.imsBaseURL, hubCode, skuCode)
+// simulatedStock pretends the line's own hub holds exactly what the line still needs
+func simulatedStock(line orders.OrderLine) map[string]int {
+	return map[string]int{line.HubID: line.Outstanding()}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// holdOrder adds an on_hold order to the backlog so it is retried when stock arrives
+func (h *OrderFinalizerHandler) holdOrder(ctx context.Context, event *OrderCreatedEvent) {
+	items := make([]backlog.Item, len(event.Items))
+	for i, item := range event.Items {
+		items[i] = backlog.Item{
+			SKU:         item.SKU,
+			ProductName: item.ProductName,
+			HubID:       item.HubID,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+		}
+	}
+
+	err := backlog.Hold(ctx, &backlog.Entry{
+		OrderID:     event.OrderID,
+		TenantID:    tenant.FromContext(ctx),
+		CustomerID:  event.CustomerID,
+		TotalAmount: event.TotalAmount,
+		OrderedAt:   event.CreatedAt,
+		Items:       items,
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to create inventory request: %w", err)
+		log.Printf("⚠️ [ORDER FINALIZER] Order %s is on_hold but not in the backlog: %v", event.OrderID, err)
 	}
+}
 
-	// Add required tenant header
-	req.Header.Set("X-Tenant-ID", "default")
+// releaseFromBacklog removes an order from the on-hold backlog once it no longer waits for stock
+func (h *OrderFinalizerHandler) releaseFromBacklog(ctx context.Context, orderID string) {
+	if err := backlog.Release(ctx, orderID); err != nil && !errors.Is(err, backlog.ErrNotInitialized) {
+		log.Printf("⚠️ [ORDER FINALIZER] Failed to release order %s from the backlog: %v", orderID, err)
+	}
+}
 
-	resp, err := h.httpClient.Do(req)
+// HandleOrderUpdated handles order update events (placeholder for future expansion)
+func (h *OrderFinalizerHandler) HandleOrderUpdated(ctx context.Context, event *OrderUpdatedEvent) error {
+	log.Printf("📥 [ORDER FINALIZER] Order updated event: OrderID=%s, Status=%s", event.OrderID, event.Status)
+	// Status changes never reserve inventory, only order.created does
+	return nil
+}
+
+// HandleOrderCancelled handles order cancellation with inventory release
+func (h *OrderFinalizerHandler) HandleOrderCancelled(ctx context.Context, event *OrderCancelledEvent) error {
+	log.Printf("🚫 [ORDER FINALIZER] Processing order cancellation: OrderID=%s", event.OrderID)
+
+	// Release any reserved inventory, as recorded on the order's shipments when it was allocated
+	items := event.Items
+	order, err := orders.GetOrder(ctx, event.OrderID)
+	if err == nil && len(order.Lines) > 0 {
+		items = reservedItems(order)
+	}
+	err = h.releaseInventory(ctx, event.OrderID, items)
 	if err != nil {
-		// IMS unavailable, simulate inventory check
-		log.Printf("⚠️ [INVENTORY] IMS unavailable, simulating inventory check for %s/%s", hubCode, skuCode)
-		return h.simulateInventoryCheck(skuCode), nil
+		log.Printf("⚠️ [ORDER FINALIZER] Failed to release inventory for cancelled order %s: %v", event.OrderID, err)
+	}
+	if order != nil && len(order.Shipments) > 0 {
+		if err := orders.CancelShipments(ctx, event.OrderID); err != nil {
+			log.Printf("⚠️ [ORDER FINALIZER] Failed to cancel shipments of order %s: %v", event.OrderID, err)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		// Inventory item not found, assume 0 available
-		return 0, nil
+	h.releaseFromBacklog(ctx, event.OrderID)
+
+	reason := event.Reason
+	if reason == "" {
+		reason = "Order cancelled by system"
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		// For other errors, simulate inventory
-		log.Printf("⚠️ [INVENTORY] IMS error %d, simulating inventory for %s/%s", resp.StatusCode, hubCode, skuCode)
-		return h.simulateInventoryCheck(skuCode), nil
+	// Update order status to cancelled
+	return h.updateOrderStatus(ctx, event.OrderID, "cancelled", reason)
+}
+
+// HandleOrderShipped handles order shipped events
+func (h *OrderFinalizerHandler) HandleOrderShipped(ctx context.Context, event *OrderShippedEvent) error {
+	log.Printf("🚚 [ORDER FINALIZER] Order shipped event: OrderID=%s, Carrier=%s, Tracking=%s",
+		event.OrderID, event.Carrier, event.TrackingNumber)
+	return nil
+}
+
+// getAvailableInventory queries IMS for the available inventory of a SKU at a hub
+func (h *OrderFinalizerHandler) getAvailableInventory(ctx context.Context, hubCode, skuCode string) (int, error) {
+	inventory, err := h.imsClient.GetInventoryItem(ctx, hubCode, skuCode)
+	if imsclient.IsNotFound(err) {
+		// Unknown hub or SKU, nothing can be reserved there
+		return 0, nil
 	}
+	if err != nil {
+		return 0, err
+	}
+	return inventory.Available, nil
+}
 
-	// Parse inventory response
-	var inventory struct {
-		Available int `json:"available"`
-		Quantity  int `json:"quantity"`
+// hubStock returns the stock available for a line at each hub the policy may reserve it
+// at: the line's own hub, or every hub holding the SKU when lines are split
+func (h *OrderFinalizerHandler) hubStock(ctx context.Context, policy *allocation.Policy, line orders.OrderLine) (map[string]int, error) {
+	if policy.Splits() {
+		stock, err := h.getHubInventory(ctx, line.SKU)
+		if err == nil {
+			return stock, nil
+		}
+		log.Printf("⚠️ [INVENTORY] Could not list hubs for %s, using hub %s only: %v", line.SKU, line.HubID, err)
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&inventory)
+	available, err := h.getAvailableInventory(ctx, line.HubID, line.SKU)
 	if err != nil {
-		log.Printf("⚠️ [INVENTORY] Failed to parse IMS response, simulating: %v", err)
-		return h.simulateInventoryCheck(skuCode), nil
+		return nil, err
 	}
-
-	return inventory.Available, nil
+	return map[string]int{line.HubID: available}, nil
 }
 
-// simulateInventoryCheck provides fallback inventory simulation
-func (h *OrderFinalizerHandler) simulateInventoryCheck(skuCode string) int {
-	// Simulate inventory based on SKU patterns
-	switch {
-	case len(skuCode) > 0 && skuCode[0] == 'L': // Laptops
-		return 50 // High stock
-	case len(skuCode) > 0 && skuCode[0] == 'M': // Mice
-		return 100 // Very high stock
-	case len(skuCode) > 0 && skuCode[0] == 'K': // Keyboards
-		return 25 // Medium stock
-	default:
-		return 10 // Default low stock
+// getHubInventory queries IMS for the available inventory of a SKU at every hub
+func (h *OrderFinalizerHandler) getHubInventory(ctx context.Context, skuCode string) (map[string]int, error) {
+	inventory, err := h.imsClient.GetInventory(ctx, imsclient.InventoryFilter{SkuCodes: []string{skuCode}, PageSize: 100})
+	if err != nil {
+		return nil, err
+	}
+
+	stock := make(map[string]int, len(inventory.Data))
+	for _, item := range inventory.Data {
+		if item.Hub.Code != "" {
+			stock[item.Hub.Code] += item.Available
+		}
 	}
+	return stock, nil
 }
 
-// reserveInventory atomically reserves inventory for all order items
-func (h *OrderFinalizerHandler) reserveInventory(ctx context.Context, event *OrderCreatedEvent) error {
-	log.Printf("🔒 [INVENTORY] Reserving inventory for order %s", event.OrderID)
+// reserveItems reserves each item at its hub. If a reservation fails, the ones made before
+// it are released so the order is left as it was.
+func (h *OrderFinalizerHandler) reserveItems(ctx context.Context, orderID string, items []OrderItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	log.Printf("🔒 [INVENTORY] Reserving inventory for order %s", orderID)
 
-	for _, item := range event.Items {
+	if h.stock != nil {
+		// IMS releases what it reserved when a line fails
+		if err := h.stock.ReserveOrder(ctx, orderID, stockRequests(items)); err != nil {
+			return err
+		}
+		log.Printf("✅ [INVENTORY] Reserved %d items for order %s", len(items), orderID)
+		return nil
+	}
+	for i, item := range items {
 		err := h.reserveItemInventory(ctx, item.HubID, item.SKU, item.Quantity)
 		if err != nil {
 			// Rollback previous reservations on failure
-			h.rollbackPreviousReservations(ctx, event, item)
+			h.releaseInventory(ctx, orderID, items[:i])
 			return fmt.Errorf("failed to reserve inventory for SKU %s: %w", item.SKU, err)
 		}
 	}
 
-	log.Printf("✅ [INVENTORY] All inventory reserved for order %s", event.OrderID)
+	log.Printf("✅ [INVENTORY] Reserved %d items for order %s", len(items), orderID)
 	return nil
 }
 
 // reserveItemInventory reserves inventory for a specific item
 func (h *OrderFinalizerHandler) reserveItemInventory(ctx context.Context, hubCode, skuCode string, quantity int) error {
-	url := fmt.Sprintf("%s/api/v1/inventory/reserve", h.imsBaseURL)
-
-	requestBody := map[string]interface{}{
-		"hub_code": hubCode,
-		"sku_code": skuCode,
-		"quantity": quantity,
-	}
-
-	body, err := json.Marshal(requestBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal reservation request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create reservation request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Tenant-ID", "default")
-
-	resp, err := h.httpClient.Do(req)
+	_, err := h.imsClient.ReserveInventory(ctx, imsclient.StockRequest{HubCode: hubCode, SkuCode: skuCode, Quantity: quantity})
 	if err != nil {
-		// IMS unavailable, log and continue (simulated success)
-		log.Printf("⚠️ [INVENTORY] IMS unavailable for reservation, simulating success: %s/%s qty=%d", hubCode, skuCode, quantity)
-		return nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		// Parse error response
-		var errorResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errorResp)
-		return fmt.Errorf("inventory reservation failed (status %d): %v", resp.StatusCode, errorResp)
+		return fmt.Errorf("inventory reservation failed: %w", err)
 	}
 
 	log.Printf("✅ [INVENTORY] Reserved %d units of %s at hub %s", quantity, skuCode, hubCode)
@@ -224,10 +478,23 @@ func (h *OrderFinalizerHandler) reserveItemInventory(ctx context.Context, hubCod
 }
 
 // releaseInventory releases reserved inventory (for cancellations or rollbacks)
-func (h *OrderFinalizerHandler) releaseInventory(ctx context.Context, event *OrderCreatedEvent) error {
-	log.Printf("🔓 [INVENTORY] Releasing inventory for order %s", event.OrderID)
+func (h *OrderFinalizerHandler) releaseInventory(ctx context.Context, orderID string, items []OrderItem) error {
+	log.Printf("🔓 [INVENTORY] Releasing inventory for order %s", orderID)
 
-	for _, item := range event.Items {
+	if h.stock != nil && len(items) > 0 {
+		errs, err := h.stock.Release(ctx, orderID, stockRequests(items))
+		if err != nil {
+			log.Printf("⚠️ [INVENTORY] Failed to release inventory for order %s: %v", orderID, err)
+			return nil
+		}
+		for i, err := range errs {
+			if err != nil {
+				log.Printf("⚠️ [INVENTORY] Failed to release inventory for SKU %s: %v", items[i].SKU, err)
+			}
+		}
+		return nil
+	}
+	for _, item := range items {
 		err := h.releaseItemInventory(ctx, item.HubID, item.SKU, item.Quantity)
 		if err != nil {
 			log.Printf("⚠️ [INVENTORY] Failed to release inventory for SKU %s: %v", item.SKU, err)
@@ -240,62 +507,29 @@ func (h *OrderFinalizerHandler) releaseInventory(ctx context.Context, event *Ord
 
 // releaseItemInventory releases inventory for a specific item
 func (h *OrderFinalizerHandler) releaseItemInventory(ctx context.Context, hubCode, skuCode string, quantity int) error {
-	url := fmt.Sprintf("%s/api/v1/inventory/release", h.imsBaseURL)
-
-	requestBody := map[string]interface{}{
-		"hub_code": hubCode,
-		"sku_code": skuCode,
-		"quantity": quantity,
-	}
-
-	body, err := json.Marshal(requestBody)
+	_, err := h.imsClient.ReleaseInventory(ctx, imsclient.StockRequest{HubCode: hubCode, SkuCode: skuCode, Quantity: quantity})
 	if err != nil {
-		return fmt.Errorf("failed to marshal release request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create release request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Tenant-ID", "default")
-
-	resp, err := h.httpClient.Do(req)
-	if err != nil {
-		// IMS unavailable, log and continue
-		log.Printf("⚠️ [INVENTORY] IMS unavailable for release, simulating success: %s/%s qty=%d", hubCode, skuCode, quantity)
-		return nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		var errorResp map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errorResp)
-		return fmt.Errorf("inventory release failed (status %d): %v", resp.StatusCode, errorResp)
+		return fmt.Errorf("inventory release failed: %w", err)
 	}
 
 	log.Printf("✅ [INVENTORY] Released %d units of %s at hub %s", quantity, skuCode, hubCode)
 	return nil
 }
 
-// rollbackPreviousReservations rolls back reservations made before a failure
-func (h *OrderFinalizerHandler) rollbackPreviousReservations(ctx context.Context, event *OrderCreatedEvent, failedItem OrderItem) {
-	log.Printf("🔄 [INVENTORY] Rolling back previous reservations for order %s", event.OrderID)
-
-	for _, item := range event.Items {
-		if item.SKU == failedItem.SKU && item.HubID == failedItem.HubID {
-			break // Stop at the failed item
-		}
-		h.releaseItemInventory(ctx, item.HubID, item.SKU, item.Quantity)
+// stockRequests returns the IMS stock requests for items
+func stockRequests(items []OrderItem) []imsclient.StockRequest {
+	requests := make([]imsclient.StockRequest, len(items))
+	for i, item := range items {
+		requests[i] = imsclient.StockRequest{HubCode: item.HubID, SkuCode: item.SKU, Quantity: item.Quantity}
 	}
+	return requests
 }
 
 // updateOrderStatus updates the order status in MongoDB
 func (h *OrderFinalizerHandler) updateOrderStatus(ctx context.Context, orderID, status, notes string) error {
 	log.Printf("📝 [ORDER] Updating order %s status: %s (%s)", orderID, status, notes)
 
-	err := orders.UpdateOrderStatus(orderID, status)
+	err := orders.UpdateOrderStatus(ctx, orderID, status)
 	if err != nil {
 		return fmt.Errorf("failed to update order status: %w", err)
 	}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"oms-service/internal/tenant"
+
 	gokafka "github.com/omniful/go_commons/kafka"
 	"github.com/omniful/go_commons/pubsub"
 )
@@ -22,6 +24,9 @@ type KafkaProducer struct {
 // Ensure KafkaProducer implements EventPublisher interface
 var _ EventPublisher = (*KafkaProducer)(nil)
 
+// Ensure KafkaProducer can republish failed messages
+var _ MessagePublisher = (*KafkaProducer)(nil)
+
 // NewKafkaProducer creates a new Kafka producer using go_commons with proper configuration
 func NewKafkaProducer(brokers []string) (*KafkaProducer, error) {
 	// Check if Kafka should be enabled
@@ -70,35 +75,17 @@ func (k *KafkaProducer) PublishOrderCreated(ctx context.Context, event *OrderCre
 		return nil
 	}
 
-	eventJSON, err := json.Marshal(event)
+	msg, err := NewOrderCreatedMessage(event, tenant.FromContext(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to marshal order event: %w", err)
-	}
-
-	// Get topic from environment variable
-	topic := os.Getenv("KAFKA_TOPIC")
-	if topic == "" {
-		topic = "order-events" // Default topic
+		return err
 	}
 
-	msg := &pubsub.Message{
-		Topic: topic,
-		Key:   event.CustomerID, // Use customer ID for FIFO ordering per customer
-		Value: eventJSON,
-		Headers: map[string]string{
-			"event_type":  "order.created",
-			"order_id":    event.OrderID,
-			"customer_id": event.CustomerID,
-			"created_at":  event.CreatedAt.Format(time.RFC3339),
-		},
-	}
-
-	log.Printf("📤 Publishing order.created event to topic '%s' for order: %s", topic, event.OrderID)
+	log.Printf("📤 Publishing order.created event to topic '%s' for order: %s", msg.Topic, event.OrderID)
 
 	err = k.producer.Publish(ctx, msg)
 	if err != nil {
 		log.Printf("❌ Failed to publish to Kafka: %v", err)
-		log.Printf("📤 [KAFKA FALLBACK] Event: %s", string(eventJSON))
+		log.Printf("📤 [KAFKA FALLBACK] Event: %s", string(msg.Value))
 		return fmt.Errorf("kafka publish failed: %w", err)
 	}
 
@@ -106,6 +93,38 @@ func (k *KafkaProducer) PublishOrderCreated(ctx context.Context, event *OrderCre
 	return nil
 }
 
+// NewOrderCreatedMessage builds the Kafka message for an order.created event of tenantID
+func NewOrderCreatedMessage(event *OrderCreatedEvent, tenantID string) (*pubsub.Message, error) {
+	// Use customer ID for FIFO ordering per customer
+	msg, err := NewEventMessage(EventOrderCreated, event.CustomerID, event.OrderID, tenantID, event)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Headers["order_id"] = event.OrderID
+	msg.Headers["customer_id"] = event.CustomerID
+	msg.Headers["created_at"] = event.CreatedAt.Format(time.RFC3339)
+	return msg, nil
+}
+
+// Publish publishes a raw message to topic, used to move messages between retry and dead-letter topics
+func (k *KafkaProducer) Publish(ctx context.Context, topic, key string, value []byte, headers map[string]string) error {
+	if !k.enabled {
+		return fmt.Errorf("kafka producer disabled, cannot publish to %s", topic)
+	}
+
+	msg := &pubsub.Message{
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: headers,
+	}
+	if err := k.producer.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("kafka publish to %s failed: %w", topic, err)
+	}
+	return nil
+}
+
 // Close closes the Kafka producer
 func (k *KafkaProducer) Close() error {
 	if k.enabled && k.producer != nil {
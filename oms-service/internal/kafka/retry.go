@@ -0,0 +1,318 @@
+package kafka
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/omniful/go_commons/pubsub"
+)
+
+// Headers used to route a failed message through the retry topics
+const (
+	HeaderRetryAttempt  = "retry_attempt"
+	HeaderOriginalTopic = "original_topic"
+	HeaderNotBefore     = "retry_not_before"
+	HeaderLastError     = "last_error"
+)
+
+// consumerHeaders are added by the consumer and never republished
+var consumerHeaders = map[string]bool{"partition": true, "offset": true, "timestamp": true}
+
+// RetryPolicy describes the retry topics a failed message passes through before it is dead-lettered.
+// Each delay gets its own topic, e.g. order-events.retry.1m, followed by order-events.dlq.
+type RetryPolicy struct {
+	Delays []time.Duration
+}
+
+// DefaultRetryPolicy reads retry delays from KAFKA_RETRY_DELAYS (comma separated durations),
+// defaulting to 1m and 10m
+func DefaultRetryPolicy() *RetryPolicy {
+	policy := &RetryPolicy{Delays: []time.Duration{time.Minute, 10 * time.Minute}}
+
+	delaysEnv := os.Getenv("KAFKA_RETRY_DELAYS")
+	if delaysEnv == "" {
+		return policy
+	}
+
+	var delays []time.Duration
+	for _, value := range strings.Split(delaysEnv, ",") {
+		delay, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || delay <= 0 {
+			log.Printf("⚠️ Invalid KAFKA_RETRY_DELAYS entry %q, using default retry delays", value)
+			return policy
+		}
+		delays = append(delays, delay)
+	}
+	policy.Delays = delays
+	return policy
+}
+
+// RetryTopics returns the retry topics for topic in the order they are used
+func (p *RetryPolicy) RetryTopics(topic string) []string {
+	topics := make([]string, len(p.Delays))
+	for i, delay := range p.Delays {
+		topics[i] = RetryTopic(topic, delay)
+	}
+	return topics
+}
+
+// RetryTopic names the retry topic of topic for delay
+func RetryTopic(topic string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", topic, formatDelay(delay))
+}
+
+// DeadLetterTopic names the dead-letter topic of topic
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+func formatDelay(delay time.Duration) string {
+	switch {
+	case delay%time.Hour == 0:
+		return fmt.Sprintf("%dh", delay/time.Hour)
+	case delay%time.Minute == 0:
+		return fmt.Sprintf("%dm", delay/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", delay/time.Second)
+	}
+}
+
+// MessagePublisher publishes raw messages, used to move failed messages between topics
+type MessagePublisher interface {
+	Publish(ctx context.Context, topic, key string, value []byte, headers map[string]string) error
+}
+
+// DeadLetter is a message that failed on every retry
+type DeadLetter struct {
+	Topic    string            `bson:"topic" json:"topic"`
+	DLQTopic string            `bson:"dlq_topic" json:"dlq_topic"`
+	Key      string            `bson:"key" json:"key"`
+	Value    string            `bson:"value" json:"value"`
+	Headers  map[string]string `bson:"headers" json:"headers"`
+	Attempts int               `bson:"attempts" json:"attempts"`
+	Error    string            `bson:"error" json:"error"`
+	FailedAt time.Time         `bson:"failed_at" json:"failed_at"`
+}
+
+// DeadLetterStore keeps dead-lettered messages so they can be inspected and replayed
+type DeadLetterStore interface {
+	SaveDeadLetter(ctx context.Context, letter *DeadLetter) error
+}
+
+// RepublishHeaders returns the headers of message without consumer and retry bookkeeping,
+// so it can be published again as a fresh message
+func RepublishHeaders(headers map[string]string) map[string]string {
+	clean := make(map[string]string, len(headers))
+	for key, value := range headers {
+		switch {
+		case consumerHeaders[key]:
+		case key == HeaderRetryAttempt, key == HeaderOriginalTopic, key == HeaderNotBefore, key == HeaderLastError:
+		default:
+			clean[key] = value
+		}
+	}
+	return clean
+}
+
+// retryAttempt returns how many retries a message has already been through
+func retryAttempt(message *pubsub.Message) int {
+	attempt, err := strconv.Atoi(message.Headers[HeaderRetryAttempt])
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// maxDelayedMessages caps the retry messages a partition holds while they wait to be due.
+// The partition is read no further until some of them are processed.
+const maxDelayedMessages = 1000
+
+// delayedMessage is a retry message waiting for its not-before time
+type delayedMessage struct {
+	message   *sarama.ConsumerMessage
+	notBefore time.Time
+	done      bool
+}
+
+// delayQueue orders delayed messages by not-before time, for container/heap
+type delayQueue []*delayedMessage
+
+func (q delayQueue) Len() int            { return len(q) }
+func (q delayQueue) Less(i, j int) bool  { return q[i].notBefore.Before(q[j].notBefore) }
+func (q delayQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *delayQueue) Push(x interface{}) { *q = append(*q, x.(*delayedMessage)) }
+func (q *delayQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// consumeRetryClaim processes the messages of a retry topic partition as each becomes due.
+// Messages not due yet are held in memory rather than waited on in turn, so no message
+// waits for one due later and the claim keeps reading. Offsets are only marked up to the
+// oldest message not processed yet, so what was still waiting is redelivered after a
+// restart or rebalance.
+func (h *ConsumerGroupHandler) consumeRetryClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	var (
+		waiting  delayQueue        // by not-before time
+		received []*delayedMessage // by offset, not marked yet
+	)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		messages := claim.Messages()
+		if len(waiting) >= maxDelayedMessages {
+			messages = nil
+		}
+		var due <-chan time.Time
+		if len(waiting) > 0 {
+			resetTimer(timer, time.Until(waiting[0].notBefore))
+			due = timer.C
+		}
+
+		select {
+		case message := <-messages:
+			if message == nil {
+				return nil
+			}
+			delayed := &delayedMessage{message: message, notBefore: notBefore(message)}
+			received = append(received, delayed)
+			heap.Push(&waiting, delayed)
+			if wait := time.Until(delayed.notBefore); wait > 0 {
+				log.Printf("⏳ Holding retry of message from %s at offset %d for %s",
+					message.Topic, message.Offset, wait.Round(time.Second))
+			}
+		case <-due:
+		case <-session.Context().Done():
+			return nil
+		case <-h.Context.Done():
+			return nil
+		}
+
+		for len(waiting) > 0 && !time.Now().Before(waiting[0].notBefore) {
+			delayed := heap.Pop(&waiting).(*delayedMessage)
+			if !h.processMessage(session, delayed.message) {
+				return nil
+			}
+			delayed.done = true
+		}
+		for len(received) > 0 && received[0].done {
+			session.MarkMessage(received[0].message, "")
+			received = received[1:]
+		}
+	}
+}
+
+// notBefore returns when a retry message is due, now if it does not say
+func notBefore(message *sarama.ConsumerMessage) time.Time {
+	for _, header := range message.Headers {
+		if header != nil && string(header.Key) == HeaderNotBefore {
+			if at, err := time.Parse(time.RFC3339Nano, string(header.Value)); err == nil {
+				return at
+			}
+		}
+	}
+	return time.Now()
+}
+
+// resetTimer makes timer fire after d, draining a fire that was not received
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// handleFailure moves a message that failed processing to the next retry topic, or to the
+// dead-letter topic and store once retries are exhausted. It returns false if the message
+// could not be handed off and must not be marked as consumed. Permanent failures skip the
+// retry topics.
+func (h *ConsumerGroupHandler) handleFailure(ctx context.Context, topic string, message *pubsub.Message, cause error) bool {
+	attempt := retryAttempt(message)
+	headers := RepublishHeaders(message.Headers)
+	headers[HeaderOriginalTopic] = topic
+	headers[HeaderLastError] = cause.Error()
+
+	if !IsPermanent(cause) && h.Retry != nil && h.Publisher != nil && attempt < len(h.Retry.Delays) {
+		delay := h.Retry.Delays[attempt]
+		retryTopic := RetryTopic(topic, delay)
+		headers[HeaderRetryAttempt] = strconv.Itoa(attempt + 1)
+		headers[HeaderNotBefore] = time.Now().Add(delay).Format(time.RFC3339Nano)
+
+		err := h.Publisher.Publish(ctx, retryTopic, message.Key, message.Value, headers)
+		if err == nil {
+			log.Printf("🔁 Scheduled retry %d of message from %s on %s", attempt+1, topic, retryTopic)
+			return true
+		}
+		log.Printf("❌ Failed to publish message to retry topic %s, dead-lettering: %v", retryTopic, err)
+	}
+
+	return h.deadLetter(ctx, topic, message, headers, attempt, cause)
+}
+
+// deadLetter publishes a message to the dead-letter topic and records it in the store
+func (h *ConsumerGroupHandler) deadLetter(ctx context.Context, topic string, message *pubsub.Message, headers map[string]string, attempt int, cause error) bool {
+	dlqTopic := DeadLetterTopic(topic)
+	headers[HeaderRetryAttempt] = strconv.Itoa(attempt)
+	delete(headers, HeaderNotBefore)
+
+	published := false
+	if h.Publisher != nil {
+		if err := h.Publisher.Publish(ctx, dlqTopic, message.Key, message.Value, headers); err != nil {
+			log.Printf("❌ Failed to publish message to dead-letter topic %s: %v", dlqTopic, err)
+		} else {
+			published = true
+		}
+	}
+
+	stored := false
+	if h.DeadLetters != nil {
+		letter := &DeadLetter{
+			Topic:    topic,
+			DLQTopic: dlqTopic,
+			Key:      message.Key,
+			Value:    string(message.Value),
+			Headers:  headers,
+			Attempts: attempt + 1,
+			Error:    cause.Error(),
+			FailedAt: time.Now(),
+		}
+		if err := h.DeadLetters.SaveDeadLetter(ctx, letter); err != nil {
+			log.Printf("❌ Failed to store dead letter from %s: %v", topic, err)
+		} else {
+			stored = true
+		}
+	}
+
+	if !published && !stored {
+		return false
+	}
+	log.Printf("☠️ Message from %s dead-lettered after %d attempts: %v", topic, attempt+1, cause)
+	return true
+}
+
+// messageHeaders converts Kafka record headers and metadata into pubsub headers
+func messageHeaders(message *sarama.ConsumerMessage) map[string]string {
+	headers := make(map[string]string, len(message.Headers)+3)
+	for _, header := range message.Headers {
+		if header != nil {
+			headers[string(header.Key)] = string(header.Value)
+		}
+	}
+	headers["partition"] = fmt.Sprintf("%d", message.Partition)
+	headers["offset"] = fmt.Sprintf("%d", message.Offset)
+	headers["timestamp"] = message.Timestamp.Format(time.RFC3339)
+	return headers
+}
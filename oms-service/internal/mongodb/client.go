@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -156,12 +157,16 @@ func (c *Client) InsertManyOrders(ctx context.Context, orders []interface{}) err
 func (c *Client) CreateIndexes(ctx context.Context) error {
 	indexes := []mongo.IndexModel{
 		{
-			Keys:    bson.D{{"order_id", 1}},
+			// Order IDs are chosen by each tenant, so only unique within one
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "order_id", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
 		{
 			Keys: bson.D{{"status", 1}},
 		},
+		{
+			Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "status", Value: 1}},
+		},
 		{
 			Keys: bson.D{{"customer_email", 1}},
 		},
@@ -170,7 +175,14 @@ func (c *Client) CreateIndexes(ctx context.Context) error {
 		},
 	}
 
-	_, err := c.collection.Indexes().CreateMany(ctx, indexes)
+	// Replace the index that made order IDs unique across tenants
+	_, err := c.collection.Indexes().DropOne(ctx, "order_id_1")
+	var cmdErr mongo.CommandError
+	if err != nil && !(errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)) {
+		return fmt.Errorf("failed to drop the order_id index: %w", err)
+	}
+
+	_, err = c.collection.Indexes().CreateMany(ctx, indexes)
 	if err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
@@ -0,0 +1,116 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Hold types. Holds are separate from the on_hold status, which means an order is waiting
+// for stock.
+const (
+	HoldFraudReview         = "fraud_review"
+	HoldAddressVerification = "address_verification"
+	HoldPaymentPending      = "payment_pending"
+	HoldManual              = "manual"
+)
+
+// ErrHoldNotFound is returned when an order has no active hold with the given ID
+var ErrHoldNotFound = errors.New("active hold not found")
+
+// Hold pauses an order until it is released. Holds stack, an order is blocked while any
+// of them is active.
+type Hold struct {
+	HoldID      string     `bson:"hold_id" json:"hold_id"`
+	Type        string     `bson:"type" json:"type"`
+	Reason      string     `bson:"reason" json:"reason"`
+	PlacedBy    string     `bson:"placed_by" json:"placed_by"`
+	PlacedAt    time.Time  `bson:"placed_at" json:"placed_at"`
+	ReleasedBy  string     `bson:"released_by,omitempty" json:"released_by,omitempty"`
+	ReleaseNote string     `bson:"release_note,omitempty" json:"release_note,omitempty"`
+	ReleasedAt  *time.Time `bson:"released_at,omitempty" json:"released_at,omitempty"`
+}
+
+// Active reports whether the hold has not been released
+func (h Hold) Active() bool {
+	return h.ReleasedAt == nil
+}
+
+// ValidHoldType reports whether holdType is one of the known hold types
+func ValidHoldType(holdType string) bool {
+	switch holdType {
+	case HoldFraudReview, HoldAddressVerification, HoldPaymentPending, HoldManual:
+		return true
+	}
+	return false
+}
+
+// ActiveHolds returns the holds of an order that have not been released
+func (o *Order) ActiveHolds() []Hold {
+	var active []Hold
+	for _, hold := range o.Holds {
+		if hold.Active() {
+			active = append(active, hold)
+		}
+	}
+	return active
+}
+
+// PlaceHold adds a hold to an order
+func PlaceHold(ctx context.Context, orderID, holdType, reason, placedBy string) (*Hold, error) {
+	if ordersCollection == nil {
+		return nil, fmt.Errorf("mongodb not initialized")
+	}
+
+	hold := &Hold{
+		HoldID:   "HLD-" + strings.ToUpper(uuid.NewString()[:8]),
+		Type:     holdType,
+		Reason:   reason,
+		PlacedBy: placedBy,
+		PlacedAt: time.Now(),
+	}
+	result, err := ordersCollection.UpdateOne(ctx, tenantQuery(ctx, bson.M{"order_id": orderID}), bson.M{
+		"$push": bson.M{"holds": hold},
+		"$set":  bson.M{"updated_at": hold.PlacedAt},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to place hold on order %s: %w", orderID, err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrOrderNotFound
+	}
+	return hold, nil
+}
+
+// ReleaseHold releases an active hold of an order
+func ReleaseHold(ctx context.Context, orderID, holdID, releasedBy, note string) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	now := time.Now()
+	result, err := ordersCollection.UpdateOne(ctx,
+		tenantQuery(ctx, bson.M{"order_id": orderID, "holds": bson.M{"$elemMatch": bson.M{"hold_id": holdID, "released_at": nil}}}),
+		bson.M{"$set": bson.M{
+			"holds.$[hold].released_at":  now,
+			"holds.$[hold].released_by":  releasedBy,
+			"holds.$[hold].release_note": note,
+			"updated_at":                 now,
+		}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"hold.hold_id": holdID}},
+		}))
+	if err != nil {
+		return fmt.Errorf("failed to release hold %s of order %s: %w", holdID, orderID, err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrHoldNotFound
+	}
+	return nil
+}
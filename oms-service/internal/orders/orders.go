@@ -2,9 +2,14 @@ package orders
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
+	"oms-service/internal/degraded"
+	"oms-service/internal/tenant"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,22 +18,103 @@ import (
 
 // Order represents an order in the system
 type Order struct {
-	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	OrderID         string             `bson:"order_id" json:"order_id"`
-	CustomerID      string             `bson:"customer_id" json:"customer_id"`
-	CustomerName    string             `bson:"customer_name" json:"customer_name"`
-	CustomerEmail   string             `bson:"customer_email" json:"customer_email"`
-	ProductName     string             `bson:"product_name" json:"product_name"`
-	ProductSKU      string             `bson:"product_sku" json:"product_sku"`
-	Quantity        int                `bson:"quantity" json:"quantity"`
-	UnitPrice       float64            `bson:"unit_price" json:"unit_price"`
-	TotalAmount     float64            `bson:"total_amount" json:"total_amount"`
-	HubID           string             `bson:"hub_id" json:"hub_id"`
-	ShippingAddress string             `bson:"shipping_address" json:"shipping_address"`
-	OrderDate       string             `bson:"order_date" json:"order_date"`
-	Status          string             `bson:"status" json:"status"`
-	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	OrderID         string              `bson:"order_id" json:"order_id"`
+	TenantID        string              `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	CustomerID      string              `bson:"customer_id" json:"customer_id"`
+	CustomerName    string              `bson:"customer_name" json:"customer_name"`
+	CustomerEmail   string              `bson:"customer_email" json:"customer_email"`
+	ProductName     string              `bson:"product_name" json:"product_name"`
+	ProductSKU      string              `bson:"product_sku" json:"product_sku"`
+	Quantity        int                 `bson:"quantity" json:"quantity"`
+	UnitPrice       float64             `bson:"unit_price" json:"unit_price"`
+	TotalAmount     float64             `bson:"total_amount" json:"total_amount"`
+	HubID           string              `bson:"hub_id" json:"hub_id"`
+	ShippingAddress string              `bson:"shipping_address" json:"shipping_address"`
+	OrderDate       string              `bson:"order_date" json:"order_date"`
+	Status          string              `bson:"status" json:"status"`
+	Lines           []OrderLine         `bson:"lines,omitempty" json:"lines,omitempty"`
+	Shipments       []Shipment          `bson:"shipments,omitempty" json:"shipments,omitempty"`
+	Holds           []Hold              `bson:"holds,omitempty" json:"holds,omitempty"`
+	Flags           []string            `bson:"flags,omitempty" json:"flags,omitempty"`
+	Degraded        []degraded.Decision `bson:"degraded,omitempty" json:"degraded,omitempty"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// OrderLine tracks how much of an order line is reserved in IMS and how much is backordered
+type OrderLine struct {
+	SKU         string  `bson:"sku" json:"sku"`
+	HubID       string  `bson:"hub_id" json:"hub_id"`
+	Quantity    int     `bson:"quantity" json:"quantity"`
+	UnitPrice   float64 `bson:"unit_price,omitempty" json:"unit_price,omitempty"`
+	Reserved    int     `bson:"reserved" json:"reserved"`
+	Backordered int     `bson:"backordered" json:"backordered"`
+}
+
+// Outstanding returns the quantity of the line that still has to be reserved
+func (l OrderLine) Outstanding() int {
+	return l.Quantity - l.Reserved
+}
+
+// StatusPartiallyAllocated is the status of an order with some of its quantity backordered
+const StatusPartiallyAllocated = "partially_allocated"
+
+// AllocationStatus derives the order status from how much of its lines is reserved
+func AllocationStatus(lines []OrderLine) string {
+	reserved, outstanding := 0, 0
+	for _, line := range lines {
+		reserved += line.Reserved
+		outstanding += line.Outstanding()
+	}
+
+	switch {
+	case outstanding == 0:
+		return "new_order"
+	case reserved > 0:
+		return StatusPartiallyAllocated
+	default:
+		return "on_hold"
+	}
+}
+
+// WithTenant returns ctx carrying the tenant that placed the order, so work done for the
+// order, such as IMS calls, is done for that tenant. Orders stored before tenants were
+// recorded keep the tenant of ctx.
+func (o *Order) WithTenant(ctx context.Context) context.Context {
+	if o.TenantID == "" {
+		return ctx
+	}
+	return tenant.WithID(ctx, o.TenantID)
+}
+
+// tenantQuery adds the tenant of ctx to query, so a tenant only finds and changes its own
+// orders. Orders stored before tenants were recorded belong to the default tenant.
+func tenantQuery(ctx context.Context, query bson.M) bson.M {
+	if tenantID := tenant.FromContext(ctx); tenantID == tenant.Default {
+		query["tenant_id"] = bson.M{"$in": []interface{}{tenantID, nil}}
+	} else {
+		query["tenant_id"] = tenantID
+	}
+	return query
+}
+
+// CurrentLines returns the lines of an order. Orders stored before lines were tracked have
+// a single line, fully reserved once the order left on_hold.
+func (o *Order) CurrentLines() []OrderLine {
+	if len(o.Lines) > 0 {
+		return append([]OrderLine(nil), o.Lines...)
+	}
+	if o.ProductSKU == "" || o.Quantity <= 0 {
+		return nil
+	}
+
+	line := OrderLine{SKU: o.ProductSKU, HubID: o.HubID, Quantity: o.Quantity, UnitPrice: o.UnitPrice}
+	if o.Status != "on_hold" && o.Status != "pending" && o.Status != "failed" {
+		line.Reserved = o.Quantity
+	}
+	line.Backordered = line.Outstanding()
+	return []OrderLine{line}
 }
 
 // OrderStats represents order statistics
@@ -63,6 +149,9 @@ func InitializeMongoDB(connectionURI string) error {
 
 	mongoClient = client
 	ordersCollection = client.Database("oms_database").Collection("orders")
+	if err := createIndexes(ctx); err != nil {
+		log.Printf("⚠️ Failed to create order indexes: %v", err)
+	}
 
 	log.Println("✅ MongoDB connected successfully")
 	log.Println("📊 Database: oms_database, Collection: orders")
@@ -70,11 +159,82 @@ func InitializeMongoDB(connectionURI string) error {
 	return nil
 }
 
+// createIndexes makes order IDs unique per tenant, replacing the index that made them
+// unique across tenants
+func createIndexes(ctx context.Context) error {
+	if _, err := ordersCollection.Indexes().DropOne(ctx, "order_id_1"); err != nil && !isIndexNotFound(err) {
+		return fmt.Errorf("failed to drop the order_id index: %w", err)
+	}
+	_, err := ordersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "order_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// isIndexNotFound reports whether err is MongoDB refusing to drop an index or collection
+// that does not exist
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27) // NamespaceNotFound, IndexNotFound
+}
+
 // CreateOrder creates a new order in MongoDB
 func CreateOrder(order *Order) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	prepareOrder(order)
+
+	// Insert the order
+	result, err := ordersCollection.InsertOne(ctx, order)
+	if err != nil {
+		log.Printf("❌ Failed to create order: %v", err)
+		return err
+	}
+
+	order.ID = result.InsertedID.(primitive.ObjectID)
+	log.Printf("✅ Order created successfully: %s", order.OrderID)
+
+	return nil
+}
+
+// CreateOrderInTransaction inserts an order and runs fn in the same MongoDB transaction.
+// Writes made by fn with the context it receives commit or abort together with the order.
+func CreateOrderInTransaction(ctx context.Context, order *Order, fn func(ctx context.Context) error) error {
+	if mongoClient == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	session, err := mongoClient.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start mongodb session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	prepareOrder(order)
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := ordersCollection.InsertOne(sessCtx, order)
+		if err != nil {
+			return nil, err
+		}
+		order.ID = result.InsertedID.(primitive.ObjectID)
+		return nil, fn(sessCtx)
+	})
+	if err != nil {
+		log.Printf("❌ Failed to create order %s: %v", order.OrderID, err)
+		return err
+	}
+
+	log.Printf("✅ Order created successfully: %s", order.OrderID)
+	return nil
+}
+
+// prepareOrder fills in timestamps and defaults before an order is inserted
+func prepareOrder(order *Order) {
 	// Set timestamps
 	order.CreatedAt = time.Now()
 	order.UpdatedAt = time.Now()
@@ -88,26 +248,14 @@ func CreateOrder(order *Order) error {
 	if order.Status == "" {
 		order.Status = "pending"
 	}
-
-	// Insert the order
-	result, err := ordersCollection.InsertOne(ctx, order)
-	if err != nil {
-		log.Printf("❌ Failed to create order: %v", err)
-		return err
-	}
-
-	order.ID = result.InsertedID.(primitive.ObjectID)
-	log.Printf("✅ Order created successfully: %s", order.OrderID)
-
-	return nil
 }
 
-// GetAllOrders retrieves all orders from MongoDB
-func GetAllOrders() ([]Order, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// GetAllOrders retrieves all orders of the tenant of ctx from MongoDB
+func GetAllOrders(ctx context.Context) ([]Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	cursor, err := ordersCollection.Find(ctx, bson.M{})
+	cursor, err := ordersCollection.Find(ctx, tenantQuery(ctx, bson.M{}))
 	if err != nil {
 		return nil, err
 	}
@@ -137,8 +285,8 @@ func GetOrderByID(orderID string) (*Order, error) {
 }
 
 // UpdateOrderStatus updates the status of an order
-func UpdateOrderStatus(orderID string, status string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func UpdateOrderStatus(ctx context.Context, orderID string, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	update := bson.M{
@@ -148,7 +296,7 @@ func UpdateOrderStatus(orderID string, status string) error {
 		},
 	}
 
-	_, err := ordersCollection.UpdateOne(ctx, bson.M{"order_id": orderID}, update)
+	_, err := ordersCollection.UpdateOne(ctx, tenantQuery(ctx, bson.M{"order_id": orderID}), update)
 	if err != nil {
 		log.Printf("❌ Failed to update order status: %v", err)
 		return err
@@ -158,19 +306,180 @@ func UpdateOrderStatus(orderID string, status string) error {
 	return nil
 }
 
-// GetOrderStats retrieves order statistics
-func GetOrderStats() (*OrderStats, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// ErrOrderNotFound is returned when the tenant has no order with the given ID
+var ErrOrderNotFound = errors.New("order not found")
+
+// GetOrder retrieves an order of the tenant of ctx by its ID. Orders of other tenants are
+// not found.
+func GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	if ordersCollection == nil {
+		return nil, fmt.Errorf("mongodb not initialized")
+	}
+
+	var order Order
+	err := ordersCollection.FindOne(ctx, tenantQuery(ctx, bson.M{"order_id": orderID})).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %s: %w", orderID, err)
+	}
+	return &order, nil
+}
+
+// ExistingOrderIDs returns which of orderIDs already have an order of the tenant of ctx
+func ExistingOrderIDs(ctx context.Context, orderIDs []string) (map[string]bool, error) {
+	if ordersCollection == nil {
+		return nil, fmt.Errorf("mongodb not initialized")
+	}
+
+	existing := make(map[string]bool)
+	if len(orderIDs) == 0 {
+		return existing, nil
+	}
+	values, err := ordersCollection.Distinct(ctx, "order_id", tenantQuery(ctx, bson.M{"order_id": bson.M{"$in": orderIDs}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing orders: %w", err)
+	}
+	for _, value := range values {
+		if orderID, ok := value.(string); ok {
+			existing[orderID] = true
+		}
+	}
+	return existing, nil
+}
+
+// UpdateAllocation records how the lines of an order are allocated, the shipments they are
+// reserved in and the resulting order status. It returns ErrOrderModified if the order
+// changed since it was read as order, as told by its status and updated_at, so of two
+// finalizations of the same order only the first records its reservations.
+func UpdateAllocation(ctx context.Context, order *Order, status string, lines []OrderLine, shipments []Shipment) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":     status,
+			"lines":      lines,
+			"shipments":  shipments,
+			"updated_at": time.Now(),
+		},
+	}
+	result, err := ordersCollection.UpdateOne(ctx,
+		tenantQuery(ctx, bson.M{"order_id": order.OrderID, "status": order.Status, "updated_at": order.UpdatedAt}), update)
+	if err != nil {
+		return fmt.Errorf("failed to update allocation of order %s: %w", order.OrderID, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to update allocation of order %s: %w", order.OrderID, ErrOrderModified)
+	}
+
+	log.Printf("✅ Order %s status updated to: %s", order.OrderID, status)
+	return nil
+}
+
+// ErrOrderModified is returned when an order changed after it was read
+var ErrOrderModified = errors.New("order was modified concurrently")
+
+// AmendInTransaction stores the amended lines, shipments, quantity, total and status of an
+// order and runs fn in the same transaction, so an event written by fn commits with the
+// amendment. It returns ErrOrderModified if the order changed since it was read, as told
+// by readAt, its updated_at at the time.
+func AmendInTransaction(ctx context.Context, order *Order, readAt time.Time, fn func(ctx context.Context) error) error {
+	if ordersCollection == nil || mongoClient == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	session, err := mongoClient.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start mongodb session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	order.UpdatedAt = time.Now()
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := ordersCollection.UpdateOne(sessCtx,
+			tenantQuery(ctx, bson.M{"order_id": order.OrderID, "updated_at": readAt}),
+			bson.M{"$set": bson.M{
+				"lines":        order.Lines,
+				"shipments":    order.Shipments,
+				"quantity":     order.Quantity,
+				"total_amount": order.TotalAmount,
+				"status":       order.Status,
+				"updated_at":   order.UpdatedAt,
+			}})
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, ErrOrderModified
+		}
+		return nil, fn(sessCtx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to amend order %s: %w", order.OrderID, err)
+	}
+
+	log.Printf("✅ Order %s amended, status: %s", order.OrderID, order.Status)
+	return nil
+}
+
+// FlagOnHoldOverdue marks an order that has waited on_hold for too long
+const FlagOnHoldOverdue = "on_hold_overdue"
+
+// FlagDegraded marks an order that was handled in degraded mode, see Order.Degraded
+const FlagDegraded = "degraded"
+
+// maxDegradedDecisions caps the decisions kept on an order, the latest are kept
+const maxDegradedDecisions = 20
+
+// AddFlag adds flag to the flags of an order, once. Flags and degraded decisions are notes
+// on an order and leave its updated_at alone, so they do not make a finalization or an
+// amendment running at the same time fail as a concurrent change.
+func AddFlag(ctx context.Context, orderID, flag string) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	update := bson.M{"$addToSet": bson.M{"flags": flag}}
+	if _, err := ordersCollection.UpdateOne(ctx, tenantQuery(ctx, bson.M{"order_id": orderID}), update); err != nil {
+		return fmt.Errorf("failed to flag order %s: %w", orderID, err)
+	}
+	return nil
+}
+
+// RecordDegraded flags an order as handled in degraded mode and keeps the decision on it.
+// Like AddFlag it leaves updated_at alone.
+func RecordDegraded(ctx context.Context, orderID string, decision degraded.Decision) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	update := bson.M{
+		"$addToSet": bson.M{"flags": FlagDegraded},
+		"$push":     bson.M{"degraded": bson.M{"$each": []degraded.Decision{decision}, "$slice": -maxDegradedDecisions}},
+	}
+	if _, err := ordersCollection.UpdateOne(ctx, tenantQuery(ctx, bson.M{"order_id": orderID}), update); err != nil {
+		return fmt.Errorf("failed to record degraded decision on order %s: %w", orderID, err)
+	}
+	return nil
+}
+
+// GetOrderStats retrieves order statistics of the tenant of ctx
+func GetOrderStats(ctx context.Context) (*OrderStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	// Count total orders
-	totalOrders, err := ordersCollection.CountDocuments(ctx, bson.M{})
+	totalOrders, err := ordersCollection.CountDocuments(ctx, tenantQuery(ctx, bson.M{}))
 	if err != nil {
 		return nil, err
 	}
 
 	// Calculate total revenue and average order
 	pipeline := []bson.M{
+		{"$match": tenantQuery(ctx, bson.M{})},
 		{
 			"$group": bson.M{
 				"_id":           nil,
@@ -0,0 +1,237 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Shipment statuses, in the order a shipment moves through them
+const (
+	ShipmentAllocated = "allocated"
+	ShipmentPicked    = "picked"
+	ShipmentPacked    = "packed"
+	ShipmentShipped   = "shipped"
+	ShipmentCancelled = "cancelled"
+)
+
+// ErrShipmentNotFound is returned when an order has no shipment with the given ID
+var ErrShipmentNotFound = errors.New("shipment not found")
+
+// ErrShipmentChanged is returned when a shipment moved on while it was being updated
+var ErrShipmentChanged = errors.New("shipment was modified concurrently")
+
+// Shipment is the part of an order fulfilled from one hub, with its own IMS reservations
+type Shipment struct {
+	ShipmentID     string          `bson:"shipment_id" json:"shipment_id"`
+	HubID          string          `bson:"hub_id" json:"hub_id"`
+	Status         string          `bson:"status" json:"status"`
+	Items          []ShipmentItem  `bson:"items" json:"items"`
+	Carrier        string          `bson:"carrier,omitempty" json:"carrier,omitempty"`
+	TrackingNumber string          `bson:"tracking_number,omitempty" json:"tracking_number,omitempty"`
+	ShippedAt      *time.Time      `bson:"shipped_at,omitempty" json:"shipped_at,omitempty"`
+	History        []ShipmentEvent `bson:"history,omitempty" json:"history,omitempty"`
+	CreatedAt      time.Time       `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+// ShipmentItem is a quantity of a SKU reserved for a shipment. Fulfilled counts the units
+// already deducted from IMS when the shipment left its hub.
+type ShipmentItem struct {
+	SKU       string `bson:"sku" json:"sku"`
+	Quantity  int    `bson:"quantity" json:"quantity"`
+	Fulfilled int    `bson:"fulfilled" json:"fulfilled"`
+}
+
+// ShipmentEvent records a status change of a shipment
+type ShipmentEvent struct {
+	From           string    `bson:"from" json:"from"`
+	To             string    `bson:"to" json:"to"`
+	Carrier        string    `bson:"carrier,omitempty" json:"carrier,omitempty"`
+	TrackingNumber string    `bson:"tracking_number,omitempty" json:"tracking_number,omitempty"`
+	At             time.Time `bson:"at" json:"at"`
+}
+
+// AddReservation adds quantity of sku reserved at hubID to the shipments of an order. It
+// goes to the hub's shipment while that is still only allocated, or to a new shipment.
+func AddReservation(shipments []Shipment, orderID, hubID, sku string, quantity int) []Shipment {
+	now := time.Now()
+	for i := range shipments {
+		shipment := &shipments[i]
+		if shipment.HubID != hubID || shipment.Status != ShipmentAllocated {
+			continue
+		}
+		shipment.UpdatedAt = now
+		for j := range shipment.Items {
+			if shipment.Items[j].SKU == sku {
+				shipment.Items[j].Quantity += quantity
+				return shipments
+			}
+		}
+		shipment.Items = append(shipment.Items, ShipmentItem{SKU: sku, Quantity: quantity})
+		return shipments
+	}
+
+	return append(shipments, Shipment{
+		ShipmentID: fmt.Sprintf("%s-S%d", orderID, len(shipments)+1),
+		HubID:      hubID,
+		Status:     ShipmentAllocated,
+		Items:      []ShipmentItem{{SKU: sku, Quantity: quantity}},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+}
+
+// TakeReservation removes quantity of sku from the allocated shipments of an order, latest
+// shipment first, cancelling shipments left empty. It returns the shipments and the quantity
+// taken from each hub, which is to be released in IMS.
+func TakeReservation(shipments []Shipment, sku string, quantity int) ([]Shipment, map[string]int) {
+	shipments = CloneShipments(shipments)
+	taken := make(map[string]int)
+	for i := len(shipments) - 1; i >= 0 && quantity > 0; i-- {
+		shipment := &shipments[i]
+		if shipment.Status != ShipmentAllocated {
+			continue
+		}
+		for j := range shipment.Items {
+			item := &shipment.Items[j]
+			if item.SKU != sku {
+				continue
+			}
+			take := min(item.Quantity, quantity)
+			item.Quantity -= take
+			quantity -= take
+			taken[shipment.HubID] += take
+		}
+	}
+
+	now := time.Now()
+	for i := range shipments {
+		shipment := &shipments[i]
+		var items []ShipmentItem
+		for _, item := range shipment.Items {
+			if item.Quantity > 0 {
+				items = append(items, item)
+			}
+		}
+		if len(items) == len(shipment.Items) {
+			continue
+		}
+		shipment.Items = items
+		shipment.UpdatedAt = now
+		if len(items) == 0 {
+			shipment.Status = ShipmentCancelled
+		}
+	}
+	return shipments, taken
+}
+
+// CloneShipments returns a copy of shipments that can be changed without touching the original
+func CloneShipments(shipments []Shipment) []Shipment {
+	cloned := make([]Shipment, len(shipments))
+	for i, shipment := range shipments {
+		shipment.Items = append([]ShipmentItem(nil), shipment.Items...)
+		shipment.History = append([]ShipmentEvent(nil), shipment.History...)
+		cloned[i] = shipment
+	}
+	return cloned
+}
+
+// CancelShipments cancels the shipments of an order that have not left their hub
+func CancelShipments(ctx context.Context, orderID string) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	now := time.Now()
+	_, err := ordersCollection.UpdateOne(ctx, tenantQuery(ctx, bson.M{"order_id": orderID}), bson.M{
+		"$set": bson.M{
+			"shipments.$[shipment].status":     ShipmentCancelled,
+			"shipments.$[shipment].updated_at": now,
+			"updated_at":                       now,
+		},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"shipment.status": bson.M{"$in": []string{
+			ShipmentAllocated, ShipmentPicked, ShipmentPacked,
+		}}}},
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to cancel shipments of order %s: %w", orderID, err)
+	}
+	return nil
+}
+
+// EnsureShipments stores shipments on an order that has none yet, for orders allocated
+// before shipments were recorded
+func EnsureShipments(ctx context.Context, orderID string, shipments []Shipment) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	_, err := ordersCollection.UpdateOne(ctx,
+		tenantQuery(ctx, bson.M{"order_id": orderID, "shipments.0": bson.M{"$exists": false}}),
+		bson.M{"$set": bson.M{"shipments": shipments, "updated_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to record shipments of order %s: %w", orderID, err)
+	}
+	return nil
+}
+
+// RecordFulfilled adds quantity to the units of sku fulfilled in IMS for a shipment
+func RecordFulfilled(ctx context.Context, orderID, shipmentID, sku string, quantity int) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	_, err := ordersCollection.UpdateOne(ctx, tenantQuery(ctx, bson.M{"order_id": orderID}), bson.M{
+		"$inc": bson.M{"shipments.$[shipment].items.$[item].fulfilled": quantity},
+	}, options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{
+			bson.M{"shipment.shipment_id": shipmentID},
+			bson.M{"item.sku": sku},
+		},
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to record fulfilment of %s in shipment %s: %w", sku, shipmentID, err)
+	}
+	return nil
+}
+
+// TransitionShipment moves a shipment from one status to another and appends the change to
+// its history. It returns ErrShipmentChanged if the shipment is no longer in status from.
+func TransitionShipment(ctx context.Context, orderID, shipmentID, from string, change ShipmentEvent) error {
+	if ordersCollection == nil {
+		return fmt.Errorf("mongodb not initialized")
+	}
+
+	change.From = from
+	set := bson.M{
+		"shipments.$.status":     change.To,
+		"shipments.$.updated_at": change.At,
+		"updated_at":             change.At,
+	}
+	if change.To == ShipmentShipped {
+		set["shipments.$.carrier"] = change.Carrier
+		set["shipments.$.tracking_number"] = change.TrackingNumber
+		set["shipments.$.shipped_at"] = change.At
+	}
+
+	result, err := ordersCollection.UpdateOne(ctx, tenantQuery(ctx, bson.M{
+		"order_id":  orderID,
+		"shipments": bson.M{"$elemMatch": bson.M{"shipment_id": shipmentID, "status": from}},
+	}), bson.M{
+		"$set":  set,
+		"$push": bson.M{"shipments.$.history": change},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update shipment %s: %w", shipmentID, err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrShipmentChanged
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Outbox entry statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed" // given up on after maxPublishAttempts, see Requeue
+)
+
+// maxPublishAttempts is how often the relay tries to publish an entry before parking it as
+// failed, so an entry that can never be published does not hold back the ones after it
+const maxPublishAttempts = 10
+
+// sentRetention is how long published entries are kept before MongoDB expires them
+const sentRetention = 7 * 24 * time.Hour
+
+// ErrNotInitialized is returned when MongoDB was not available at startup
+var ErrNotInitialized = errors.New("outbox not initialized")
+
+// Entry is an event waiting to be published, written in the same transaction as the
+// change that produced it
+type Entry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AggregateID string             `bson:"aggregate_id" json:"aggregate_id"`
+	EventType   string             `bson:"event_type" json:"event_type"`
+	Topic       string             `bson:"topic" json:"topic"`
+	Key         string             `bson:"key" json:"key"`
+	Payload     string             `bson:"payload" json:"payload"`
+	Headers     map[string]string  `bson:"headers" json:"headers"`
+	Status      string             `bson:"status" json:"status"`
+	Attempts    int                `bson:"attempts" json:"attempts"`
+	LastError   string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	SentAt      *time.Time         `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+}
+
+// Lag summarises how far the relay is behind
+type Lag struct {
+	Pending          int64      `json:"pending"`
+	Failed           int64      `json:"failed"`
+	OldestPendingAt  *time.Time `json:"oldest_pending_at,omitempty"`
+	OldestPendingAge float64    `json:"oldest_pending_age_seconds"`
+	OldestAttempts   int        `json:"oldest_pending_attempts"`
+	LastError        string     `json:"last_error,omitempty"`
+	LastSentAt       *time.Time `json:"last_sent_at,omitempty"`
+}
+
+var outboxCollection *mongo.Collection
+
+// Initialize sets up the outbox collection and its indexes
+func Initialize(client *mongo.Client) error {
+	if client == nil {
+		return ErrNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := client.Database("oms_database").Collection("outbox")
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "sent_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(sentRetention.Seconds())),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create outbox indexes: %w", err)
+	}
+
+	outboxCollection = collection
+	log.Println("📊 Database: oms_database, Collection: outbox")
+	return nil
+}
+
+// Insert adds a pending entry. Call it with the session context of the transaction that
+// writes the related change so both commit together.
+func Insert(ctx context.Context, entry *Entry) error {
+	if outboxCollection == nil {
+		return ErrNotInitialized
+	}
+
+	entry.Status = StatusPending
+	entry.CreatedAt = time.Now()
+
+	result, err := outboxCollection.InsertOne(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// pendingEntries returns up to limit pending entries in the order they were written
+func pendingEntries(ctx context.Context, limit int64) ([]Entry, error) {
+	cursor, err := outboxCollection.Find(ctx, bson.M{"status": StatusPending}, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []Entry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox entries: %w", err)
+	}
+	return entries, nil
+}
+
+// markSent records that an entry was published
+func markSent(ctx context.Context, id primitive.ObjectID) error {
+	_, err := outboxCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"status": StatusSent, "sent_at": time.Now()},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"last_error": ""},
+	})
+	return err
+}
+
+// markFailed records a failed publish attempt of entry. The entry stays pending until its
+// attempts reach maxPublishAttempts, then it is parked as failed; parked reports which.
+func markFailed(ctx context.Context, entry *Entry, cause error) (parked bool, err error) {
+	status := StatusPending
+	if entry.Attempts+1 >= maxPublishAttempts {
+		status = StatusFailed
+	}
+	_, err = outboxCollection.UpdateOne(ctx, bson.M{"_id": entry.ID, "status": StatusPending}, bson.M{
+		"$set": bson.M{"status": status, "last_error": cause.Error()},
+		"$inc": bson.M{"attempts": 1},
+	})
+	return status == StatusFailed, err
+}
+
+// Requeue makes entries parked as failed pending again with their attempts reset, once
+// what kept them from being published is fixed. It returns how many were requeued.
+func Requeue(ctx context.Context) (int64, error) {
+	if outboxCollection == nil {
+		return 0, ErrNotInitialized
+	}
+
+	result, err := outboxCollection.UpdateMany(ctx, bson.M{"status": StatusFailed}, bson.M{
+		"$set": bson.M{"status": StatusPending, "attempts": 0},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue outbox entries: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetLag reports the pending backlog, the entries parked as failed and the age of the oldest
+// unpublished entry
+func GetLag(ctx context.Context) (*Lag, error) {
+	if outboxCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	pending, err := outboxCollection.CountDocuments(ctx, bson.M{"status": StatusPending})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending outbox entries: %w", err)
+	}
+	failed, err := outboxCollection.CountDocuments(ctx, bson.M{"status": StatusFailed})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed outbox entries: %w", err)
+	}
+	lag := &Lag{Pending: pending, Failed: failed}
+
+	var oldest Entry
+	err = outboxCollection.FindOne(ctx, bson.M{"status": StatusPending},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}})).Decode(&oldest)
+	if err == nil {
+		lag.OldestPendingAt = &oldest.CreatedAt
+		lag.OldestPendingAge = time.Since(oldest.CreatedAt).Seconds()
+		lag.OldestAttempts = oldest.Attempts
+		lag.LastError = oldest.LastError
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to find oldest outbox entry: %w", err)
+	}
+
+	var lastSent Entry
+	err = outboxCollection.FindOne(ctx, bson.M{"status": StatusSent},
+		options.FindOne().SetSort(bson.D{{Key: "sent_at", Value: -1}})).Decode(&lastSent)
+	if err == nil {
+		lag.LastSentAt = lastSent.SentAt
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to find last sent outbox entry: %w", err)
+	}
+
+	return lag, nil
+}
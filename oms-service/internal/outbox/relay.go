@@ -0,0 +1,130 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// maxRelayBackoff caps how long the relay waits after a failed publish
+const maxRelayBackoff = time.Minute
+
+// outageFailures is how many entries in a row may fail to publish before a batch stops,
+// as Kafka is then more likely down than the entries unpublishable
+const outageFailures = 3
+
+// Publisher publishes a raw message to a topic
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, value []byte, headers map[string]string) error
+}
+
+// Relay publishes pending outbox entries in the order they were written. Entries are
+// marked sent only after a successful publish, so delivery is at-least-once. An entry that
+// fails holds back the later entries of its order only, and is parked as failed after
+// maxPublishAttempts.
+type Relay struct {
+	publisher Publisher
+	interval  time.Duration
+	batchSize int64
+}
+
+// NewRelay creates a relay that polls the outbox every interval
+func NewRelay(publisher Publisher, interval time.Duration, batchSize int) *Relay {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &Relay{publisher: publisher, interval: interval, batchSize: int64(batchSize)}
+}
+
+// Start runs the relay until ctx is cancelled
+func (r *Relay) Start(ctx context.Context) {
+	go func() {
+		log.Printf("📮 Outbox relay started (interval %s, batch %d)", r.interval, r.batchSize)
+		backoff := r.interval
+
+		for {
+			sent, err := r.relayBatch(ctx)
+			wait := r.interval
+			switch {
+			case err != nil && sent == 0:
+				log.Printf("❌ Outbox relay: %v (retrying in %s)", err, backoff)
+				wait = backoff
+				backoff *= 2
+				if backoff > maxRelayBackoff {
+					backoff = maxRelayBackoff
+				}
+			case err != nil:
+				// Events of other orders went out, so Kafka is up and only some entries fail
+				log.Printf("⚠️ Outbox relay: %v", err)
+				backoff = r.interval
+			case sent == int(r.batchSize):
+				// More entries are waiting, keep draining
+				backoff = r.interval
+				wait = 0
+			default:
+				backoff = r.interval
+			}
+
+			select {
+			case <-ctx.Done():
+				log.Println("📮 Outbox relay stopped")
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// relayBatch publishes one batch of pending entries. Once an entry fails, the later entries
+// of its aggregate are left for the next batch so the events of an order are never
+// published out of order, while those of other orders go on. It returns how many entries
+// it published and the first publish error.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	if outboxCollection == nil {
+		return 0, ErrNotInitialized
+	}
+
+	entries, err := pendingEntries(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var firstErr error
+	blocked := make(map[string]bool)
+	sent, failures := 0, 0
+	for _, entry := range entries {
+		if blocked[entry.AggregateID] {
+			continue
+		}
+		if err := r.publisher.Publish(ctx, entry.Topic, entry.Key, []byte(entry.Payload), entry.Headers); err != nil {
+			parked, markErr := markFailed(ctx, &entry, err)
+			switch {
+			case markErr != nil:
+				log.Printf("⚠️ Outbox relay could not record failure for %s: %v", entry.ID.Hex(), markErr)
+			case parked:
+				log.Printf("🛑 Outbox relay gave up on %s for %s after %d attempts: %v",
+					entry.EventType, entry.AggregateID, entry.Attempts+1, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			blocked[entry.AggregateID] = true
+			if failures++; failures >= outageFailures {
+				return sent, firstErr
+			}
+			continue
+		}
+		failures = 0
+
+		// A crash before this update republishes the entry, consumers must tolerate duplicates
+		if err := markSent(ctx, entry.ID); err != nil {
+			return sent, err
+		}
+		sent++
+		log.Printf("📤 Outbox relayed %s for %s to %s", entry.EventType, entry.AggregateID, entry.Topic)
+	}
+	return sent, firstErr
+}
@@ -4,22 +4,25 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
-	"oms-service/internal/ims"
+	"oms-service/internal/degraded"
 	"oms-service/internal/invalid"
 	"oms-service/internal/kafka"
 	"oms-service/internal/orders"
+	"oms-service/internal/outbox"
+	"oms-service/internal/quota"
 	"oms-service/internal/s3"
+	"oms-service/internal/tenant"
+	"oms-service/internal/validation"
 
-	commonscsv "github.com/omniful/go_commons/csv"
+	"github.com/omniful/ims-service/pkg/imsclient"
 )
 
 // Order represents a parsed order from CSV
@@ -38,96 +41,38 @@ type Order struct {
 	Status          string    `json:"status" bson:"status"`
 	CreatedAt       time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" bson:"updated_at"`
+
+	// Degraded is set when the order was accepted in degraded mode, see degraded.Mode
+	Degraded *degraded.Decision `json:"degraded,omitempty" bson:"degraded,omitempty"`
 }
 
-// ValidationResult holds validation results from IMS
+// ValidationResult holds validation results from IMS. The statuses are only known for
+// batch validation and tell an inactive SKU or hub apart from a missing one.
 type ValidationResult struct {
-	SKUValid bool   `json:"sku_valid"`
-	HubValid bool   `json:"hub_valid"`
-	Error    string `json:"error,omitempty"`
+	SKUValid  bool   `json:"sku_valid"`
+	HubValid  bool   `json:"hub_valid"`
+	SKUStatus string `json:"sku_status,omitempty"`
+	HubStatus string `json:"hub_status,omitempty"`
+	Error     string `json:"error,omitempty"`
 }
 
-// ProcessCSVFromS3 processes a CSV file from S3 using go_commons CSV and S3
-func ProcessCSVFromS3(ctx context.Context, bucket, key string) error {
-	log.Printf("Starting CSV processing: bucket=%s, key=%s", bucket, key)
-	// Create S3 client using go_commons
-	s3Client, err := s3.NewSimpleS3Client()
-	if err != nil {
-		log.Printf("Failed to create S3 client: %v, processing with mock data", err)
-		return processWithMockData(ctx, bucket, key)
-	}
-
-	// Verify S3 connectivity
-	err = s3Client.CreateBucket(ctx, bucket)
-	if err != nil {
-		log.Printf("S3 bucket verification failed: %v, processing with mock data", err)
-		return processWithMockData(ctx, bucket, key)
-	}
-
-	// Try to create go_commons CSV reader for S3
-	csvReader, err := commonscsv.NewCommonCSV(
-		commonscsv.WithBatchSize(100),
-		commonscsv.WithSource(commonscsv.S3),
-		commonscsv.WithFileInfo(bucket, key),
-		commonscsv.WithHeaderSanitizers(commonscsv.SanitizeAsterisks, commonscsv.SanitizeToLower),
-		commonscsv.WithDataRowSanitizers(commonscsv.SanitizeSpace, commonscsv.SanitizeToLower),
-	)
-	if err != nil {
-		log.Printf("Failed to create CSV reader: %v, processing with mock data", err)
-		return processWithMockData(ctx, bucket, key)
-	}
-
-	if err := csvReader.InitializeReader(ctx); err != nil {
-		log.Printf("S3 connection failed (%v), processing with mock data for demo", err)
-		return processWithMockData(ctx, bucket, key)
-	}
-
-	var totalProcessed, validOrders, invalidOrders int
-
-	log.Printf("Processing CSV file in batches of 100 records using go_commons...")
-
-	for !csvReader.IsEOF() {
-		records, err := csvReader.ReadNextBatch()
-		if err != nil {
-			return fmt.Errorf("csv batch read: %w", err)
-		}
+// ErrStorageUnavailable is returned when an uploaded file cannot be fetched from S3
+var ErrStorageUnavailable = errors.New("file storage unavailable")
 
-		// Convert go_commons CSV records to our format
-		convertedRecords := convertGoCommonsCSVRecords(records)
+// ProcessSimulatedUpload processes mock orders in place of a file S3 could not serve, for
+// DEGRADED_MODE=simulate. The orders are flagged with decision.
+func ProcessSimulatedUpload(ctx context.Context, bucket, key string, decision degraded.Decision) error {
+	log.Printf("🧪 Processing with mock data for file: %s/%s", bucket, key)
 
-		// Process each batch using our existing pipeline
-		batchValid, batchInvalid, err := processBatch(ctx, convertedRecords)
-		if err != nil {
-			log.Printf("Error processing batch: %v", err)
-			// Continue processing other batches
+	results := ingestRecords(ctx, createMockRecordsForFile(key, 10), &ingestOptions{degraded: &decision})
+	var validCount int
+	for _, result := range results {
+		if result.Accepted {
+			validCount++
 		}
-
-		validOrders += batchValid
-		invalidOrders += batchInvalid
-		totalProcessed += len(convertedRecords)
-
-		log.Printf("Processed batch: %d records, %d valid, %d invalid",
-			len(records), batchValid, batchInvalid)
-	}
-
-	log.Printf("CSV processing completed: %d total, %d valid, %d invalid",
-		totalProcessed, validOrders, invalidOrders)
-
-	return nil
-}
-
-// processWithMockData processes mock data when S3 connection fails
-func processWithMockData(ctx context.Context, bucket, key string) error {
-	log.Printf("Processing with mock data for file: %s/%s", bucket, key)
-
-	// Create mock data representing the CSV file
-	mockRecords := createMockRecordsForFile(key, 10) // Process 10 mock records
-	validCount, invalidCount, err := ProcessMockBatch(ctx, mockRecords)
-	if err != nil {
-		return fmt.Errorf("mock processing failed: %w", err)
 	}
 
-	log.Printf("Mock CSV processing completed: %d valid, %d invalid", validCount, invalidCount)
+	log.Printf("🧪 Mock CSV processing completed: %d valid, %d invalid", validCount, len(results)-validCount)
 	return nil
 }
 
@@ -157,248 +102,363 @@ func createMockRecordsForFile(filename string, count int) []map[string]interface
 	return records
 }
 
+// csvBatchSize is the number of rows handed to ingestRecords at a time
+const csvBatchSize = 100
+
 // ProcessCSVContentDirectly processes CSV content directly from memory
 func ProcessCSVContentDirectly(ctx context.Context, csvContent []byte, filename string) error {
 	log.Printf("Processing CSV content directly: %s (%d bytes)", filename, len(csvContent))
+	return ProcessCSVStream(ctx, bytes.NewReader(csvContent), filename)
+}
 
-	// Parse CSV content
-	csvReader := csv.NewReader(bytes.NewReader(csvContent))
+// ProcessCSVStream reads CSV rows from r and processes them in bounded batches,
+// so memory use does not grow with the size of the file. Saved rows count against the
+// tenant's daily row quota.
+func ProcessCSVStream(ctx context.Context, r io.Reader, filename string) error {
+	return processCSVStream(ctx, r, filename, ingestOptions{rowQuota: true})
+}
+
+// processCSVStream streams CSV rows in batches, handling each batch as opts ask
+func processCSVStream(ctx context.Context, r io.Reader, filename string, opts ingestOptions) error {
+	tracker := opts.tracker
+	log.Printf("Streaming CSV content: %s", filename)
+
+	csvReader := csv.NewReader(r)
 	csvReader.Comma = ','
 	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
 
-	// Read all records
-	records, err := csvReader.ReadAll()
+	// Read header row
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("CSV file is empty")
+	}
 	if err != nil {
-		return fmt.Errorf("failed to read CSV: %w", err)
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	headers := make([]string, len(header))
+	for i, h := range header {
+		headers[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	if tracker != nil {
+		tracker.setHeaders(headers)
+	}
+	if opts.errorReport != nil {
+		opts.errorReport.setHeaders(headers)
 	}
 
-	if len(records) == 0 {
-		return fmt.Errorf("CSV file is empty")
+	var totalRows, validOrders, invalidOrders int
+	rowNumber := 1 // the header is row 1
+	batch := make([]map[string]interface{}, 0, csvBatchSize)
+	batchRows := make([]int, 0, csvBatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		results := ingestRecords(ctx, batch, &opts)
+		for _, result := range results {
+			if result.Accepted {
+				validOrders++
+			} else {
+				invalidOrders++
+			}
+		}
+		if tracker != nil {
+			tracker.recordBatch(ctx, batch, batchRows, results)
+		}
+		if opts.report != nil {
+			opts.report.add(batchRows, results)
+		}
+		if opts.errorReport != nil {
+			opts.errorReport.write(batch, batchRows, results)
+		}
+		batch = make([]map[string]interface{}, 0, csvBatchSize)
+		batchRows = make([]int, 0, csvBatchSize)
 	}
 
-	// Skip header row and convert to map format
-	var processedRecords []map[string]interface{}
-	headers := records[0]
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("CSV processing cancelled after %d rows: %w", totalRows, err)
+		}
 
-	for i := 1; i < len(records); i++ {
-		record := records[i]
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNumber++
+		if err != nil {
+			flush()
+			return fmt.Errorf("failed to read CSV row %d: %w", rowNumber, err)
+		}
 		if len(record) == 0 {
 			continue
 		}
 
 		// Create record map
-		recordMap := make(map[string]interface{})
+		recordMap := make(map[string]interface{}, len(headers))
 		for j, header := range headers {
 			if j < len(record) {
-				recordMap[strings.ToLower(strings.TrimSpace(header))] = strings.TrimSpace(record[j])
+				recordMap[header] = strings.TrimSpace(record[j])
 			}
 		}
+		totalRows++
+		if opts.report != nil {
+			opts.report.TotalRows++
+		}
 
-		processedRecords = append(processedRecords, recordMap)
-	}
-
-	log.Printf("Parsed %d records from CSV", len(processedRecords))
+		if tracker != nil && !tracker.accept(rowNumber, recordMap) {
+			continue
+		}
 
-	// Process the records using existing logic
-	validCount, invalidCount, err := processBatch(ctx, processedRecords)
-	if err != nil {
-		return fmt.Errorf("failed to process batch: %w", err)
+		batch = append(batch, recordMap)
+		batchRows = append(batchRows, rowNumber)
+		if len(batch) >= csvBatchSize {
+			flush()
+		}
 	}
+	flush()
 
-	log.Printf("CSV processing completed: %d valid, %d invalid orders", validCount, invalidCount)
+	log.Printf("CSV processing completed: %d rows, %d valid, %d invalid orders", totalRows, validOrders, invalidOrders)
 	return nil
 }
 
-// ProcessCSVFromS3Real processes CSV file directly from S3 without go_commons fallback
+// ProcessCSVFromS3Real streams a CSV file from S3 through the pipeline. Failing to reach
+// S3 returns ErrStorageUnavailable, for the caller to apply the degraded mode.
 func ProcessCSVFromS3Real(ctx context.Context, bucket, key string) error {
 	log.Printf("🚀 [S3 Processor] Starting real S3 CSV processing: bucket=%s, key=%s", bucket, key)
 
 	// Create S3 client
 	s3Client, err := s3.NewSimpleS3Client()
 	if err != nil {
-		return fmt.Errorf("failed to create S3 client: %w", err)
+		return fmt.Errorf("%w: failed to create S3 client: %w", ErrStorageUnavailable, err)
 	}
 
-	// Download file content from S3
-	log.Printf("📥 [S3 Processor] Downloading file from S3: %s/%s", bucket, key)
-	fileContent, err := s3Client.Download(ctx, bucket, key)
+	// Stream file content from S3
+	log.Printf("📥 [S3 Processor] Streaming file from S3: %s/%s", bucket, key)
+	body, err := s3Client.DownloadStream(ctx, bucket, key)
 	if err != nil {
-		return fmt.Errorf("failed to download file from S3: %w", err)
+		return fmt.Errorf("%w: failed to download file from S3: %w", ErrStorageUnavailable, err)
 	}
+	defer body.Close()
 
-	log.Printf("✅ [S3 Processor] Downloaded %d bytes from S3", len(fileContent))
-
-	// Process the downloaded CSV content
-	return ProcessCSVContentDirectly(ctx, fileContent, key)
+	// Process the CSV content as it arrives. The upload was processed, and its rows counted
+	// against the quota, when it was received, so rows saved again here are not counted.
+	return processCSVStream(ctx, body, key, ingestOptions{})
 }
 
-// processBatch processes a batch of CSV records with optimized MongoDB batch insertion
-func processBatch(ctx context.Context, records []map[string]interface{}) (validCount, invalidCount int, err error) {
-	var validOrders []*Order
-	var kafkaEvents []*kafka.OrderCreatedEvent
-	// First pass: parse and validate all records
-	for _, record := range records {
-		order, err := parseOrderFromRecord(record)
-		if err != nil {
-			log.Printf("Failed to parse record: %v", err)
-			invalidCount++
-			continue
-		}
+// OrderResult reports whether a single order record was accepted by the pipeline
+type OrderResult struct {
+	Index    int                     `json:"index"`
+	OrderID  string                  `json:"order_id,omitempty"`
+	Accepted bool                    `json:"accepted"`
+	Skipped  bool                    `json:"skipped,omitempty"` // the order already exists, for replays
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+}
 
-		// Validate SKU and Hub via IMS APIs
-		valid, err := validateOrderWithIMS(ctx, order)
-		if err != nil {
-			log.Printf("⚠️  Validation error for order %s: %v", order.OrderID, err)
-			invalidCount++
-			continue
-		}
+// ProcessOrderRecords runs order records through the same validation, persistence and
+// event emission as CSV rows and reports the outcome of each record
+func ProcessOrderRecords(ctx context.Context, records []map[string]interface{}) []OrderResult {
+	return processRecords(ctx, records)
+}
 
-		if !valid.SKUValid || !valid.HubValid {
-			log.Printf("❌ Validation failed for order %s: SKU=%v, Hub=%v",
-				order.OrderID, valid.SKUValid, valid.HubValid)
-			invalidCount++
-			continue
-		}
+// processRecords parses, validates, saves and emits events for a batch of records
+func processRecords(ctx context.Context, records []map[string]interface{}) []OrderResult {
+	return ingestRecords(ctx, records, &ingestOptions{})
+}
 
-		// Set order status and timestamps
-		order.Status = "on_hold"
-		order.CreatedAt = time.Now()
-		order.UpdatedAt = time.Now()
+// ingestRecords runs a batch of records through the pipeline as opts ask, skipping orders
+// that already exist for replays and stopping after validation for dry runs
+func ingestRecords(ctx context.Context, records []map[string]interface{}, opts *ingestOptions) []OrderResult {
+	results := make([]OrderResult, len(records))
+	for i := range results {
+		results[i] = OrderResult{Index: i}
+	}
+	if opts.skipExisting {
+		opts.markExisting(ctx, records, results)
+	}
 
-		validOrders = append(validOrders, order)
-		// Prepare Kafka event
-		event := &kafka.OrderCreatedEvent{
-			OrderID:     order.OrderID,
-			CustomerID:  order.CustomerEmail,
-			TotalAmount: order.TotalAmount,
-			Status:      order.Status,
-			CreatedAt:   order.CreatedAt,
-			Items: []kafka.OrderItem{
-				{
-					SKU:         order.SKU,
-					ProductName: order.ProductName,
-					Quantity:    order.Quantity,
-					UnitPrice:   order.UnitPrice,
-					HubID:       order.HubID,
-				},
-			},
+	validOrders, validIndexes := validateRecords(ctx, records, results)
+	if opts.dryRun {
+		for n, order := range validOrders {
+			opts.accept(results, validIndexes[n], order.OrderID)
 		}
-		kafkaEvents = append(kafkaEvents, event)
+		return results
+	}
+	if opts.rowQuota {
+		validOrders, validIndexes = consumeRowQuota(ctx, results, validOrders, validIndexes)
 	}
 
-	// Batch save to MongoDB for better performance
-	if len(validOrders) > 0 {
-		savedCount, failedCount, err := SaveOrdersBatch(ctx, validOrders)
-		if err != nil {
-			log.Printf("❌ Batch MongoDB save failed: %v", err)
-			// Fall back to individual saves
-			for _, order := range validOrders {
-				err = saveOrderToMongoDB(ctx, order)
-				if err != nil {
-					log.Printf("❌ Individual save failed for order %s: %v", order.OrderID, err)
-					invalidCount++
-				} else {
-					validCount++
-				}
-			}
-		} else {
-			validCount += savedCount
-			invalidCount += failedCount
-		}
-		// Emit Kafka events for successfully saved orders
-		for i := range kafkaEvents {
-			if i < validCount { // Only emit for successfully saved orders
-				err = emitOrderCreatedEvent(ctx, validOrders[i])
-				if err != nil {
-					log.Printf("⚠️  Failed to emit Kafka event for order %s: %v", validOrders[i].OrderID, err)
-					// Don't fail the order for Kafka errors, just log
-				}
+	// Save each valid order together with its order.created outbox entry
+	saved := 0
+	for n, order := range validOrders {
+		i := validIndexes[n]
+		if order.Degraded == nil {
+			order.Degraded = opts.degraded
+		}
+		if err := saveOrderToMongoDB(ctx, order); err != nil {
+			log.Printf("❌ Failed to save order %s: %v", order.OrderID, err)
+			results[i].Errors = []validation.FieldError{
+				validation.NewFieldError("", validation.CodeSaveFailed, fmt.Sprintf("Failed to save order: %v", err)),
 			}
+			continue
 		}
-
-		log.Printf("Batch processed: %d valid orders saved, %d invalid", validCount, invalidCount)
+		opts.accept(results, i, order.OrderID)
+		saved++
 	}
+	if opts.rowQuota {
+		// Rows that failed to save, such as duplicates, do not count against the quota
+		quota.ReturnRows(ctx, tenant.FromContext(ctx), len(validOrders)-saved)
+	}
+
+	return results
+}
 
-	return validCount, invalidCount, nil
+// consumeRowQuota counts the valid orders against the tenant's daily row quota, so rows
+// rejected by validation cost nothing, and rejects those beyond it. Orders that then fail
+// to save are given back by ingestRecords. It returns the orders
+// granted and their indexes in results.
+func consumeRowQuota(ctx context.Context, results []OrderResult, orders []*Order, indexes []int) ([]*Order, []int) {
+	tenantID := tenant.FromContext(ctx)
+	granted, err := quota.ConsumeRows(ctx, tenantID, len(orders))
+	if err == nil {
+		return orders, indexes
+	}
+	log.Printf("⚠️ Tenant %s is over its upload quota, rejecting %d rows: %v", tenantID, len(orders)-granted, err)
+	for _, i := range indexes[granted:] {
+		results[i].Errors = []validation.FieldError{validation.NewFieldError("", validation.CodeQuotaExceeded, err.Error())}
+	}
+	return orders[:granted], indexes[:granted]
 }
 
-// ProcessMockBatch processes a mock batch for demonstration purposes
-func ProcessMockBatch(ctx context.Context, records []map[string]interface{}) (validCount, invalidCount int, err error) {
-	log.Printf("🔄 Processing mock batch of %d records...", len(records))
+// validateRecords parses and validates the records whose result is still open, recording
+// failures in results. SKUs and hubs are checked with one IMS call for the whole batch,
+// falling back to one call per order if that fails. Orders IMS cannot check are handled
+// as the degraded mode says. It returns the valid orders and their indexes in records.
+func validateRecords(ctx context.Context, records []map[string]interface{}, results []OrderResult) ([]*Order, []int) {
+	var parsed []*Order
+	var parsedIndexes []int
+
+	tenantID := tenant.FromContext(ctx)
+	rules := validation.RulesFor(ctx, tenantID)
 
+	// First pass: parse and validate all records
 	for i, record := range records {
-		log.Printf("📝 Processing record %d/%d: Order ID %v", i+1, len(records), record["order_id"])
+		if results[i].Skipped || len(results[i].Errors) > 0 {
+			continue
+		}
 
-		order, err := parseOrderFromRecord(record)
-		if err != nil {
-			log.Printf("❌ Failed to parse record: %v", err)
-			invalidCount++
+		if errs := rules.Validate(record); len(errs) > 0 {
+			log.Printf("❌ Record %v failed validation: %v", record["order_id"], errs)
+			results[i].Errors = errs
+			continue
+		}
+		// Rows may name their tenant, but only the tenant the upload belongs to
+		if rowTenant := recordTenantID(record); rowTenant != "" && rowTenant != tenantID {
+			log.Printf("❌ Record %v is for tenant %s, not %s", record["order_id"], rowTenant, tenantID)
+			results[i].Errors = []validation.FieldError{validation.NewFieldError("tenant_id", validation.CodeTenantMismatch,
+				fmt.Sprintf("tenant_id %s does not match the upload's tenant %s", rowTenant, tenantID))}
 			continue
 		}
-		// Validate SKU and Hub via IMS APIs (simulated)
-		valid, err := validateOrderWithIMS(ctx, order)
+
+		order, err := parseOrderFromRecord(record, rules)
 		if err != nil {
-			log.Printf("⚠️  Validation error for order %s: %v", order.OrderID, err)
+			log.Printf("Failed to parse record: %v", err)
+			results[i].Errors = validation.AsFieldErrors(err)
+			continue
+		}
+		results[i].OrderID = order.OrderID
+		parsed = append(parsed, order)
+		parsedIndexes = append(parsedIndexes, i)
+	}
+
+	// Second pass: validate SKU and Hub via IMS APIs
+	imsResults, batchErr := validateOrdersWithIMS(ctx, parsed)
+	if batchErr != nil && !imsclient.IsUnavailable(batchErr) {
+		log.Printf("⚠️ IMS batch validation failed, validating %d orders one by one: %v", len(parsed), batchErr)
+	}
+
+	// One degraded decision covers the batch, so an outage counts and alerts once per batch
+	var decision *degraded.Decision
+	degrade := func(cause error) *degraded.Decision {
+		if decision == nil {
+			d := degraded.Record(degraded.ComponentIMSValidation, cause)
+			decision = &d
+		}
+		return decision
+	}
 
-			// Log invalid record
-			logInvalidRecord(ctx, i+1, record, []string{fmt.Sprintf("Validation error: %v", err)}, "mock_batch.csv")
+	var validOrders []*Order
+	var validIndexes []int
+	for n, order := range parsed {
+		i := parsedIndexes[n]
 
-			invalidCount++
+		var valid *ValidationResult
+		var err error
+		switch {
+		case imsResults != nil:
+			valid = imsResults[n]
+		case imsclient.IsUnavailable(batchErr):
+			// Calling IMS again for every order would fail the same way
+			err = batchErr
+		default:
+			valid, err = validateOrderWithIMS(ctx, order)
+		}
+		if imsclient.IsUnavailable(err) {
+			order.Degraded = degrade(err)
+			switch order.Degraded.Mode {
+			case degraded.ModeQueue:
+				// Accepted unchecked, the finalizer holds it until IMS has the stock
+				log.Printf("⚠️  IMS unavailable, accepting order %s unvalidated: %v", order.OrderID, err)
+				valid, err = &ValidationResult{SKUValid: true, HubValid: true}, nil
+			case degraded.ModeSimulate:
+				log.Printf("🧪 IMS unavailable, simulating validation of order %s: %v", order.OrderID, err)
+				valid, err = simulateValidation(order), nil
+			}
+		}
+		if err != nil {
+			log.Printf("⚠️  Validation error for order %s: %v", order.OrderID, err)
+			results[i].Errors = []validation.FieldError{
+				validation.NewFieldError("", validation.CodeValidationUnavailable, fmt.Sprintf("Validation error: %v", err)),
+			}
 			continue
 		}
 
 		if !valid.SKUValid || !valid.HubValid {
 			log.Printf("❌ Validation failed for order %s: SKU=%v, Hub=%v",
 				order.OrderID, valid.SKUValid, valid.HubValid)
-
-			// Log invalid record with validation details
-			var errors []string
 			if !valid.SKUValid {
-				errors = append(errors, "Invalid SKU")
+				results[i].Errors = append(results[i].Errors, skuFieldError(valid.SKUStatus))
 			}
 			if !valid.HubValid {
-				errors = append(errors, "Invalid Hub")
-			}
-			if valid.Error != "" {
-				errors = append(errors, valid.Error)
+				results[i].Errors = append(results[i].Errors, hubFieldError(valid.HubStatus))
 			}
-
-			logInvalidRecord(ctx, i+1, record, errors, "mock_batch.csv")
-
-			invalidCount++
 			continue
 		}
 
-		// Set order status to on_hold
+		// Set order status and timestamps
 		order.Status = "on_hold"
 		order.CreatedAt = time.Now()
 		order.UpdatedAt = time.Now()
 
-		// Save to MongoDB using go_commons (placeholder)
-		err = saveOrderToMongoDB(ctx, order)
-		if err != nil {
-			log.Printf("❌ Failed to save order %s to MongoDB: %v", order.OrderID, err)
-			invalidCount++
-			continue
-		}
-		// Emit order.created Kafka event (disabled - Kafka not available)
-		// err = emitOrderCreatedEvent(ctx, order)
-		// if err != nil {
-		//	log.Printf("⚠️  Failed to emit Kafka event for order %s: %v", order.OrderID, err)
-		//	// Don't fail the order for Kafka errors, just log
-		// }
-		log.Printf("📤 Kafka event emission disabled (Kafka not available)")
-
-		validCount++
-		log.Printf("Successfully processed order: %s", order.OrderID)
+		validOrders = append(validOrders, order)
+		validIndexes = append(validIndexes, i)
 	}
 
-	return validCount, invalidCount, nil
+	return validOrders, validIndexes
+}
+
+// recordTenantID returns the trimmed tenant_id of a record, empty when the file has no such column
+func recordTenantID(record map[string]interface{}) string {
+	tenantID, _ := record["tenant_id"].(string)
+	return strings.TrimSpace(tenantID)
 }
 
 // parseOrderFromRecord converts CSV record to Order struct
-func parseOrderFromRecord(record map[string]interface{}) (*Order, error) {
+func parseOrderFromRecord(record map[string]interface{}, rules *validation.Rules) (*Order, error) {
 	order := &Order{}
+	var errs validation.Errors
 
 	// Extract and convert fields from CSV record
 	if val, ok := record["order_id"]; ok {
@@ -424,139 +484,182 @@ func parseOrderFromRecord(record map[string]interface{}) (*Order, error) {
 	}
 
 	// Parse date field
-	if val, ok := record["order_date"]; ok {
-		dateStr := fmt.Sprintf("%v", val)
-		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
-			order.OrderDate = date
+	if val, ok := record["order_date"]; ok && fmt.Sprintf("%v", val) != "" {
+		date, err := rules.ParseDate(fmt.Sprintf("%v", val))
+		if err != nil {
+			errs = append(errs, validation.NewFieldError("order_date", validation.CodeInvalidDate, err.Error()))
 		}
+		order.OrderDate = date
 	}
 
 	// Parse numeric fields
-	if val, ok := record["quantity"]; ok {
-		if qty := parseIntField(fmt.Sprintf("%v", val)); qty > 0 {
-			order.Quantity = qty
-		} else {
-			order.Quantity = 1 // Default quantity
+	order.Quantity = 1 // Default quantity
+	if val, ok := record["quantity"]; ok && fmt.Sprintf("%v", val) != "" {
+		qty, err := parseIntField(fmt.Sprintf("%v", val))
+		if err != nil {
+			errs = append(errs, validation.NewFieldError("quantity", validation.CodeInvalidFormat, err.Error()))
 		}
-	} else {
-		order.Quantity = 1 // Default quantity
+		order.Quantity = qty
 	}
 
-	if val, ok := record["unit_price"]; ok {
-		if price := parseFloatField(fmt.Sprintf("%v", val)); price > 0 {
-			order.UnitPrice = price
-		} else {
-			order.UnitPrice = 0.0 // Default price
+	if val, ok := record["unit_price"]; ok && fmt.Sprintf("%v", val) != "" {
+		price, err := parseFloatField(fmt.Sprintf("%v", val))
+		if err != nil {
+			errs = append(errs, validation.NewFieldError("unit_price", validation.CodeInvalidFormat, err.Error()))
 		}
+		order.UnitPrice = price
 	}
 
-	if val, ok := record["total_amount"]; ok {
-		if total := parseFloatField(fmt.Sprintf("%v", val)); total > 0 {
-			order.TotalAmount = total
-		} else {
-			// Calculate total if not provided
-			order.TotalAmount = order.UnitPrice * float64(order.Quantity)
+	// Calculate total if not provided
+	order.TotalAmount = order.UnitPrice * float64(order.Quantity)
+	if val, ok := record["total_amount"]; ok && fmt.Sprintf("%v", val) != "" {
+		total, err := parseFloatField(fmt.Sprintf("%v", val))
+		if err != nil {
+			errs = append(errs, validation.NewFieldError("total_amount", validation.CodeInvalidFormat, err.Error()))
 		}
-	} else {
-		// Calculate total if not provided
-		order.TotalAmount = order.UnitPrice * float64(order.Quantity)
+		order.TotalAmount = total
 	}
 
 	// Validate required fields
-	if order.OrderID == "" || order.SKU == "" || order.HubID == "" {
-		return nil, fmt.Errorf("missing required fields: order_id=%s, sku=%s, hub_id=%s",
-			order.OrderID, order.SKU, order.HubID)
+	if order.OrderID == "" {
+		errs = append(errs, validation.NewFieldError("order_id", validation.CodeRequired, "order_id is required"))
+	}
+	if order.SKU == "" {
+		errs = append(errs, validation.NewFieldError("sku", validation.CodeRequired, "sku is required"))
+	}
+	if order.HubID == "" {
+		errs = append(errs, validation.NewFieldError("hub_id", validation.CodeRequired, "hub_id is required"))
+	}
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
 	return order, nil
 }
 
-// parseIntField safely parses an integer field from string
-func parseIntField(value string) int {
-	value = strings.TrimSpace(value)
-	if value == "" {
-		return 0
-	}
+// parseIntField parses an integer field from string
+func parseIntField(value string) (int, error) {
+	return validation.ParseInt(value)
+}
 
-	if intVal, err := strconv.Atoi(value); err == nil {
-		return intVal
-	}
-	return 0
+// parseFloatField parses a float field from string
+func parseFloatField(value string) (float64, error) {
+	return validation.ParseFloat(value)
 }
 
-// parseFloatField safely parses a float field from string
-func parseFloatField(value string) float64 {
-	value = strings.TrimSpace(value)
-	if value == "" {
-		return 0.0
-	}
+// Global IMS client
+var imsClient *imsclient.Client
 
-	if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
-		return floatVal
-	}
-	return 0.0
+// InitializeIMSClient sets the IMS client orders are validated with
+func InitializeIMSClient(client *imsclient.Client) {
+	imsClient = client
 }
 
-// Global IMS client
-var imsClient *ims.Client
+// BatchValidator validates many order lines with one IMS call
+type BatchValidator interface {
+	ValidateBatch(ctx context.Context, lines []imsclient.ValidationLine) ([]imsclient.ValidationResult, error)
+}
 
-// InitializeIMSClient initializes the IMS client
-func InitializeIMSClient(baseURL string) {
-	imsClient = ims.NewClient(baseURL)
+var batchValidator BatchValidator
+
+// InitializeBatchValidator sets what CSV batches are validated with, such as the IMS gRPC
+// client. The IMS client is used when none is set.
+func InitializeBatchValidator(validator BatchValidator) {
+	batchValidator = validator
 }
 
-// validateOrderWithIMS validates SKU and Hub via IMS service APIs
-func validateOrderWithIMS(ctx context.Context, order *Order) (*ValidationResult, error) {
-	// Initialize IMS client if not already done
+// getIMSClient returns the IMS client, creating one for IMS_SERVICE_URL if none was set
+func getIMSClient() *imsclient.Client {
 	if imsClient == nil {
-		// Use default IMS service URL (should be configurable via env var)
 		imsURL := os.Getenv("IMS_SERVICE_URL")
 		if imsURL == "" {
 			imsURL = "http://localhost:8081" // Default for local development
 		}
-		InitializeIMSClient(imsURL)
+		imsClient = imsclient.New(imsclient.Config{BaseURL: imsURL, TenantFromContext: tenant.FromContext})
 	}
+	return imsClient
+}
 
-	// Use the IMS client to validate the order
-	validation, err := imsClient.ValidateOrder(ctx, order.SKU, order.HubID)
+// validateOrderWithIMS validates SKU and Hub via IMS service APIs. It returns an error
+// when IMS cannot be reached, the order is then handled as the degraded mode says.
+func validateOrderWithIMS(ctx context.Context, order *Order) (*ValidationResult, error) {
+	result, err := getIMSClient().Validate(ctx, imsclient.ValidationLine{SKU: order.SKU, HubID: order.HubID})
 	if err != nil {
-		// If IMS service is not available, use permissive validation for testing
-		log.Printf("⚠️ IMS validation failed, using permissive validation: %v", err)
-		return &ValidationResult{
-			SKUValid: !strings.HasPrefix(order.SKU, "INVALID"),
-			HubValid: !strings.HasPrefix(order.HubID, "INVALID"),
-			Error:    "",
-		}, nil
+		return nil, err
 	}
+	return toValidationResult(result), nil
+}
 
-	return &ValidationResult{
-		SKUValid: validation.SKUValid,
-		HubValid: validation.HubValid,
-		Error:    validation.Error,
-	}, nil
+// simulateValidation accepts any SKU or hub not starting with INVALID, standing in for IMS
+// when DEGRADED_MODE=simulate
+func simulateValidation(order *Order) *ValidationResult {
+	result := &ValidationResult{
+		SKUValid:  !strings.HasPrefix(strings.ToUpper(order.SKU), "INVALID"),
+		HubValid:  !strings.HasPrefix(strings.ToUpper(order.HubID), "INVALID"),
+		SKUStatus: imsclient.StatusActive,
+		HubStatus: imsclient.StatusActive,
+	}
+	if !result.SKUValid {
+		result.SKUStatus = imsclient.StatusMissing
+	}
+	if !result.HubValid {
+		result.HubStatus = imsclient.StatusMissing
+	}
+	return result
 }
 
-// validateSKUWithIMS validates SKU against IMS service
-func validateSKUWithIMS(ctx context.Context, sku string) (bool, error) {
-	// IMS service URL (should be configurable)
-	imsURL := "http://localhost:8081/api/v1/skus/" + sku
+// validateOrdersWithIMS validates the SKUs and hubs of a batch of orders with one IMS call,
+// returning one result per order
+func validateOrdersWithIMS(ctx context.Context, orders []*Order) ([]*ValidationResult, error) {
+	if len(orders) == 0 {
+		return nil, nil
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", imsURL, nil)
+	lines := make([]imsclient.ValidationLine, len(orders))
+	for i, order := range orders {
+		lines[i] = imsclient.ValidationLine{SKU: order.SKU, HubID: order.HubID}
+	}
+	var validator BatchValidator = getIMSClient()
+	if batchValidator != nil {
+		validator = batchValidator
+	}
+	validations, err := validator.ValidateBatch(ctx, lines)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("⚠️  IMS SKU API not available, simulating validation for %s", sku)
-		// Simulate validation - accept SKUs that don't start with 'INVALID'
-		return !strings.HasPrefix(sku, "INVALID"), nil
+	results := make([]*ValidationResult, len(validations))
+	for i := range validations {
+		results[i] = toValidationResult(&validations[i])
 	}
-	defer resp.Body.Close()
+	return results, nil
+}
+
+// toValidationResult converts an IMS verdict to a ValidationResult
+func toValidationResult(v *imsclient.ValidationResult) *ValidationResult {
+	return &ValidationResult{
+		SKUValid:  v.SKUValid,
+		HubValid:  v.HubValid,
+		SKUStatus: v.SKUStatus,
+		HubStatus: v.HubStatus,
+		Error:     strings.Join(v.Errors, "; "),
+	}
+}
+
+// skuFieldError describes a SKU rejected by IMS, telling an inactive SKU from a missing one
+func skuFieldError(status string) validation.FieldError {
+	if status == imsclient.StatusInactive {
+		return validation.NewFieldError("sku", validation.CodeInactiveSKU, "SKU is inactive")
+	}
+	return validation.NewFieldError("sku", validation.CodeUnknownSKU, "Invalid SKU")
+}
 
-	// SKU is valid if found (200) or accept others for demo
-	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound, nil
+// hubFieldError describes a hub rejected by IMS, telling an inactive hub from a missing one
+func hubFieldError(status string) validation.FieldError {
+	if status == imsclient.StatusInactive {
+		return validation.NewFieldError("hub_id", validation.CodeInactiveHub, "Hub is inactive")
+	}
+	return validation.NewFieldError("hub_id", validation.CodeUnknownHub, "Invalid Hub")
 }
 
 // Global Kafka producer
@@ -592,6 +695,7 @@ func saveOrderToMongoDB(ctx context.Context, order *Order) error {
 	// Convert processor Order to orders.Order
 	ordersOrder := &orders.Order{
 		OrderID:         order.OrderID,
+		TenantID:        tenant.FromContext(ctx),
 		CustomerName:    order.CustomerName,
 		CustomerEmail:   order.CustomerEmail,
 		ProductName:     order.ProductName,
@@ -604,9 +708,19 @@ func saveOrderToMongoDB(ctx context.Context, order *Order) error {
 		OrderDate:       order.OrderDate.Format("2006-01-02"),
 		Status:          order.Status,
 	}
+	if order.Degraded != nil {
+		ordersOrder.Flags = []string{orders.FlagDegraded}
+		ordersOrder.Degraded = []degraded.Decision{*order.Degraded}
+	}
 
-	// Save order using the orders package
-	err := orders.CreateOrder(ordersOrder)
+	// Save the order and its order.created outbox entry atomically, the outbox relay publishes the event
+	err := orders.CreateOrderInTransaction(ctx, ordersOrder, func(txCtx context.Context) error {
+		entry, err := newOrderCreatedEntry(order, tenant.FromContext(ctx))
+		if err != nil {
+			return err
+		}
+		return outbox.Insert(txCtx, entry)
+	})
 	if err != nil {
 		log.Printf("❌ MongoDB save failed for order %s: %v", order.OrderID, err)
 		return fmt.Errorf("mongodb save failed: %w", err)
@@ -616,9 +730,8 @@ func saveOrderToMongoDB(ctx context.Context, order *Order) error {
 	return nil
 }
 
-// emitOrderCreatedEvent emits order.created event to Kafka
-func emitOrderCreatedEvent(ctx context.Context, order *Order) error {
-	// Use the real Kafka producer type from producer.go
+// newOrderCreatedEntry builds the outbox entry for an order's order.created event of tenantID
+func newOrderCreatedEntry(order *Order, tenantID string) (*outbox.Entry, error) {
 	event := &kafka.OrderCreatedEvent{
 		OrderID:     order.OrderID,
 		CustomerID:  order.CustomerEmail, // Using email as customer ID for demo
@@ -636,102 +749,24 @@ func emitOrderCreatedEvent(ctx context.Context, order *Order) error {
 		},
 	}
 
-	// Check if Kafka producer is initialized
-	if kafkaProducer == nil {
-		log.Printf("⚠️  Kafka producer not initialized, attempting to initialize...")
-		if err := InitializeKafka(); err != nil {
-			// If Kafka is not available, just log the event for demo
-			eventJSON, _ := json.Marshal(event)
-			log.Printf("📤 [KAFKA UNAVAILABLE] Would emit order.created event: %s", string(eventJSON))
-			return nil
-		}
-	}
-
-	// Publish the event using the real Kafka producer
-	err := kafkaProducer.PublishOrderCreated(ctx, event)
+	msg, err := kafka.NewOrderCreatedMessage(event, tenantID)
 	if err != nil {
-		log.Printf("❌ Failed to publish Kafka event for order %s: %v", order.OrderID, err)
-		// Don't fail the order processing for Kafka errors
-		eventJSON, _ := json.Marshal(event)
-		log.Printf("📤 [KAFKA FALLBACK] Event payload: %s", string(eventJSON))
-		return nil
-	}
-
-	log.Printf("Successfully published order.created event for order: %s", order.OrderID)
-	return nil
-}
-
-// convertCSVRecords converts standard CSV records to our map format
-func convertCSVRecords(records [][]string) []map[string]interface{} {
-	var result []map[string]interface{}
-
-	// For demonstration, create mock records since we can't access S3 yet
-	// In real implementation, this would process the actual CSV records
-	for i := 0; i < len(records); i++ {
-		recordMap := map[string]interface{}{
-			"order_id":         fmt.Sprintf("ORD%04d", i+1),
-			"customer_name":    "Test Customer",
-			"customer_email":   fmt.Sprintf("customer%d@example.com", i+1),
-			"product_name":     "Test Product",
-			"sku":              "TEST-SKU-001",
-			"hub_id":           "HUB001",
-			"quantity":         "1",
-			"unit_price":       "99.99",
-			"total_amount":     "99.99",
-			"order_date":       "2024-01-01",
-			"shipping_address": "123 Test St",
-			"status":           "pending",
-		}
-		result = append(result, recordMap)
-	}
-
-	return result
-}
-
-// convertGoCommonsCSVRecords converts go_commons CSV records to our map format
-func convertGoCommonsCSVRecords(records commonscsv.Records) []map[string]interface{} {
-	var result []map[string]interface{}
-
-	// Process the actual go_commons CSV records
-	for i, record := range records {
-		if len(record) == 0 {
-			continue
-		}
-
-		// Create a map from the record data
-		// Assuming standard CSV columns for orders
-		recordMap := map[string]interface{}{
-			"order_id":         getFieldSafely(record, 0, fmt.Sprintf("ORD%04d", i+1)),
-			"customer_name":    getFieldSafely(record, 1, "Unknown Customer"),
-			"customer_email":   getFieldSafely(record, 2, fmt.Sprintf("customer%d@example.com", i+1)),
-			"product_name":     getFieldSafely(record, 3, "Unknown Product"),
-			"sku":              getFieldSafely(record, 4, fmt.Sprintf("SKU-%03d", i+1)),
-			"hub_id":           getFieldSafely(record, 5, fmt.Sprintf("HUB%03d", i+1)),
-			"quantity":         getFieldSafely(record, 6, "1"),
-			"unit_price":       getFieldSafely(record, 7, "99.99"),
-			"total_amount":     getFieldSafely(record, 8, "99.99"),
-			"order_date":       getFieldSafely(record, 9, "2024-01-01"),
-			"shipping_address": getFieldSafely(record, 10, "123 Test St"),
-			"status":           getFieldSafely(record, 11, "pending"),
-		}
-		result = append(result, recordMap)
-	}
-
-	return result
-}
-
-// getFieldSafely gets a field from a record safely with a default value
-func getFieldSafely(record []string, index int, defaultValue string) string {
-	if index < len(record) && record[index] != "" {
-		return record[index]
-	}
-	return defaultValue
+		return nil, err
+	}
+	return &outbox.Entry{
+		AggregateID: order.OrderID,
+		EventType:   kafka.EventOrderCreated,
+		Topic:       msg.Topic,
+		Key:         msg.Key,
+		Payload:     string(msg.Value),
+		Headers:     msg.Headers,
+	}, nil
 }
 
 // GetMongoDBStats returns MongoDB statistics using the orders package
 func GetMongoDBStats(ctx context.Context) (map[string]interface{}, error) {
 	// Get stats from orders package
-	stats, err := orders.GetOrderStats()
+	stats, err := orders.GetOrderStats(ctx)
 	if err != nil {
 		return map[string]interface{}{
 			"status":  "error",
@@ -741,7 +776,7 @@ func GetMongoDBStats(ctx context.Context) (map[string]interface{}, error) {
 	}
 
 	// Get all orders to calculate status breakdown
-	allOrders, err := orders.GetAllOrders()
+	allOrders, err := orders.GetAllOrders(ctx)
 	if err != nil {
 		log.Printf("⚠️  Failed to get orders for status breakdown: %v", err)
 		return map[string]interface{}{
@@ -829,28 +864,6 @@ func GetInvalidFilePath(filename string) (string, error) {
 	return invalidLogger.GetFilePath(filename)
 }
 
-// logInvalidRecord logs an invalid record to CSV
-func logInvalidRecord(ctx context.Context, rowNumber int, data map[string]interface{}, errors []string, originalFile string) {
-	if invalidLogger == nil {
-		if err := InitializeInvalidLogger(); err != nil {
-			log.Printf("⚠️  Failed to initialize invalid logger: %v", err)
-			return
-		}
-	}
-
-	invalidRecord := &invalid.InvalidRecord{
-		RowNumber:    rowNumber,
-		Data:         data,
-		Errors:       errors,
-		Timestamp:    time.Now(),
-		OriginalFile: originalFile,
-	}
-
-	if err := invalidLogger.LogInvalidRecord(ctx, invalidRecord); err != nil {
-		log.Printf("⚠️  Failed to log invalid record: %v", err)
-	}
-}
-
 // OrderStats represents statistics about processed orders
 type OrderStats struct {
 	TotalOrders       int64   `json:"total_orders"`
@@ -864,7 +877,7 @@ type OrderStats struct {
 // GetOrderStats retrieves order statistics using the orders package
 func GetOrderStats(ctx context.Context) (*OrderStats, error) {
 	// Get stats from orders package
-	stats, err := orders.GetOrderStats()
+	stats, err := orders.GetOrderStats(ctx)
 	if err != nil {
 		// Return simulated stats if orders package fails
 		return &OrderStats{
@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"oms-service/internal/jobs"
+	"oms-service/internal/validation"
+)
+
+// errorReport writes rejected rows as CSV in the layout of a correction template: the
+// source row, the original columns and the errors of the row
+type errorReport struct {
+	writer  *csv.Writer
+	headers []string
+	err     error
+}
+
+// newErrorReport creates an error report written to w
+func newErrorReport(w io.Writer) *errorReport {
+	return &errorReport{writer: csv.NewWriter(w)}
+}
+
+// setHeaders writes the header row for the CSV columns of the file
+func (e *errorReport) setHeaders(headers []string) {
+	e.headers = jobs.TemplateHeaders(headers)
+	e.check(e.writer.Write(append(append([]string{jobs.SourceRowColumn}, e.headers...), jobs.ErrorsColumn)))
+}
+
+// write adds the rejected rows of a batch
+func (e *errorReport) write(batch []map[string]interface{}, rowNumbers []int, results []OrderResult) {
+	for i, result := range results {
+		if result.Accepted || result.Skipped {
+			continue
+		}
+		data := recordData(batch[i])
+		record := make([]string, 0, len(e.headers)+2)
+		record = append(record, strconv.Itoa(rowNumbers[i]))
+		for _, header := range e.headers {
+			record = append(record, data[header])
+		}
+		record = append(record, strings.Join(validation.Messages(result.Errors), "; "))
+		e.check(e.writer.Write(record))
+	}
+}
+
+// flush writes buffered rows and returns the first write error
+func (e *errorReport) flush() error {
+	e.writer.Flush()
+	e.check(e.writer.Error())
+	return e.err
+}
+
+// check keeps the first write error
+func (e *errorReport) check(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// DryRunCSV parses, maps and validates every row of r as an upload would, checking SKUs
+// and hubs with one IMS call per batch, without writing orders, events or invalid
+// records. Rejected rows are written to rejected as CSV with their errors.
+func DryRunCSV(ctx context.Context, r io.Reader, filename string, rejected io.Writer) (*IngestReport, error) {
+	log.Printf("🧪 Dry run of %s", filename)
+	rejectedRows := newErrorReport(rejected)
+	report := &IngestReport{DryRun: true, Rows: []RowReport{}}
+
+	err := processCSVStream(ctx, r, filename, ingestOptions{
+		report:      report,
+		dryRun:      true,
+		errorReport: rejectedRows,
+	})
+	if flushErr := rejectedRows.flush(); err == nil {
+		err = flushErr
+	}
+	return report, err
+}
@@ -0,0 +1,175 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"oms-service/internal/degraded"
+	"oms-service/internal/jobs"
+	"oms-service/internal/orders"
+	"oms-service/internal/s3"
+	"oms-service/internal/validation"
+)
+
+// Row outcomes reported when a file is re-ingested
+const (
+	OutcomeCreated  = "created"
+	OutcomeSkipped  = "skipped" // an order with the same ID already exists
+	OutcomeRejected = "rejected"
+)
+
+// maxReportRows caps how many skipped and rejected rows a report lists
+const maxReportRows = 1000
+
+// ingestOptions change how processCSVStream handles rows
+type ingestOptions struct {
+	tracker      *jobTracker        // records row outcomes against an upload job
+	report       *IngestReport      // counts row outcomes
+	dryRun       bool               // validate only, no orders or events are written
+	skipExisting bool               // rows for orders that already exist are skipped
+	errorReport  *errorReport       // receives rejected rows with their errors
+	degraded     *degraded.Decision // flags every order created, for simulated uploads
+	rowQuota     bool               // saved rows count against the tenant's daily upload quota
+	accepted     map[string]bool
+}
+
+// IngestReport counts what processing a file created, skipped or rejected. For dry runs
+// the counts are what would have happened.
+type IngestReport struct {
+	Bucket    string      `json:"bucket,omitempty"`
+	Key       string      `json:"key,omitempty"`
+	DryRun    bool        `json:"dry_run"`
+	TotalRows int         `json:"total_rows"`
+	Created   int         `json:"created"`
+	Skipped   int         `json:"skipped"`
+	Rejected  int         `json:"rejected"`
+	Rows      []RowReport `json:"rows"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// RowReport is the outcome of one skipped or rejected row
+type RowReport struct {
+	Row     int                     `json:"row"`
+	OrderID string                  `json:"order_id,omitempty"`
+	Outcome string                  `json:"outcome"`
+	Errors  []validation.FieldError `json:"errors,omitempty"`
+}
+
+// add counts the results of a batch whose rows are numbered by rowNumbers
+func (r *IngestReport) add(rowNumbers []int, results []OrderResult) {
+	for i, result := range results {
+		row := RowReport{Row: rowNumbers[i], OrderID: result.OrderID, Errors: result.Errors}
+		switch {
+		case result.Accepted:
+			r.Created++
+			continue
+		case result.Skipped:
+			r.Skipped++
+			row.Outcome = OutcomeSkipped
+		default:
+			r.Rejected++
+			row.Outcome = OutcomeRejected
+		}
+
+		if len(r.Rows) >= maxReportRows {
+			r.Truncated = true
+			continue
+		}
+		r.Rows = append(r.Rows, row)
+	}
+}
+
+// accept marks the record at i as accepted, so later rows of the same order are skipped
+func (o *ingestOptions) accept(results []OrderResult, i int, orderID string) {
+	results[i].Accepted = true
+	if o.skipExisting {
+		if o.accepted == nil {
+			o.accepted = make(map[string]bool)
+		}
+		o.accepted[orderID] = true
+	}
+}
+
+// markExisting marks records whose order already exists, or was accepted earlier in the
+// file, as skipped. Records are rejected when existing orders cannot be looked up, since
+// processing them could create duplicates.
+func (o *ingestOptions) markExisting(ctx context.Context, records []map[string]interface{}, results []OrderResult) {
+	orderIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		if orderID := recordOrderID(record); orderID != "" {
+			orderIDs = append(orderIDs, orderID)
+		}
+	}
+
+	existing, err := orders.ExistingOrderIDs(ctx, orderIDs)
+	if err != nil {
+		log.Printf("⚠️ Could not check for existing orders, rejecting batch: %v", err)
+	}
+	for i, record := range records {
+		orderID := recordOrderID(record)
+		results[i].OrderID = orderID
+		switch {
+		case err != nil:
+			results[i].Errors = []validation.FieldError{
+				validation.NewFieldError("order_id", validation.CodeValidationUnavailable,
+					fmt.Sprintf("Could not check for an existing order: %v", err)),
+			}
+		case orderID != "" && (existing[orderID] || o.accepted[orderID]):
+			results[i].Skipped = true
+		}
+	}
+}
+
+// recordOrderID returns the trimmed order ID of a record
+func recordOrderID(record map[string]interface{}) string {
+	orderID, _ := record["order_id"].(string)
+	return strings.TrimSpace(orderID)
+}
+
+// openStoredUpload streams a stored upload from S3
+func openStoredUpload(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	s3Client, err := s3.NewSimpleS3Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	body, err := s3Client.DownloadStream(ctx, bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s/%s: %w", bucket, key, err)
+	}
+	return body, nil
+}
+
+// ReprocessCSVFromS3 re-ingests a stored upload for the tenant in ctx. Orders that already
+// exist are skipped, so a file can be replayed after a fix without duplicating the orders
+// it created before. A dry run validates every row and reports what would be created,
+// skipped or rejected without writing orders or emitting events.
+func ReprocessCSVFromS3(ctx context.Context, bucket, key string, dryRun bool) (*IngestReport, error) {
+	log.Printf("🔁 Reprocessing %s/%s (dry run: %v)", bucket, key, dryRun)
+	body, err := openStoredUpload(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	report := &IngestReport{Bucket: bucket, Key: key, DryRun: dryRun, Rows: []RowReport{}}
+	err = processCSVStream(ctx, body, key, ingestOptions{report: report, dryRun: dryRun, skipExisting: true, rowQuota: !dryRun})
+	return report, err
+}
+
+// ReprocessJobFromS3 re-ingests the stored upload of job, a job created to reprocess an
+// earlier upload, tracking its rows like any other upload
+func ReprocessJobFromS3(ctx context.Context, job *jobs.Job) error {
+	body, err := openStoredUpload(ctx, job.Bucket, job.Key)
+	if err != nil {
+		if completeErr := jobs.CompleteJob(ctx, job, err); completeErr != nil {
+			log.Printf("❌ Failed to complete job %s: %v", job.JobID, completeErr)
+		}
+		return err
+	}
+	defer body.Close()
+
+	return ProcessJobCSV(ctx, job, body)
+}
@@ -0,0 +1,183 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"oms-service/internal/jobs"
+	"oms-service/internal/s3"
+	"oms-service/internal/tenant"
+)
+
+// defaultUploadBucket is used to mirror invalid rows when a job has no bucket of its own
+const defaultUploadBucket = "oms-orders"
+
+// jobTracker records the outcome of each CSV row against an upload job
+type jobTracker struct {
+	job        *jobs.Job
+	openRows   map[int]bool // parent rows still waiting for correction, for resubmissions
+	parentRows map[int]int  // row number in this file -> source row in the parent job
+}
+
+// newJobTracker prepares tracking for job, loading the parent's open rows for resubmissions
+func newJobTracker(ctx context.Context, job *jobs.Job) (*jobTracker, error) {
+	tracker := &jobTracker{job: job, parentRows: make(map[int]int)}
+	if job.ParentJobID == "" {
+		return tracker, nil
+	}
+
+	openRows, err := jobs.OpenRowNumbers(ctx, job.ParentJobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invalid rows of job %s: %w", job.ParentJobID, err)
+	}
+	tracker.openRows = openRows
+	log.Printf("🔁 Resubmission %s of job %s: %d rows awaiting correction", job.JobID, job.ParentJobID, len(openRows))
+	return tracker, nil
+}
+
+// setHeaders records the CSV columns so a correction template can be generated later
+func (t *jobTracker) setHeaders(headers []string) {
+	t.job.Headers = append([]string(nil), headers...)
+}
+
+// accept reports whether a row should be processed. Resubmissions only process rows
+// that point at a parent row which is still awaiting correction.
+func (t *jobTracker) accept(rowNumber int, record map[string]interface{}) bool {
+	t.job.TotalRows++
+	if t.job.ParentJobID == "" {
+		return true
+	}
+
+	sourceRow, err := strconv.Atoi(strings.TrimSpace(fmt.Sprintf("%v", record[jobs.SourceRowColumn])))
+	if err != nil || !t.openRows[sourceRow] {
+		t.job.SkippedRows++
+		return false
+	}
+
+	// Each parent row can only be corrected once per resubmission
+	delete(t.openRows, sourceRow)
+	t.parentRows[rowNumber] = sourceRow
+	return true
+}
+
+// recordBatch persists rejected rows and updates the parent's rows for resubmissions
+func (t *jobTracker) recordBatch(ctx context.Context, batch []map[string]interface{}, rowNumbers []int, results []OrderResult) {
+	var invalidRows []jobs.InvalidRow
+	var resolved, superseded []int
+
+	for i, result := range results {
+		rowNumber := rowNumbers[i]
+		parentRow, isCorrection := t.parentRows[rowNumber]
+		delete(t.parentRows, rowNumber)
+
+		if result.Skipped {
+			t.job.SkippedRows++
+			continue
+		}
+		if result.Accepted {
+			t.job.ValidRows++
+			if isCorrection {
+				resolved = append(resolved, parentRow)
+			}
+			continue
+		}
+
+		t.job.InvalidRows++
+		invalidRows = append(invalidRows, jobs.InvalidRow{
+			JobID:     t.job.JobID,
+			RowNumber: rowNumber,
+			ParentRow: parentRow,
+			Data:      recordData(batch[i]),
+			Errors:    result.Errors,
+		})
+		if isCorrection {
+			superseded = append(superseded, parentRow)
+		}
+	}
+
+	if err := jobs.SaveInvalidRows(ctx, invalidRows); err != nil {
+		log.Printf("❌ Failed to store invalid rows for job %s: %v", t.job.JobID, err)
+	}
+	if t.job.ParentJobID == "" {
+		return
+	}
+	if err := jobs.UpdateRowStatus(ctx, t.job.ParentJobID, resolved, jobs.RowResolved, t.job.JobID); err != nil {
+		log.Printf("❌ Failed to resolve rows of job %s: %v", t.job.ParentJobID, err)
+	}
+	if err := jobs.UpdateRowStatus(ctx, t.job.ParentJobID, superseded, jobs.RowSuperseded, t.job.JobID); err != nil {
+		log.Printf("❌ Failed to supersede rows of job %s: %v", t.job.ParentJobID, err)
+	}
+}
+
+// ProcessJobCSV streams a CSV upload for job, persisting rejected rows so they can be
+// corrected and resubmitted, and records the final counts on the job
+func ProcessJobCSV(ctx context.Context, job *jobs.Job, r io.Reader) error {
+	ctx = tenant.WithID(ctx, job.TenantID)
+	tracker, err := newJobTracker(ctx, job)
+	if err == nil {
+		err = processCSVStream(ctx, r, job.Filename, ingestOptions{tracker: tracker, skipExisting: job.ReprocessOf != "", rowQuota: true})
+	}
+
+	if job.InvalidRows > 0 {
+		key, mirrorErr := mirrorInvalidRows(ctx, job)
+		if mirrorErr != nil {
+			log.Printf("⚠️ Failed to mirror invalid rows of job %s to S3: %v", job.JobID, mirrorErr)
+		} else {
+			job.InvalidKey = key
+		}
+	}
+
+	if completeErr := jobs.CompleteJob(ctx, job, err); completeErr != nil {
+		log.Printf("❌ Failed to complete job %s: %v", job.JobID, completeErr)
+	}
+	return err
+}
+
+// mirrorInvalidRows uploads the correction template of a job to S3 and returns its key
+func mirrorInvalidRows(ctx context.Context, job *jobs.Job) (string, error) {
+	tmp, err := os.CreateTemp("", "invalid-*.csv")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := jobs.WriteCorrectionTemplate(ctx, tmp, job); err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind temp file: %w", err)
+	}
+
+	s3Client, err := s3.NewSimpleS3Client()
+	if err != nil {
+		return "", fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	bucket := job.Bucket
+	if bucket == "" {
+		bucket = defaultUploadBucket
+	}
+	key := fmt.Sprintf("invalid/%s.csv", job.JobID)
+	if err := s3Client.Upload(ctx, bucket, key, tmp, "text/csv"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// recordData converts a CSV record into the string form stored with invalid rows
+func recordData(record map[string]interface{}) map[string]string {
+	data := make(map[string]string, len(record))
+	for key, value := range record {
+		if key == jobs.SourceRowColumn || key == jobs.ErrorsColumn || value == nil {
+			continue
+		}
+		data[key] = fmt.Sprintf("%v", value)
+	}
+	return data
+}
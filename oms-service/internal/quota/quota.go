@@ -0,0 +1,299 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// limitsCacheTTL bounds how long a tenant's limits are served from memory
+const limitsCacheTTL = time.Minute
+
+// usageRetention is how long daily usage is kept after the day ends
+const usageRetention = 7 * 24 * time.Hour
+
+// ErrNotInitialized is returned when MongoDB was not available at startup. Quotas are
+// not enforced then.
+var ErrNotInitialized = errors.New("upload quota store not initialized")
+
+// ErrExceeded is wrapped by the errors returned for a tenant over its daily quota
+var ErrExceeded = errors.New("upload quota exceeded")
+
+// ExceededError reports which daily quota a tenant ran out of
+type ExceededError struct {
+	Kind  string // files or rows
+	Limit int
+	// RetryAfter is the time left until the quota resets at midnight UTC
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("daily quota of %d %s exceeded, it resets in %s", e.Limit, e.Kind, e.RetryAfter.Round(time.Minute))
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrExceeded
+}
+
+// Limits are the daily upload quotas of a tenant. Zero means unlimited.
+type Limits struct {
+	TenantID    string    `bson:"tenant_id" json:"tenant_id"`
+	FilesPerDay int       `bson:"files_per_day" json:"files_per_day"`
+	RowsPerDay  int       `bson:"rows_per_day" json:"rows_per_day"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Validate checks that the limits are not negative
+func (l *Limits) Validate() error {
+	if l.FilesPerDay < 0 || l.RowsPerDay < 0 {
+		return fmt.Errorf("files_per_day and rows_per_day must not be negative")
+	}
+	return nil
+}
+
+// Usage counts what a tenant uploaded on a day (UTC)
+type Usage struct {
+	TenantID  string    `bson:"tenant_id" json:"tenant_id"`
+	Day       string    `bson:"day" json:"day"`
+	Files     int       `bson:"files" json:"files"`
+	Rows      int       `bson:"rows" json:"rows"`
+	ExpiresAt time.Time `bson:"expires_at" json:"-"`
+}
+
+type cachedLimits struct {
+	limits   *Limits
+	loadedAt time.Time
+}
+
+var (
+	limitsCollection *mongo.Collection
+	usageCollection  *mongo.Collection
+	defaultLimits    = Limits{TenantID: "default"}
+	limitsCache      = make(map[string]cachedLimits)
+	limitsMu         sync.RWMutex
+)
+
+// Initialize sets up the quota collections. defaults apply to tenants without limits of
+// their own.
+func Initialize(client *mongo.Client, defaults Limits) error {
+	defaultLimits = defaults
+	defaultLimits.TenantID = "default"
+	if client == nil {
+		return ErrNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := client.Database("oms_database")
+	limits := db.Collection("upload_quotas")
+	if _, err := limits.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("failed to create upload_quotas index: %w", err)
+	}
+
+	usage := db.Collection("upload_usage")
+	if _, err := usage.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to create upload_usage indexes: %w", err)
+	}
+
+	limitsCollection = limits
+	usageCollection = usage
+	log.Println("📊 Database: oms_database, Collections: upload_quotas, upload_usage")
+	return nil
+}
+
+// LimitsFor returns the quotas of a tenant, or the defaults when it has none of its own
+func LimitsFor(ctx context.Context, tenantID string) *Limits {
+	limitsMu.RLock()
+	cached, ok := limitsCache[tenantID]
+	limitsMu.RUnlock()
+	if ok && time.Since(cached.loadedAt) <= limitsCacheTTL {
+		return cached.limits
+	}
+
+	limits, err := loadLimits(ctx, tenantID)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) && !errors.Is(err, ErrNotInitialized) {
+			log.Printf("⚠️ Failed to load upload quotas for tenant %s, using defaults: %v", tenantID, err)
+		}
+		limits = &Limits{TenantID: tenantID, FilesPerDay: defaultLimits.FilesPerDay, RowsPerDay: defaultLimits.RowsPerDay}
+	}
+
+	limitsMu.Lock()
+	limitsCache[tenantID] = cachedLimits{limits: limits, loadedAt: time.Now()}
+	limitsMu.Unlock()
+	return limits
+}
+
+// SaveLimits stores the quotas of a tenant, replacing its previous ones
+func SaveLimits(ctx context.Context, limits *Limits) error {
+	if limitsCollection == nil {
+		return ErrNotInitialized
+	}
+	if limits.TenantID == "" {
+		return fmt.Errorf("tenant_id is required")
+	}
+
+	limits.UpdatedAt = time.Now()
+	_, err := limitsCollection.ReplaceOne(ctx, bson.M{"tenant_id": limits.TenantID}, limits,
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save upload quotas: %w", err)
+	}
+
+	limitsMu.Lock()
+	delete(limitsCache, limits.TenantID)
+	limitsMu.Unlock()
+
+	log.Printf("✅ Upload quotas saved for tenant %s: %d files, %d rows per day", limits.TenantID, limits.FilesPerDay, limits.RowsPerDay)
+	return nil
+}
+
+// DeleteLimits removes the quotas of a tenant, so the defaults apply to it again
+func DeleteLimits(ctx context.Context, tenantID string) error {
+	if limitsCollection == nil {
+		return ErrNotInitialized
+	}
+	if _, err := limitsCollection.DeleteOne(ctx, bson.M{"tenant_id": tenantID}); err != nil {
+		return fmt.Errorf("failed to delete upload quotas: %w", err)
+	}
+
+	limitsMu.Lock()
+	delete(limitsCache, tenantID)
+	limitsMu.Unlock()
+	return nil
+}
+
+// UsageFor returns what a tenant uploaded today
+func UsageFor(ctx context.Context, tenantID string) (*Usage, error) {
+	if usageCollection == nil {
+		return nil, ErrNotInitialized
+	}
+	usage := &Usage{TenantID: tenantID, Day: today()}
+	err := usageCollection.FindOne(ctx, bson.M{"tenant_id": tenantID, "day": usage.Day}).Decode(usage)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to read upload usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ConsumeFile counts an uploaded file against the tenant's daily quota. It returns an
+// ExceededError when the quota is used up. Quotas are not enforced while the store is
+// unavailable.
+func ConsumeFile(ctx context.Context, tenantID string) error {
+	if usageCollection == nil {
+		return nil
+	}
+	limit := LimitsFor(ctx, tenantID).FilesPerDay
+
+	day := today()
+	filter := bson.M{"tenant_id": tenantID, "day": day}
+	if limit > 0 {
+		filter["files"] = bson.M{"$lt": limit}
+	}
+	_, err := usageCollection.UpdateOne(ctx, filter, usageUpdate("files", 1, day), options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// Today's usage exists but is at the limit, so the upsert tried to insert a second one
+		return &ExceededError{Kind: "files", Limit: limit, RetryAfter: untilReset()}
+	}
+	if err != nil {
+		log.Printf("⚠️ Failed to count upload for tenant %s, not enforcing its quota: %v", tenantID, err)
+	}
+	return nil
+}
+
+// ConsumeRows counts up to n rows against the tenant's daily quota and returns how many
+// it may process. Rows beyond the quota should be rejected. Quotas are not enforced
+// while the store is unavailable.
+func ConsumeRows(ctx context.Context, tenantID string, n int) (int, error) {
+	if usageCollection == nil || n <= 0 {
+		return n, nil
+	}
+	limit := LimitsFor(ctx, tenantID).RowsPerDay
+
+	day := today()
+	filter := bson.M{"tenant_id": tenantID, "day": day}
+	var usage Usage
+	err := usageCollection.FindOneAndUpdate(ctx, filter, usageUpdate("rows", n, day),
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&usage)
+	if err != nil {
+		log.Printf("⚠️ Failed to count rows for tenant %s, not enforcing its quota: %v", tenantID, err)
+		return n, nil
+	}
+	if limit <= 0 || usage.Rows <= limit {
+		return n, nil
+	}
+
+	// Give back the rows that are not granted, so they do not count against the tenant
+	granted := max(0, n-(usage.Rows-limit))
+	if _, err := usageCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"rows": granted - n}}); err != nil {
+		log.Printf("⚠️ Failed to return %d rows to tenant %s's quota: %v", n-granted, tenantID, err)
+	}
+	return granted, &ExceededError{Kind: "rows", Limit: limit, RetryAfter: untilReset()}
+}
+
+// ReturnRows gives back n rows counted by ConsumeRows that were not processed after all,
+// such as rows that failed to save
+func ReturnRows(ctx context.Context, tenantID string, n int) {
+	if usageCollection == nil || n <= 0 {
+		return
+	}
+	filter := bson.M{"tenant_id": tenantID, "day": today()}
+	if _, err := usageCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"rows": -n}}); err != nil {
+		log.Printf("⚠️ Failed to return %d rows to tenant %s's quota: %v", n, tenantID, err)
+	}
+}
+
+func usageUpdate(field string, n int, day string) bson.M {
+	return bson.M{
+		"$inc": bson.M{field: n},
+		"$setOnInsert": bson.M{
+			"expires_at": dayStart(day).Add(24*time.Hour + usageRetention),
+		},
+	}
+}
+
+func loadLimits(ctx context.Context, tenantID string) (*Limits, error) {
+	if limitsCollection == nil {
+		return nil, ErrNotInitialized
+	}
+	var limits Limits
+	if err := limitsCollection.FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&limits); err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
+
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+func dayStart(day string) time.Time {
+	start, _ := time.Parse(time.DateOnly, day)
+	return start
+}
+
+// untilReset returns the time left until the quotas reset at midnight UTC
+func untilReset() time.Duration {
+	now := time.Now().UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
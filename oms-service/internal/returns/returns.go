@@ -0,0 +1,352 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"oms-service/internal/orders"
+	"oms-service/internal/tenant"
+
+	"github.com/google/uuid"
+	"github.com/omniful/ims-service/pkg/imsclient"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Return statuses
+const (
+	StatusRequested = "requested"
+	StatusReceived  = "received"
+	StatusCompleted = "completed"
+)
+
+var (
+	// ErrInvalidReturn is returned for requests that do not match what the order shipped
+	ErrInvalidReturn = errors.New("invalid return")
+	// ErrInvalidStatus is returned when a return is not in the status an action needs
+	ErrInvalidStatus = errors.New("invalid return status")
+)
+
+// Return is a customer return (RMA) of shipped units of an order
+type Return struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ReturnID     string             `bson:"return_id" json:"return_id"`
+	OrderID      string             `bson:"order_id" json:"order_id"`
+	TenantID     string             `bson:"tenant_id" json:"tenant_id"`
+	CustomerID   string             `bson:"customer_id" json:"customer_id"`
+	Reason       string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status       string             `bson:"status" json:"status"`
+	HubID        string             `bson:"hub_id,omitempty" json:"hub_id,omitempty"`
+	Lines        []Line             `bson:"lines" json:"lines"`
+	RefundAmount float64            `bson:"refund_amount" json:"refund_amount"`
+	History      []StatusChange     `bson:"history" json:"history"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	ReceivedAt   *time.Time         `bson:"received_at,omitempty" json:"received_at,omitempty"`
+	CompletedAt  *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// Line is a returned quantity of a SKU and, once received, how its units were graded
+type Line struct {
+	SKU         string  `bson:"sku" json:"sku"`
+	ShipmentID  string  `bson:"shipment_id" json:"shipment_id"`
+	Quantity    int     `bson:"quantity" json:"quantity"`
+	UnitPrice   float64 `bson:"unit_price" json:"unit_price"`
+	Restockable int     `bson:"restockable" json:"restockable"`
+	Damaged     int     `bson:"damaged" json:"damaged"`
+	Destroyed   int     `bson:"destroyed" json:"destroyed"`
+	Restocked   bool    `bson:"restocked" json:"restocked"`
+}
+
+// graded reports whether every unit of the line has a grade
+func (l Line) graded() bool {
+	return l.Restockable+l.Damaged+l.Destroyed == l.Quantity
+}
+
+// StatusChange records a status change of a return
+type StatusChange struct {
+	From string    `bson:"from" json:"from"`
+	To   string    `bson:"to" json:"to"`
+	Note string    `bson:"note,omitempty" json:"note,omitempty"`
+	At   time.Time `bson:"at" json:"at"`
+}
+
+// CreateRequest asks to return shipped units of an order
+type CreateRequest struct {
+	OrderID string `json:"order_id"`
+	Reason  string `json:"reason"`
+	Lines   []struct {
+		SKU        string `json:"sku"`
+		ShipmentID string `json:"shipment_id,omitempty"`
+		Quantity   int    `json:"quantity"`
+	} `json:"lines"`
+}
+
+// GradeRequest grades every unit of a received return as restockable, damaged or destroyed.
+// RefundAmount overrides the default refund of the full price of the returned units.
+type GradeRequest struct {
+	Lines []struct {
+		SKU         string `json:"sku"`
+		Restockable int    `json:"restockable"`
+		Damaged     int    `json:"damaged"`
+		Destroyed   int    `json:"destroyed"`
+	} `json:"lines"`
+	RefundAmount *float64 `json:"refund_amount,omitempty"`
+}
+
+// Service runs returns from request to restock
+type Service struct {
+	imsClient *imsclient.Client
+	// mutex serialises returns so units are neither over-returned nor restocked twice
+	mutex sync.Mutex
+}
+
+// NewService creates a returns service that restocks graded units through imsClient
+func NewService(imsClient *imsclient.Client) *Service {
+	return &Service{imsClient: imsClient}
+}
+
+// Create opens a return for shipped units of an order. Each SKU can be returned up to the
+// quantity shipped less what earlier returns of the order already cover.
+func (s *Service) Create(ctx context.Context, req CreateRequest) (*Return, error) {
+	if req.OrderID == "" || len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: order_id and at least one line are required", ErrInvalidReturn)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	order, err := orders.GetOrder(ctx, req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = order.WithTenant(ctx)
+	returned, err := returnedQuantities(ctx, req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	shipped := make(map[string]int)
+	for _, shipment := range order.Shipments {
+		if shipment.Status != orders.ShipmentShipped {
+			continue
+		}
+		for _, item := range shipment.Items {
+			shipped[item.SKU] += item.Fulfilled
+		}
+	}
+
+	now := time.Now()
+	ret := &Return{
+		ReturnID:   "RMA-" + strings.ToUpper(uuid.NewString()[:8]),
+		OrderID:    order.OrderID,
+		TenantID:   tenant.FromContext(ctx),
+		CustomerID: order.CustomerID,
+		Reason:     req.Reason,
+		Status:     StatusRequested,
+		History:    []StatusChange{{To: StatusRequested, Note: req.Reason, At: now}},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	for _, requested := range req.Lines {
+		if requested.SKU == "" || requested.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: every line needs a sku and a positive quantity", ErrInvalidReturn)
+		}
+		if returnable := shipped[requested.SKU] - returned[requested.SKU]; requested.Quantity > returnable {
+			return nil, fmt.Errorf("%w: %d of %s requested, %d shipped and not yet returned",
+				ErrInvalidReturn, requested.Quantity, requested.SKU, max(returnable, 0))
+		}
+		returned[requested.SKU] += requested.Quantity
+
+		shipmentID, err := shipmentFor(order, requested.SKU, requested.ShipmentID)
+		if err != nil {
+			return nil, err
+		}
+		ret.Lines = append(ret.Lines, Line{
+			SKU:        requested.SKU,
+			ShipmentID: shipmentID,
+			Quantity:   requested.Quantity,
+			UnitPrice:  order.UnitPrice,
+		})
+		ret.RefundAmount += float64(requested.Quantity) * order.UnitPrice
+	}
+	ret.RefundAmount = roundCents(ret.RefundAmount)
+
+	if err := insert(ctx, ret); err != nil {
+		return nil, err
+	}
+	log.Printf("↩️ Return %s opened for order %s (%d lines)", ret.ReturnID, ret.OrderID, len(ret.Lines))
+	return ret, nil
+}
+
+// Receive records that a return arrived at hubID, the hub it shipped from when empty.
+// Receiving a return again at the same hub changes nothing.
+func (s *Service) Receive(ctx context.Context, returnID, hubID string) (*Return, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ret, err := Get(ctx, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if hubID == "" {
+		hubID, err = shipmentHub(ctx, ret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch ret.Status {
+	case StatusRequested:
+	case StatusReceived, StatusCompleted:
+		if ret.HubID != hubID {
+			return nil, fmt.Errorf("%w: return %s was already received at %s", ErrInvalidStatus, returnID, ret.HubID)
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("%w: return %s is %s", ErrInvalidStatus, returnID, ret.Status)
+	}
+
+	now := time.Now()
+	change := StatusChange{To: StatusReceived, Note: "received at " + hubID, At: now}
+	if err := transition(ctx, returnID, StatusRequested, change, bson.M{"hub_id": hubID, "received_at": now}); err != nil {
+		return nil, err
+	}
+	log.Printf("📥 Return %s received at hub %s", returnID, hubID)
+	return Get(ctx, returnID)
+}
+
+// Grade grades the units of a received return, restocks them in IMS and completes the
+// return with its refund. Restocked lines are recorded one by one, so grading again after
+// a failure only restocks what is left.
+func (s *Service) Grade(ctx context.Context, returnID string, req GradeRequest) (*Return, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ret, err := Get(ctx, returnID)
+	if err != nil {
+		return nil, err
+	}
+	if ret.TenantID != "" {
+		ctx = tenant.WithID(ctx, ret.TenantID)
+	}
+	if ret.Status == StatusCompleted {
+		return ret, nil
+	}
+	if ret.Status != StatusReceived {
+		return nil, fmt.Errorf("%w: return %s must be received before it is graded", ErrInvalidStatus, returnID)
+	}
+
+	lines, err := applyGrades(ret.Lines, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveGrades(ctx, returnID, lines); err != nil {
+		return nil, err
+	}
+
+	for i, line := range lines {
+		if line.Restocked {
+			continue
+		}
+		// Destroyed units are written off and not taken back into inventory
+		if line.Restockable+line.Damaged > 0 {
+			_, err := s.imsClient.RestockInventory(ctx, imsclient.RestockRequest{
+				HubCode:     ret.HubID,
+				SkuCode:     line.SKU,
+				Restockable: line.Restockable,
+				Damaged:     line.Damaged,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to restock %s for return %s: %w", line.SKU, returnID, err)
+			}
+		}
+		if err := markRestocked(ctx, returnID, i); err != nil {
+			return nil, err
+		}
+	}
+
+	refund := ret.RefundAmount
+	if req.RefundAmount != nil {
+		if *req.RefundAmount < 0 {
+			return nil, fmt.Errorf("%w: refund_amount must not be negative", ErrInvalidReturn)
+		}
+		refund = roundCents(*req.RefundAmount)
+	}
+
+	now := time.Now()
+	change := StatusChange{To: StatusCompleted, Note: fmt.Sprintf("refund %.2f", refund), At: now}
+	if err := transition(ctx, returnID, StatusReceived, change, bson.M{"refund_amount": refund, "completed_at": now}); err != nil {
+		return nil, err
+	}
+	log.Printf("✅ Return %s completed, refund %.2f", returnID, refund)
+	return Get(ctx, returnID)
+}
+
+// applyGrades returns the lines of a return graded by req. Lines graded by an earlier
+// attempt keep their grades, a different grading for them is rejected.
+func applyGrades(lines []Line, req GradeRequest) ([]Line, error) {
+	graded := append([]Line(nil), lines...)
+	for i := range graded {
+		line := &graded[i]
+		found := false
+		for _, grade := range req.Lines {
+			if grade.SKU != line.SKU {
+				continue
+			}
+			found = true
+			if grade.Restockable < 0 || grade.Damaged < 0 || grade.Destroyed < 0 ||
+				grade.Restockable+grade.Damaged+grade.Destroyed != line.Quantity {
+				return nil, fmt.Errorf("%w: grades of %s must add up to the %d units returned",
+					ErrInvalidReturn, line.SKU, line.Quantity)
+			}
+			if line.Restocked && (grade.Restockable != line.Restockable || grade.Damaged != line.Damaged) {
+				return nil, fmt.Errorf("%w: %s was already restocked with different grades", ErrInvalidStatus, line.SKU)
+			}
+			line.Restockable, line.Damaged, line.Destroyed = grade.Restockable, grade.Damaged, grade.Destroyed
+		}
+		if !found && !line.graded() {
+			return nil, fmt.Errorf("%w: no grades for %s", ErrInvalidReturn, line.SKU)
+		}
+	}
+	return graded, nil
+}
+
+// shipmentFor returns the shipped shipment of an order a SKU is returned from
+func shipmentFor(order *orders.Order, sku, shipmentID string) (string, error) {
+	for _, shipment := range order.Shipments {
+		if shipment.Status != orders.ShipmentShipped || (shipmentID != "" && shipment.ShipmentID != shipmentID) {
+			continue
+		}
+		for _, item := range shipment.Items {
+			if item.SKU == sku && item.Fulfilled > 0 {
+				return shipment.ShipmentID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: no shipped shipment of order %s holds %s", ErrInvalidReturn, order.OrderID, sku)
+}
+
+// shipmentHub returns the hub the first line of a return shipped from
+func shipmentHub(ctx context.Context, ret *Return) (string, error) {
+	order, err := orders.GetOrder(ctx, ret.OrderID)
+	if err != nil {
+		return "", err
+	}
+	for _, shipment := range order.Shipments {
+		if len(ret.Lines) > 0 && shipment.ShipmentID == ret.Lines[0].ShipmentID {
+			return shipment.HubID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: hub_id is required", ErrInvalidReturn)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,188 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"oms-service/internal/tenant"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotInitialized is returned when MongoDB was not available at startup
+var ErrNotInitialized = errors.New("returns store not initialized")
+
+// ErrNotFound is returned when there is no return with the given ID
+var ErrNotFound = errors.New("return not found")
+
+// errStatusChanged is returned when a return moved on while it was being updated
+var errStatusChanged = errors.New("return was modified concurrently")
+
+var returnsCollection *mongo.Collection
+
+// Initialize sets up the returns collection and its indexes
+func Initialize(client *mongo.Client) error {
+	if client == nil {
+		return ErrNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := client.Database("oms_database").Collection("returns")
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "return_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create returns indexes: %w", err)
+	}
+
+	returnsCollection = collection
+	log.Println("📊 Database: oms_database, Collection: returns")
+	return nil
+}
+
+// insert stores a new return
+func insert(ctx context.Context, ret *Return) error {
+	if returnsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	result, err := returnsCollection.InsertOne(ctx, ret)
+	if err != nil {
+		return fmt.Errorf("failed to create return: %w", err)
+	}
+	ret.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// Get returns a return by its ID
+func Get(ctx context.Context, returnID string) (*Return, error) {
+	if returnsCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var ret Return
+	err := returnsCollection.FindOne(ctx, bson.M{"return_id": returnID}).Decode(&ret)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return %s: %w", returnID, err)
+	}
+	return &ret, nil
+}
+
+// List returns the returns of an order, or of a tenant when orderID is empty, newest first
+func List(ctx context.Context, tenantID, orderID string, limit int64) ([]Return, error) {
+	if returnsCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	query := bson.M{}
+	if tenantID != "" {
+		query["tenant_id"] = tenantID
+	}
+	if orderID != "" {
+		query["order_id"] = orderID
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	cursor, err := returnsCollection.Find(ctx, query,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list returns: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	returns := []Return{}
+	if err := cursor.All(ctx, &returns); err != nil {
+		return nil, fmt.Errorf("failed to decode returns: %w", err)
+	}
+	return returns, nil
+}
+
+// returnedQuantities sums the quantity of each SKU already returned for an order of the
+// tenant of ctx
+func returnedQuantities(ctx context.Context, orderID string) (map[string]int, error) {
+	existing, err := List(ctx, tenant.FromContext(ctx), orderID, 500)
+	if err != nil {
+		return nil, err
+	}
+
+	returned := make(map[string]int)
+	for _, ret := range existing {
+		for _, line := range ret.Lines {
+			returned[line.SKU] += line.Quantity
+		}
+	}
+	return returned, nil
+}
+
+// transition moves a return from one status to another, applying set and recording the
+// change in its history. It returns errStatusChanged if the return is no longer in from.
+func transition(ctx context.Context, returnID, from string, change StatusChange, set bson.M) error {
+	if returnsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	change.From = from
+	if set == nil {
+		set = bson.M{}
+	}
+	set["status"] = change.To
+	set["updated_at"] = change.At
+
+	result, err := returnsCollection.UpdateOne(ctx,
+		bson.M{"return_id": returnID, "status": from},
+		bson.M{"$set": set, "$push": bson.M{"history": change}})
+	if err != nil {
+		return fmt.Errorf("failed to update return %s: %w", returnID, err)
+	}
+	if result.MatchedCount == 0 {
+		return errStatusChanged
+	}
+	return nil
+}
+
+// saveGrades records the grading of the lines of a received return
+func saveGrades(ctx context.Context, returnID string, lines []Line) error {
+	if returnsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	result, err := returnsCollection.UpdateOne(ctx,
+		bson.M{"return_id": returnID, "status": StatusReceived},
+		bson.M{"$set": bson.M{"lines": lines, "updated_at": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to grade return %s: %w", returnID, err)
+	}
+	if result.MatchedCount == 0 {
+		return errStatusChanged
+	}
+	return nil
+}
+
+// markRestocked records that the units of a line were taken back in by IMS
+func markRestocked(ctx context.Context, returnID string, line int) error {
+	if returnsCollection == nil {
+		return ErrNotInitialized
+	}
+
+	_, err := returnsCollection.UpdateOne(ctx, bson.M{"return_id": returnID},
+		bson.M{"$set": bson.M{fmt.Sprintf("lines.%d.restocked", line): true}})
+	if err != nil {
+		return fmt.Errorf("failed to record restock of return %s: %w", returnID, err)
+	}
+	return nil
+}
@@ -93,3 +93,19 @@ func (s *SimpleS3Client) Download(ctx context.Context, bucketName, key string) (
 	log.Printf("Successfully downloaded %d bytes from S3", len(content))
 	return content, nil
 }
+
+// DownloadStream opens an object for reading without buffering it in memory.
+// The caller must close the returned reader.
+func (s *SimpleS3Client) DownloadStream(ctx context.Context, bucketName, key string) (io.ReadCloser, error) {
+	log.Printf("Streaming from S3: bucket=%s, key=%s", bucketName, key)
+
+	result, err := s.client.GetObject(ctx, &awsS3.GetObjectInput{
+		Bucket: &bucketName,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object from S3: %w", err)
+	}
+
+	return result.Body, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -15,6 +16,9 @@ import (
 	commonsqs "github.com/omniful/go_commons/sqs"
 )
 
+// maxMessageDelay is the longest delay SQS allows on a message
+const maxMessageDelay = 15 * time.Minute
+
 // Client wraps SQS functionality with compression workaround
 type Client struct {
 	Queue     *commonsqs.Queue
@@ -62,6 +66,11 @@ func New(queue *commonsqs.Queue) *Client {
 
 // Publish publishes a message to SQS using direct AWS SDK to avoid compression issues
 func (c *Client) Publish(ctx context.Context, msg *commonsqs.Message) error {
+	return c.PublishDelayed(ctx, msg, 0)
+}
+
+// PublishDelayed publishes a message that consumers only receive after delay, at most 15 minutes
+func (c *Client) PublishDelayed(ctx context.Context, msg *commonsqs.Message, delay time.Duration) error {
 	if c.sqsClient == nil {
 		log.Printf("❌ [SQS] No SQS client available, logging message: %s", string(msg.Value))
 		return fmt.Errorf("SQS client not initialized")
@@ -72,6 +81,9 @@ func (c *Client) Publish(ctx context.Context, msg *commonsqs.Message) error {
 		QueueUrl:    aws.String(c.QueueURL),
 		MessageBody: aws.String(string(msg.Value)),
 	}
+	if delay > 0 {
+		input.DelaySeconds = aws.Int64(int64(min(delay, maxMessageDelay) / time.Second))
+	}
 
 	// Add message attributes if any
 	if msg.Attributes != nil && len(msg.Attributes) > 0 {
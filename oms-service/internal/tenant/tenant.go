@@ -0,0 +1,43 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Header carries the tenant of a request, matching the header IMS expects
+const Header = "X-Tenant-ID"
+
+// Default is used when a request does not name a tenant
+const Default = "default"
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx carrying tenantID
+func WithID(ctx context.Context, tenantID string) context.Context {
+	if tenantID == "" {
+		tenantID = Default
+	}
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext returns the tenant carried by ctx, or Default
+func FromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(contextKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return Default
+}
+
+// FromRequest returns the tenant the request was authenticated for, else the one named
+// in the request header, or Default
+func FromRequest(r *http.Request) string {
+	if tenantID, ok := r.Context().Value(contextKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	if tenantID := strings.TrimSpace(r.Header.Get(Header)); tenantID != "" {
+		return tenantID
+	}
+	return Default
+}
@@ -12,11 +12,10 @@ import (
 // Service provides upload functionality using go_commons/uploads
 type Service struct {
 	tempUploadService *uploads.TempUploadService
-	// Using interface{} for now since we don't know the exact type
-	ephemeralClient interface{}
-	tempBucket      string
-	permanentBucket string
-	region          string
+	ephemeralClient   *uploads.EphemeralDownloadClient
+	tempBucket        string
+	permanentBucket   string
+	region            string
 }
 
 // Config contains the configuration for the upload service
@@ -49,6 +48,11 @@ func NewService(cfg Config) (*Service, error) {
 	}, nil
 }
 
+// PermanentBucket returns the bucket completed uploads are transferred to
+func (s *Service) PermanentBucket() string {
+	return s.permanentBucket
+}
+
 // GenerateUploadURL creates a pre-signed URL for frontend uploads
 func (s *Service) GenerateUploadURL(tenant, useCase, contentType, filename string) (*uploads.TempURLResponse, error) {
 	return s.tempUploadService.GenerateTempURL(tenant, useCase, contentType, filename)
@@ -73,7 +77,7 @@ func (s *Service) DownloadUploadedFile(ctx context.Context, uploadID string, out
 	return nil
 }
 
-// DownloadToReader downloads a file from the temporary bucket to an io.Writer
+// DownloadToWriter downloads a file from the temporary bucket to an io.Writer
 func (s *Service) DownloadToWriter(ctx context.Context, uploadID string, writer io.Writer) error {
 	err := s.ephemeralClient.DownloadObject(ctx, &uploads.DownloadObjectInput{
 		EphemeralUploadID: uploadID,
@@ -0,0 +1,81 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error codes reported for rejected order fields
+const (
+	CodeRequired              = "required"
+	CodeInvalidFormat         = "invalid_format"
+	CodeUnknownSKU            = "unknown_sku"
+	CodeUnknownHub            = "unknown_hub"
+	CodeInactiveSKU           = "inactive_sku"
+	CodeInactiveHub           = "inactive_hub"
+	CodeTenantMismatch        = "tenant_mismatch"
+	CodeValidationUnavailable = "validation_unavailable"
+	CodeSaveFailed            = "save_failed"
+	CodeInvalidRecord         = "invalid_record"
+	CodeQuotaExceeded         = "quota_exceeded"
+)
+
+// FieldError describes why a single field of an order was rejected.
+// Field is empty when the error applies to the whole record.
+type FieldError struct {
+	Field   string `json:"field,omitempty" bson:"field,omitempty"`
+	Code    string `json:"code" bson:"code"`
+	Message string `json:"message" bson:"message"`
+}
+
+// Error implements the error interface
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Errors is a list of field errors reported for one record
+type Errors []FieldError
+
+// Error implements the error interface
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// NewFieldError creates a field error
+func NewFieldError(field, code, message string) FieldError {
+	return FieldError{Field: field, Code: code, Message: message}
+}
+
+// AsFieldErrors converts err into field errors, wrapping unstructured errors as a record-level error
+func AsFieldErrors(err error) []FieldError {
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs Errors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs
+	}
+	var fieldErr FieldError
+	if errors.As(err, &fieldErr) {
+		return []FieldError{fieldErr}
+	}
+	return []FieldError{{Code: CodeInvalidRecord, Message: err.Error()}}
+}
+
+// Messages returns the human readable messages of errs, prefixed with their codes
+func Messages(errs []FieldError) []string {
+	messages := make([]string, len(errs))
+	for i, fieldErr := range errs {
+		messages[i] = fmt.Sprintf("[%s] %s", fieldErr.Code, fieldErr.Error())
+	}
+	return messages
+}
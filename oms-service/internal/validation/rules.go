@@ -0,0 +1,185 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes reported by the rule engine
+const (
+	CodeInvalidEmail  = "invalid_email"
+	CodeInvalidDate   = "invalid_date"
+	CodeNotPositive   = "not_positive"
+	CodeOutOfRange    = "out_of_range"
+	CodeExceedsMax    = "exceeds_max"
+	CodeTotalMismatch = "total_mismatch"
+)
+
+// alwaysRequired are needed to validate an order against IMS, whatever the tenant configures
+var alwaysRequired = []string{"order_id", "sku", "hub_id"}
+
+// Rules is the validation rule set applied to incoming order records
+type Rules struct {
+	TenantID           string    `json:"tenant_id" bson:"tenant_id"`
+	RequiredFields     []string  `json:"required_fields" bson:"required_fields"`
+	ValidateEmail      bool      `json:"validate_email" bson:"validate_email"`
+	MaxQuantityPerLine int       `json:"max_quantity_per_line" bson:"max_quantity_per_line"` // 0 disables the check
+	MinUnitPrice       float64   `json:"min_unit_price" bson:"min_unit_price"`
+	MaxUnitPrice       float64   `json:"max_unit_price" bson:"max_unit_price"` // 0 disables the check
+	DateFormats        []string  `json:"date_formats" bson:"date_formats"`
+	CheckTotal         bool      `json:"check_total" bson:"check_total"`
+	TotalTolerance     float64   `json:"total_tolerance" bson:"total_tolerance"`
+	UpdatedAt          time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// DefaultRules returns the rule set used for tenants without their own configuration
+func DefaultRules() *Rules {
+	return &Rules{
+		TenantID:           "default",
+		RequiredFields:     []string{"order_id", "sku", "hub_id"},
+		ValidateEmail:      true,
+		MaxQuantityPerLine: 10000,
+		MinUnitPrice:       0,
+		DateFormats:        []string{"2006-01-02"},
+		CheckTotal:         true,
+		TotalTolerance:     0.01,
+	}
+}
+
+// Validate checks a raw order record against the rules and returns every failure
+func (r *Rules) Validate(record map[string]interface{}) Errors {
+	var errs Errors
+
+	// Required fields
+	seen := make(map[string]bool)
+	for _, field := range append(append([]string(nil), alwaysRequired...), r.RequiredFields...) {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+		if fieldValue(record, field) == "" {
+			errs = append(errs, NewFieldError(field, CodeRequired, fmt.Sprintf("%s is required", field)))
+		}
+	}
+
+	// Email format
+	if email := fieldValue(record, "customer_email"); r.ValidateEmail && email != "" {
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			errs = append(errs, NewFieldError("customer_email", CodeInvalidEmail,
+				fmt.Sprintf("%q is not a valid email address", email)))
+		}
+	}
+
+	// Quantity must be a positive integer within the per-line maximum
+	quantity, quantityOK := 1, true
+	if raw := fieldValue(record, "quantity"); raw != "" {
+		value, err := ParseInt(raw)
+		switch {
+		case err != nil:
+			quantityOK = false
+			errs = append(errs, NewFieldError("quantity", CodeInvalidFormat, err.Error()))
+		case value <= 0:
+			quantityOK = false
+			errs = append(errs, NewFieldError("quantity", CodeNotPositive, "quantity must be greater than zero"))
+		case r.MaxQuantityPerLine > 0 && value > r.MaxQuantityPerLine:
+			quantityOK = false
+			errs = append(errs, NewFieldError("quantity", CodeExceedsMax,
+				fmt.Sprintf("quantity %d exceeds the maximum of %d per line", value, r.MaxQuantityPerLine)))
+		default:
+			quantity = value
+		}
+	}
+
+	// Unit price must be within the configured range
+	unitPrice, priceOK := 0.0, false
+	if raw := fieldValue(record, "unit_price"); raw != "" {
+		value, err := ParseFloat(raw)
+		switch {
+		case err != nil:
+			errs = append(errs, NewFieldError("unit_price", CodeInvalidFormat, err.Error()))
+		case value < r.MinUnitPrice || (r.MaxUnitPrice > 0 && value > r.MaxUnitPrice):
+			errs = append(errs, NewFieldError("unit_price", CodeOutOfRange,
+				fmt.Sprintf("unit_price %.2f is outside the allowed range %s", value, r.priceRange())))
+		default:
+			unitPrice, priceOK = value, true
+		}
+	}
+
+	// Total must match quantity x unit price
+	if raw := fieldValue(record, "total_amount"); raw != "" {
+		total, err := ParseFloat(raw)
+		switch {
+		case err != nil:
+			errs = append(errs, NewFieldError("total_amount", CodeInvalidFormat, err.Error()))
+		case r.CheckTotal && quantityOK && priceOK:
+			expected := float64(quantity) * unitPrice
+			if math.Abs(total-expected) > r.TotalTolerance {
+				errs = append(errs, NewFieldError("total_amount", CodeTotalMismatch,
+					fmt.Sprintf("total_amount %.2f does not match quantity x unit_price (%.2f)", total, expected)))
+			}
+		}
+	}
+
+	// Order date must use an accepted format
+	if raw := fieldValue(record, "order_date"); raw != "" {
+		if _, err := r.ParseDate(raw); err != nil {
+			errs = append(errs, NewFieldError("order_date", CodeInvalidDate, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// ParseDate parses value with the first accepted date format that matches
+func (r *Rules) ParseDate(value string) (time.Time, error) {
+	formats := r.DateFormats
+	if len(formats) == 0 {
+		formats = DefaultRules().DateFormats
+	}
+	for _, format := range formats {
+		if date, err := time.Parse(format, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q does not match accepted date formats %v", value, formats)
+}
+
+func (r *Rules) priceRange() string {
+	if r.MaxUnitPrice > 0 {
+		return fmt.Sprintf("[%.2f, %.2f]", r.MinUnitPrice, r.MaxUnitPrice)
+	}
+	return fmt.Sprintf("[%.2f, ∞)", r.MinUnitPrice)
+}
+
+// ParseInt parses an integer field, rejecting values that are not whole numbers
+func ParseInt(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	intVal, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a whole number", value)
+	}
+	return intVal, nil
+}
+
+// ParseFloat parses a decimal field, rejecting values that are not numbers
+func ParseFloat(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	floatVal, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(floatVal) || math.IsInf(floatVal, 0) {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return floatVal, nil
+}
+
+// fieldValue returns a record field as a trimmed string
+func fieldValue(record map[string]interface{}, field string) string {
+	value, ok := record[field]
+	if !ok || value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", value))
+}
@@ -0,0 +1,130 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rulesCacheTTL bounds how long a tenant's rules are served from memory
+const rulesCacheTTL = time.Minute
+
+// ErrNotInitialized is returned when MongoDB was not available at startup
+var ErrNotInitialized = errors.New("validation rule store not initialized")
+
+type cachedRules struct {
+	rules    *Rules
+	loadedAt time.Time
+}
+
+var (
+	rulesCollection *mongo.Collection
+	rulesCache      = make(map[string]cachedRules)
+	rulesMu         sync.RWMutex
+)
+
+// Initialize sets up the per-tenant rule collection
+func Initialize(client *mongo.Client) error {
+	if client == nil {
+		return ErrNotInitialized
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := client.Database("oms_database").Collection("validation_rules")
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create validation_rules index: %w", err)
+	}
+
+	rulesCollection = collection
+	log.Println("📊 Database: oms_database, Collection: validation_rules")
+	return nil
+}
+
+// RulesFor returns the rules of a tenant, falling back to the "default" tenant's
+// stored rules and then to DefaultRules
+func RulesFor(ctx context.Context, tenantID string) *Rules {
+	if rules := cachedRulesFor(tenantID); rules != nil {
+		return rules
+	}
+
+	rules, err := loadRules(ctx, tenantID)
+	if err != nil && tenantID != "default" {
+		rules, err = loadRules(ctx, "default")
+	}
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) && !errors.Is(err, ErrNotInitialized) {
+			log.Printf("⚠️ Failed to load validation rules for tenant %s, using defaults: %v", tenantID, err)
+		}
+		rules = DefaultRules()
+	}
+
+	rulesMu.Lock()
+	rulesCache[tenantID] = cachedRules{rules: rules, loadedAt: time.Now()}
+	rulesMu.Unlock()
+	return rules
+}
+
+// SaveRules stores the rules of a tenant, replacing any previous configuration
+func SaveRules(ctx context.Context, rules *Rules) error {
+	if rulesCollection == nil {
+		return ErrNotInitialized
+	}
+	if rules.TenantID == "" {
+		return fmt.Errorf("tenant_id is required")
+	}
+
+	rules.UpdatedAt = time.Now()
+	_, err := rulesCollection.ReplaceOne(ctx, bson.M{"tenant_id": rules.TenantID}, rules,
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save validation rules: %w", err)
+	}
+
+	// Tenants falling back to the default rules must pick up the change as well
+	rulesMu.Lock()
+	if rules.TenantID == "default" {
+		rulesCache = make(map[string]cachedRules)
+	} else {
+		delete(rulesCache, rules.TenantID)
+	}
+	rulesMu.Unlock()
+
+	log.Printf("✅ Validation rules saved for tenant %s", rules.TenantID)
+	return nil
+}
+
+func cachedRulesFor(tenantID string) *Rules {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	cached, ok := rulesCache[tenantID]
+	if !ok || time.Since(cached.loadedAt) > rulesCacheTTL {
+		return nil
+	}
+	return cached.rules
+}
+
+func loadRules(ctx context.Context, tenantID string) (*Rules, error) {
+	if rulesCollection == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var rules Rules
+	if err := rulesCollection.FindOne(ctx, bson.M{"tenant_id": tenantID}).Decode(&rules); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}